@@ -1,9 +1,13 @@
 package main
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/go-gost/x/socket"
 )
 
 // Config 配置结构体
@@ -13,6 +17,20 @@ type Config struct {
 	Http   int    `json:"http"`
 	Tls    int    `json:"tls"`
 	Socks  int    `json:"socks"`
+	// WsReport 为 1 时流量和配置报告通过 WebSocket 长连接发送，连接断开时回退到 HTTP
+	WsReport int `json:"ws_report"`
+	// NoLegacy 为 1 时不兼容旧版面板的加密格式，只接受带认证帧头的命令
+	NoLegacy int `json:"no_legacy"`
+
+	// 面板 TLS 配置：scheme 为 https / wss（或 addr 带 https:// / wss:// 前缀）时
+	// WebSocket 使用 wss，HTTP 上报使用 https
+	Scheme     string `json:"scheme"`
+	CA         string `json:"ca"`          // 自定义 CA 证书文件
+	Cert       string `json:"cert"`        // 客户端证书文件
+	Key        string `json:"key"`         // 客户端私钥文件
+	ServerName string `json:"server_name"` // 证书校验使用的主机名
+	Pin        string `json:"pin"`         // 面板证书公钥 SHA-256 指纹，多个用逗号分隔
+	Insecure   int    `json:"insecure"`    // 为 1 时跳过证书链校验，必须同时设置 pin
 }
 
 // LoadConfig 加载配置文件
@@ -34,6 +52,31 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("解析配置文件失败: %v", err)
 	}
 
+	// addr 允许带协议前缀
+	for prefix, scheme := range map[string]string{
+		"https://": "https", "wss://": "https",
+		"http://": "http", "ws://": "http",
+	} {
+		if strings.HasPrefix(config.Addr, prefix) {
+			config.Addr = strings.TrimSuffix(strings.TrimPrefix(config.Addr, prefix), "/")
+			if config.Scheme == "" {
+				config.Scheme = scheme
+			}
+			break
+		}
+	}
+	// ws / wss 分别等同于 http / https
+	switch scheme := strings.ToLower(strings.TrimSpace(config.Scheme)); scheme {
+	case "", "http", "https":
+		config.Scheme = scheme
+	case "ws":
+		config.Scheme = "http"
+	case "wss":
+		config.Scheme = "https"
+	default:
+		return nil, fmt.Errorf("不支持的协议: %s", config.Scheme)
+	}
+
 	// 验证必要的配置项
 	if config.Addr == "" {
 		return nil, fmt.Errorf("服务器地址不能为空")
@@ -41,3 +84,18 @@ func LoadConfig(configPath string) (*Config, error) {
 
 	return &config, nil
 }
+
+// PanelTLSConfig 返回连接面板使用的 TLS 配置，未启用 https 时返回 nil
+func (c *Config) PanelTLSConfig() (*tls.Config, error) {
+	if c.Scheme != "https" {
+		return nil, nil
+	}
+	return socket.NewPanelTLSConfig(socket.PanelTLSOptions{
+		CAFile:     c.CA,
+		CertFile:   c.Cert,
+		KeyFile:    c.Key,
+		ServerName: c.ServerName,
+		Pin:        c.Pin,
+		Insecure:   c.Insecure == 1,
+	})
+}
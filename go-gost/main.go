@@ -120,9 +120,23 @@ func main() {
 	log := xlogger.NewLogger()
 	logger.SetDefault(log)
 
-	wsReporter := socket.StartWebSocketReporterWithConfig(config.Addr, config.Secret, "1.2.0")
+	tlsConfig, err := config.PanelTLSConfig()
+	if err != nil {
+		fmt.Printf("❌ 面板 TLS 配置错误: %v\n", err)
+		os.Exit(1)
+	}
+
+	if config.NoLegacy == 1 {
+		socket.DisableLegacyFrames()
+	}
+	wsReporter := socket.StartWebSocketReporterWithConfig(config.Addr, config.Secret, "1.2.0", tlsConfig)
 	defer wsReporter.Stop()
-	service.SetHTTPReportURL(config.Addr, config.Secret)
+	service.SetHTTPReportURL(config.Addr, config.Secret, tlsConfig)
+	// 共用密钥环，通过 WebSocket 完成的密钥轮换同时作用于 HTTP 上报
+	service.SetReportKeyring(wsReporter.Keyring())
+	if config.WsReport == 1 {
+		service.SetReportTransport(wsReporter)
+	}
 
 	p := &program{}
 	if err := svc.Run(p); err != nil {
@@ -16,6 +16,7 @@ import (
 	metrics "github.com/go-gost/x/metrics/service"
 	"github.com/go-gost/x/registry"
 	xservice "github.com/go-gost/x/service"
+	"github.com/go-gost/x/socket"
 	"github.com/judwhite/go-svc"
 	"net/http"
 	"os"
@@ -34,7 +35,15 @@ type program struct {
 }
 
 func (p *program) Init(env svc.Environment) error {
-	parser.Init(parser.Args{
+	initParser()
+
+	return nil
+}
+
+// initParser initializes the config parser. Without -C/-L/-F the node state
+// is restored from the latest persisted generation that passes its checksum.
+func initParser() {
+	args := parser.Args{
 		CfgFile:     cfgFile,
 		Services:    services,
 		Nodes:       nodes,
@@ -42,9 +51,19 @@ func (p *program) Init(env svc.Environment) error {
 		Trace:       trace,
 		ApiAddr:     apiAddr,
 		MetricsAddr: metricsAddr,
-	})
+	}
 
-	return nil
+	if cfgFile == "" && len(services) == 0 && len(nodes) == 0 {
+		data, err := socket.LoadState()
+		if err != nil {
+			logger.Default().Warnf("restore state: %v", err)
+		}
+		if data != nil {
+			args.CfgFile = string(data)
+		}
+	}
+
+	parser.Init(args)
 }
 
 func (p *program) Start() error {
@@ -73,6 +92,7 @@ func (p *program) Start() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	p.cancel = cancel
 	go p.reload(ctx)
+	go xservice.StartTrafficAggregator(ctx)
 
 	go func() {
 		select {
@@ -87,8 +107,21 @@ func (p *program) Start() error {
 }
 
 func (p *program) run(cfg *config.Config) error {
-	for _, svc := range registry.ServiceRegistry().GetAll() {
+	paused := make(map[string]bool)
+	for _, s := range cfg.Services {
+		if s.Metadata != nil && s.Metadata["paused"] == true {
+			paused[s.Name] = true
+		}
+	}
+
+	for name, svc := range registry.ServiceRegistry().GetAll() {
 		svc := svc
+		// paused services stay registered but do not hold their ports
+		if paused[name] {
+			svc.Close()
+			logger.Default().Infof("service %s is paused", name)
+			continue
+		}
 		go func() {
 			svc.Serve()
 		}()
@@ -220,6 +253,8 @@ func (p *program) reload(ctx context.Context) {
 }
 
 func (p *program) reloadConfig() error {
+	initParser()
+
 	cfg, err := parser.Parse()
 	if err != nil {
 		return err
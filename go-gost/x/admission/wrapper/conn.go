@@ -41,6 +41,25 @@ func (c *serverConn) Read(b []byte) (n int, err error) {
 	return c.Conn.Read(b)
 }
 
+// Unwrap implements xnet.Splicer.
+func (c *serverConn) Unwrap() io.ReadWriter {
+	return c.Conn
+}
+
+// Spliceable implements xnet.Splicer.
+func (c *serverConn) Spliceable(read bool) bool {
+	return true
+}
+
+// Spliced implements xnet.Splicer, the transfer is stopped once the client is no longer admitted.
+func (c *serverConn) Spliced(n int, read bool) error {
+	if read && c.admission != nil &&
+		!c.admission.Admit(context.Background(), c.RemoteAddr().String()) {
+		return io.EOF
+	}
+	return nil
+}
+
 func (c *serverConn) SyscallConn() (rc syscall.RawConn, err error) {
 	if sc, ok := c.Conn.(syscall.Conn); ok {
 		rc, err = sc.SyscallConn()
@@ -5,6 +5,7 @@ import (
 
 	"net/http"
 
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,8 +13,9 @@ import (
 	"github.com/go-gost/core/service"
 	"github.com/go-gost/x/config"
 	parser "github.com/go-gost/x/config/parsing/service"
-	kill "github.com/go-gost/x/internal/util/port"
+	xhop "github.com/go-gost/x/hop"
 	"github.com/go-gost/x/registry"
+	xservice "github.com/go-gost/x/service"
 )
 
 // swagger:parameters createServiceRequest
@@ -353,6 +355,11 @@ type deleteServiceRequest struct {
 	// in: path
 	// required: true
 	Service string `uri:"service" json:"service"`
+	// drain existing connections instead of closing them immediately.
+	// The value is the deadline as a duration (e.g. 30s, 10m) or in seconds,
+	// "true" uses the default deadline (5m).
+	// in: query
+	Drain string `form:"drain" json:"drain"`
 }
 
 // successful operation.
@@ -377,6 +384,7 @@ func deleteService(ctx *gin.Context) {
 	// swagger:route DELETE /config/services/{service} Service deleteServiceRequest
 	//
 	// Delete service by name.
+	// With the drain parameter, existing connections are kept until they finish or the deadline expires.
 	//
 	//     Security:
 	//       basicAuth: []
@@ -386,9 +394,16 @@ func deleteService(ctx *gin.Context) {
 
 	var req deleteServiceRequest
 	ctx.ShouldBindUri(&req)
+	ctx.ShouldBindQuery(&req)
 
 	name := strings.TrimSpace(req.Service)
 
+	drain, timeout, err := parseDrain(req.Drain)
+	if err != nil {
+		writeError(ctx, NewError(http.StatusBadRequest, ErrCodeInvalid, err.Error()))
+		return
+	}
+
 	svc := registry.ServiceRegistry().Get(name)
 	if svc == nil {
 		writeError(ctx, NewError(http.StatusBadRequest, ErrCodeNotFound, fmt.Sprintf("service %s not found", name)))
@@ -397,6 +412,7 @@ func deleteService(ctx *gin.Context) {
 
 	registry.ServiceRegistry().Unregister(name)
 	svc.Close()
+	disconnectService(name, svc, drain, timeout)
 
 	config.OnUpdate(func(c *config.Config) error {
 		services := c.Services
@@ -463,6 +479,7 @@ func deleteServices(ctx *gin.Context) {
 	for _, std := range servicesToDelete {
 		registry.ServiceRegistry().Unregister(std.name)
 		std.service.Close()
+		xservice.CloseConnections(std.service)
 	}
 
 	// 第三阶段：更新配置
@@ -494,6 +511,11 @@ type pauseServiceRequest struct {
 	// in: path
 	// required: true
 	Service string `uri:"service" json:"service"`
+	// drain existing connections instead of closing them immediately.
+	// The value is the deadline as a duration (e.g. 30s, 10m) or in seconds,
+	// "true" uses the default deadline (5m).
+	// in: query
+	Drain string `form:"drain" json:"drain"`
 }
 
 // successful operation.
@@ -519,6 +541,7 @@ func pauseService(ctx *gin.Context) {
 	// swagger:route POST /config/services/{service}/pause Service pauseServiceRequest
 	//
 	// Pause service by name, the service will be stopped but configuration remains.
+	// With the drain parameter, existing connections are kept until they finish or the deadline expires.
 	//
 	//     Security:
 	//       basicAuth: []
@@ -528,29 +551,25 @@ func pauseService(ctx *gin.Context) {
 
 	var req pauseServiceRequest
 	ctx.ShouldBindUri(&req)
+	ctx.ShouldBindQuery(&req)
 
 	name := strings.TrimSpace(req.Service)
 
+	drain, timeout, err := parseDrain(req.Drain)
+	if err != nil {
+		writeError(ctx, NewError(http.StatusBadRequest, ErrCodeInvalid, err.Error()))
+		return
+	}
+
 	svc := registry.ServiceRegistry().Get(name)
 	if svc == nil {
 		writeError(ctx, NewError(http.StatusBadRequest, ErrCodeNotFound, fmt.Sprintf("service %s not found", name)))
 		return
 	}
 
-	// 获取服务地址用于强制断开连接
-	var serviceAddr string
-	cfg := config.Global()
-	for _, s := range cfg.Services {
-		if s.Name == name {
-			serviceAddr = s.Addr
-			break
-		}
-	}
-
-	// 强制断开端口的所有连接
-	if serviceAddr != "" {
-		_ = kill.ForceClosePortConnections(serviceAddr)
-	}
+	// 停止接受新连接，现有连接立即断开或排空
+	svc.Close()
+	disconnectService(name, svc, drain, timeout)
 
 	// 更新配置中的暂停状态
 	config.OnUpdate(func(c *config.Config) error {
@@ -681,6 +700,12 @@ type ServiceStatusInfo struct {
 	Name   string `json:"name"`
 	Status string `json:"status"` // running, paused, stopped
 	Paused bool   `json:"paused"`
+	// Connections number of active connections.
+	Connections int `json:"connections"`
+	// Draining drain progress, present while the service is draining.
+	Draining *xservice.DrainStatus `json:"draining,omitempty"`
+	// Nodes health of the forwarder nodes, present when health check is enabled.
+	Nodes []xhop.NodeHealth `json:"nodes,omitempty"`
 }
 
 func getServiceStatus(ctx *gin.Context) {
@@ -723,15 +748,52 @@ func getServiceStatus(ctx *gin.Context) {
 		status = "paused"
 	}
 
+	info := ServiceStatusInfo{
+		Name:        name,
+		Status:      status,
+		Paused:      paused,
+		Connections: xservice.ActiveConnections(svc),
+	}
+	if st, ok := xservice.DrainStatusOf(name); ok {
+		info.Draining = &st
+	}
+	if nodes, ok := xhop.Health(name); ok {
+		info.Nodes = nodes
+	}
+
 	ctx.JSON(http.StatusOK, getServiceStatusResponse{
-		Data: ServiceStatusInfo{
-			Name:   name,
-			Status: status,
-			Paused: paused,
-		},
+		Data: info,
 	})
 }
 
+// parseDrain 解析 drain 参数，支持时长（30s、10m）、秒数和 true/false
+func parseDrain(v string) (bool, time.Duration, error) {
+	v = strings.TrimSpace(v)
+	switch v {
+	case "", "false", "0":
+		return false, 0, nil
+	case "true":
+		return true, xservice.DefaultDrainTimeout, nil
+	}
+
+	if n, err := strconv.Atoi(v); err == nil && n > 0 {
+		return true, time.Duration(n) * time.Second, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return true, d, nil
+	}
+	return false, 0, fmt.Errorf("invalid drain value %q", v)
+}
+
+// disconnectService 处理已停止接受新连接的服务上的现有连接：立即断开或在后台排空
+func disconnectService(name string, svc service.Service, drain bool, timeout time.Duration) {
+	if !drain {
+		xservice.CloseConnections(svc)
+		return
+	}
+	xservice.Drain(name, svc, timeout, nil)
+}
+
 // 回滚已暂停的服务 - 重新启动它们
 func rollbackPausedServices(pausedServices []struct {
 	name          string
@@ -908,10 +970,8 @@ func pauseServices(ctx *gin.Context) {
 			return
 		}
 
-		// 强制断开端口的所有连接
-		if serviceConfig.Addr != "" {
-			_ = kill.ForceClosePortConnections(serviceConfig.Addr)
-		}
+		// 断开该服务的所有连接
+		xservice.CloseConnections(stp.service)
 
 		// 记录已暂停的服务
 		pausedServices = append(pausedServices, struct {
@@ -2,12 +2,14 @@ package chain
 
 import (
 	"context"
+	"io"
 
 	"github.com/go-gost/core/chain"
 	"github.com/go-gost/core/hop"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/metadata"
 	"github.com/go-gost/core/selector"
+	xs "github.com/go-gost/x/selector"
 )
 
 var (
@@ -65,6 +67,18 @@ func (c *Chain) AddHop(hop hop.Hop) {
 	c.hops = append(c.hops, hop)
 }
 
+// Close implements io.Closer interface.
+// It closes the hops defined inline in the chain, hops referenced by name belong to the hop registry and are left open.
+func (c *Chain) Close() error {
+	xs.RemoveStats(c)
+	for _, hop := range c.hops {
+		if closer, ok := hop.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	return nil
+}
+
 // Metadata implements metadata.Metadatable interface.
 func (c *Chain) Metadata() metadata.Metadata {
 	return c.metadata
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/go-gost/core/chain"
@@ -16,6 +17,7 @@ import (
 	"github.com/go-gost/x/internal/net/dialer"
 	"github.com/go-gost/x/internal/net/udp"
 	xmetrics "github.com/go-gost/x/metrics"
+	xs "github.com/go-gost/x/selector"
 )
 
 var (
@@ -149,6 +151,23 @@ func (r *chainRoute) Dial(ctx context.Context, network, address string, opts ...
 		}
 		return nil, err
 	}
+
+	// only stream connections are tracked, datagram connections may be asserted to other types by the caller
+	if strings.HasPrefix(network, "tcp") {
+		releases := make([]func(), 0, len(r.nodes)+1)
+		if r.options.Chain != nil {
+			// the connections of the chain are counted for the selection among chains
+			releases = append(releases, xs.Acquire(r.options.Chain))
+		}
+		for _, node := range r.nodes {
+			releases = append(releases, xs.Acquire(node))
+		}
+		cc = xs.TrackConn(cc, func() {
+			for _, release := range releases {
+				release()
+			}
+		})
+	}
 	return cc, nil
 }
 
@@ -188,6 +207,7 @@ func (r *chainRoute) Bind(ctx context.Context, network, address string, opts ...
 func (r *chainRoute) connect(ctx context.Context, logger logger.Logger) (conn net.Conn, err error) {
 	network := "ip"
 	node := r.nodes[0]
+	begin := time.Now()
 
 	defer func() {
 		if r.options.Chain != nil {
@@ -214,6 +234,7 @@ func (r *chainRoute) connect(ctx context.Context, logger logger.Logger) (conn ne
 			if marker != nil {
 				marker.Reset()
 			}
+			xs.ObserveLatency(r.options.Chain, time.Since(begin))
 		}
 	}()
 
@@ -247,6 +268,8 @@ func (r *chainRoute) connect(ctx context.Context, logger logger.Logger) (conn ne
 		marker.Reset()
 	}
 
+	duration := time.Since(start)
+	xs.ObserveLatency(node, duration)
 	if r.options.Chain != nil {
 		var name string
 		if cn, _ := r.options.Chain.(chainNamer); cn != nil {
@@ -254,7 +277,7 @@ func (r *chainRoute) connect(ctx context.Context, logger logger.Logger) (conn ne
 		}
 		if v := xmetrics.GetObserver(xmetrics.MetricNodeConnectDurationObserver,
 			metrics.Labels{"chain": name, "node": node.Name}); v != nil {
-			v.Observe(time.Since(start).Seconds())
+			v.Observe(duration.Seconds())
 		}
 	}
 
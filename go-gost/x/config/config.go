@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/spf13/viper"
@@ -24,6 +25,8 @@ func init() {
 var (
 	global    = &Config{}
 	globalMux sync.RWMutex
+	// globalVersion is incremented whenever the global config is replaced or may have been modified.
+	globalVersion atomic.Uint64
 )
 
 func Global() *Config {
@@ -40,15 +43,30 @@ func Set(c *Config) {
 	defer globalMux.Unlock()
 
 	global = c
+	globalVersion.Add(1)
 }
 
 func OnUpdate(f func(c *Config) error) error {
 	globalMux.Lock()
 	defer globalMux.Unlock()
+	defer globalVersion.Add(1)
 
 	return f(global)
 }
 
+// View calls f with the global config under the read lock, f must not modify the config.
+func View(f func(c *Config)) {
+	globalMux.RLock()
+	defer globalMux.RUnlock()
+
+	f(global)
+}
+
+// Version returns the version of the global config, which changes after every Set and OnUpdate.
+func Version() uint64 {
+	return globalVersion.Load()
+}
+
 type LogConfig struct {
 	Output   string             `yaml:",omitempty" json:"output,omitempty"`
 	Level    string             `yaml:",omitempty" json:"level,omitempty"`
@@ -153,6 +171,26 @@ type SelectorConfig struct {
 	FailTimeout time.Duration `yaml:"failTimeout" json:"failTimeout"`
 }
 
+// HealthCheckConfig active health check of the nodes in a hop.
+type HealthCheckConfig struct {
+	// probe type: tcp (default), tls, http, https, send
+	Type     string        `yaml:",omitempty" json:"type,omitempty"`
+	Interval time.Duration `yaml:",omitempty" json:"interval,omitempty"`
+	Timeout  time.Duration `yaml:",omitempty" json:"timeout,omitempty"`
+	// consecutive successes to mark a node as healthy
+	Rise int `yaml:",omitempty" json:"rise,omitempty"`
+	// consecutive failures to mark a node as unhealthy
+	Fall int `yaml:",omitempty" json:"fall,omitempty"`
+	// request path and host for http(s) probes, host is also used as the TLS server name
+	Path string `yaml:",omitempty" json:"path,omitempty"`
+	Host string `yaml:",omitempty" json:"host,omitempty"`
+	// expected http status code, any 2xx or 3xx status is accepted by default
+	Status int `yaml:",omitempty" json:"status,omitempty"`
+	// data sent by send probes, and the data expected in the response
+	Send   string `yaml:",omitempty" json:"send,omitempty"`
+	Expect string `yaml:",omitempty" json:"expect,omitempty"`
+}
+
 type AdmissionConfig struct {
 	Name string `json:"name"`
 	// Deprecated: use whitelist instead
@@ -356,6 +394,8 @@ type ForwarderConfig struct {
 	Hop      string               `yaml:",omitempty" json:"hop,omitempty"`
 	Selector *SelectorConfig      `yaml:",omitempty" json:"selector,omitempty"`
 	Nodes    []*ForwardNodeConfig `json:"nodes"`
+
+	HealthCheck *HealthCheckConfig `yaml:"healthCheck,omitempty" json:"healthCheck,omitempty"`
 }
 
 type ForwardNodeConfig struct {
@@ -521,6 +561,9 @@ type HopConfig struct {
 	HTTP      *HTTPLoader     `yaml:"http,omitempty" json:"http,omitempty"`
 	Plugin    *PluginConfig   `yaml:",omitempty" json:"plugin,omitempty"`
 	Metadata  map[string]any  `yaml:",omitempty" json:"metadata,omitempty"`
+
+	// active health check of the nodes
+	HealthCheck *HealthCheckConfig `yaml:"healthCheck,omitempty" json:"healthCheck,omitempty"`
 }
 
 type NodeConfig struct {
@@ -19,6 +19,7 @@ import (
 	router_parser "github.com/go-gost/x/config/parsing/router"
 	sd_parser "github.com/go-gost/x/config/parsing/sd"
 	service_parser "github.com/go-gost/x/config/parsing/service"
+	xrecorder "github.com/go-gost/x/recorder"
 	"github.com/go-gost/x/registry"
 )
 
@@ -159,6 +160,11 @@ func register(cfg *config.Config) error {
 			return err
 		}
 	}
+	for name, r := range xrecorder.Builtins() {
+		if !registry.RecorderRegistry().IsRegistered(name) {
+			registry.RecorderRegistry().Register(name, r)
+		}
+	}
 
 	for name := range registry.TrafficLimiterRegistry().GetAll() {
 		registry.TrafficLimiterRegistry().Unregister(name)
@@ -123,6 +123,21 @@ func ParseHop(cfg *config.HopConfig, log logger.Logger) (hop.Hop, error) {
 		})),
 	}
 
+	if hc := cfg.HealthCheck; hc != nil {
+		opts = append(opts, xhop.HealthCheckOption(&xhop.HealthCheck{
+			Type:     strings.ToLower(strings.TrimSpace(hc.Type)),
+			Interval: hc.Interval,
+			Timeout:  hc.Timeout,
+			Rise:     hc.Rise,
+			Fall:     hc.Fall,
+			Path:     hc.Path,
+			Host:     hc.Host,
+			Status:   hc.Status,
+			Send:     hc.Send,
+			Expect:   hc.Expect,
+		}))
+	}
+
 	if cfg.File != nil && cfg.File.Path != "" {
 		opts = append(opts, xhop.FileLoaderOption(loader.FileLoader(cfg.File.Path)))
 	}
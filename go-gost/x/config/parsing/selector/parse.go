@@ -22,6 +22,12 @@ func ParseChainSelector(cfg *config.SelectorConfig) selector.Selector[chain.Chai
 		strategy = xs.FIFOStrategy[chain.Chainer]()
 	case "hash":
 		strategy = xs.HashStrategy[chain.Chainer]()
+	case "least", "leastconn", "lc":
+		strategy = xs.LeastConnStrategy[chain.Chainer]()
+	case "ewma", "latency":
+		strategy = xs.EWMAStrategy[chain.Chainer]()
+	case "chash", "consistent":
+		strategy = xs.ConsistentHashStrategy[chain.Chainer]()
 	default:
 		strategy = xs.RoundRobinStrategy[chain.Chainer]()
 	}
@@ -47,6 +53,12 @@ func ParseNodeSelector(cfg *config.SelectorConfig) selector.Selector[*chain.Node
 		strategy = xs.FIFOStrategy[*chain.Node]()
 	case "hash":
 		strategy = xs.HashStrategy[*chain.Node]()
+	case "least", "leastconn", "lc":
+		strategy = xs.LeastConnStrategy[*chain.Node]()
+	case "ewma", "latency":
+		strategy = xs.EWMAStrategy[*chain.Node]()
+	case "chash", "consistent":
+		strategy = xs.ConsistentHashStrategy[*chain.Node]()
 	default:
 		strategy = xs.RoundRobinStrategy[*chain.Node]()
 	}
@@ -1,15 +1,20 @@
 package service
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"reflect"
 	"runtime"
 	"strings"
 	"time"
 
+	"github.com/go-gost/core/admission"
 	"github.com/go-gost/core/auth"
 	"github.com/go-gost/core/chain"
 	"github.com/go-gost/core/handler"
 	"github.com/go-gost/core/hop"
+	"github.com/go-gost/core/limiter/traffic"
 	"github.com/go-gost/core/listener"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/observer"
@@ -39,7 +44,46 @@ import (
 	"github.com/vishvananda/netns"
 )
 
-func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
+// ParseOption 解析服务时的附加选项
+type ParseOption func(opts *parseOptions)
+
+type parseOptions struct {
+	stats stats.Stats
+}
+
+// StatsParseOption 沿用已有的统计对象，重建服务时保留尚未上报的流量
+func StatsParseOption(st stats.Stats) ParseOption {
+	return func(opts *parseOptions) {
+		opts.stats = st
+	}
+}
+
+// serviceParams 从服务配置和元数据中解析出的、监听器与处理器共用的参数
+type serviceParams struct {
+	log           logger.Logger
+	serviceLogger logger.Logger
+
+	sockOpts       *chain.SockOpts
+	ppv            int
+	ifce           string
+	preUp          []string
+	preDown        []string
+	postUp         []string
+	postDown       []string
+	ignoreChain    bool
+	enableStats    bool
+	resetTraffic   bool
+	observerPeriod time.Duration
+	netnsIn        string
+	netnsOut       string
+	dialTimeout    time.Duration
+
+	limiterRefreshInterval time.Duration
+	limiterCleanupInterval time.Duration
+	limiterScope           string
+}
+
+func parseServiceParams(cfg *config.ServiceConfig) *serviceParams {
 	if cfg.Listener == nil {
 		cfg.Listener = &config.ListenerConfig{}
 	}
@@ -54,18 +98,80 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 		cfg.Handler.Type = "auto"
 	}
 
-	log := logger.Default()
+	p := &serviceParams{
+		log:            logger.Default(),
+		ifce:           cfg.Interface,
+		enableStats:    true,
+		resetTraffic:   true,
+		observerPeriod: 5 * time.Second,
+	}
 	if loggers := logger_parser.List(cfg.Logger, cfg.Loggers...); len(loggers) > 0 {
-		log = logger.LoggerGroup(loggers...)
+		p.log = logger.LoggerGroup(loggers...)
 	}
 
-	serviceLogger := log.WithFields(map[string]any{
+	p.serviceLogger = p.log.WithFields(map[string]any{
 		"kind":     "service",
 		"service":  cfg.Name,
 		"listener": cfg.Listener.Type,
 		"handler":  cfg.Handler.Type,
 	})
 
+	if cfg.SockOpts != nil {
+		p.sockOpts = &chain.SockOpts{
+			Mark: cfg.SockOpts.Mark,
+		}
+	}
+
+	if cfg.Metadata != nil {
+		md := metadata.NewMetadata(cfg.Metadata)
+		p.ppv = mdutil.GetInt(md, parsing.MDKeyProxyProtocol)
+		if v := mdutil.GetString(md, parsing.MDKeyInterface); v != "" {
+			p.ifce = v
+		}
+		if v := mdutil.GetInt(md, parsing.MDKeySoMark); v > 0 {
+			p.sockOpts = &chain.SockOpts{
+				Mark: v,
+			}
+		}
+		p.preUp = mdutil.GetStrings(md, parsing.MDKeyPreUp)
+		p.preDown = mdutil.GetStrings(md, parsing.MDKeyPreDown)
+		p.postUp = mdutil.GetStrings(md, parsing.MDKeyPostUp)
+		p.postDown = mdutil.GetStrings(md, parsing.MDKeyPostDown)
+		p.ignoreChain = mdutil.GetBool(md, parsing.MDKeyIgnoreChain)
+
+		if md.IsExists(parsing.MDKeyEnableStats) {
+			p.enableStats = mdutil.GetBool(md, parsing.MDKeyEnableStats)
+		}
+		if md.IsExists(parsing.MDKeyObserverResetTraffic) {
+			p.resetTraffic = mdutil.GetBool(md, parsing.MDKeyObserverResetTraffic)
+		}
+
+		if period := mdutil.GetDuration(md, parsing.MDKeyObserverPeriod, "observePeriod"); period > 0 {
+			p.observerPeriod = period
+		}
+
+		p.netnsIn = mdutil.GetString(md, parsing.MDKeyNetns)
+		p.netnsOut = mdutil.GetString(md, parsing.MDKeyNetnsOut)
+
+		p.dialTimeout = mdutil.GetDuration(md, parsing.MDKeyDialTimeout)
+
+		p.limiterRefreshInterval = mdutil.GetDuration(md, parsing.MDKeyLimiterRefreshInterval)
+		p.limiterCleanupInterval = mdutil.GetDuration(md, parsing.MDKeyLimiterCleanupInterval)
+		p.limiterScope = mdutil.GetString(md, parsing.MDKeyLimiterScope)
+	}
+
+	return p
+}
+
+func ParseService(cfg *config.ServiceConfig, opts ...ParseOption) (service.Service, error) {
+	var parseOpts parseOptions
+	for _, opt := range opts {
+		opt(&parseOpts)
+	}
+
+	p := parseServiceParams(cfg)
+	serviceLogger := p.serviceLogger
+
 	tlsCfg := cfg.Listener.TLS
 	if tlsCfg == nil {
 		tlsCfg = &config.TLSConfig{}
@@ -93,73 +199,12 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 
 	admissions := admission_parser.List(cfg.Admission, cfg.Admissions...)
 
-	var sockOpts *chain.SockOpts
-	if cfg.SockOpts != nil {
-		sockOpts = &chain.SockOpts{
-			Mark: cfg.SockOpts.Mark,
-		}
-	}
-
-	var ppv int
-	ifce := cfg.Interface
-	var preUp, preDown, postUp, postDown []string
-	var ignoreChain bool
 	var pStats stats.Stats
-	var observerPeriod time.Duration
-	var netnsIn, netnsOut string
-	var dialTimeout time.Duration
-
-	var limiterRefreshInterval time.Duration
-	var limiterCleanupInterval time.Duration
-	var limiterScope string
-
-	enableStats := true
-	observerPeriod = 5 * time.Second
-
-	if cfg.Metadata != nil {
-		md := metadata.NewMetadata(cfg.Metadata)
-		ppv = mdutil.GetInt(md, parsing.MDKeyProxyProtocol)
-		if v := mdutil.GetString(md, parsing.MDKeyInterface); v != "" {
-			ifce = v
+	if p.enableStats {
+		pStats = parseOpts.stats
+		if pStats == nil {
+			pStats = xstats.NewStats(p.resetTraffic)
 		}
-		if v := mdutil.GetInt(md, parsing.MDKeySoMark); v > 0 {
-			sockOpts = &chain.SockOpts{
-				Mark: v,
-			}
-		}
-		preUp = mdutil.GetStrings(md, parsing.MDKeyPreUp)
-		preDown = mdutil.GetStrings(md, parsing.MDKeyPreDown)
-		postUp = mdutil.GetStrings(md, parsing.MDKeyPostUp)
-		postDown = mdutil.GetStrings(md, parsing.MDKeyPostDown)
-		ignoreChain = mdutil.GetBool(md, parsing.MDKeyIgnoreChain)
-
-		if md.IsExists(parsing.MDKeyEnableStats) {
-			enableStats = mdutil.GetBool(md, parsing.MDKeyEnableStats)
-		}
-
-		if period := mdutil.GetDuration(md, parsing.MDKeyObserverPeriod, "observePeriod"); period > 0 {
-			observerPeriod = period
-		}
-
-		netnsIn = mdutil.GetString(md, parsing.MDKeyNetns)
-		netnsOut = mdutil.GetString(md, parsing.MDKeyNetnsOut)
-
-		dialTimeout = mdutil.GetDuration(md, parsing.MDKeyDialTimeout)
-
-		limiterRefreshInterval = mdutil.GetDuration(md, parsing.MDKeyLimiterRefreshInterval)
-		limiterCleanupInterval = mdutil.GetDuration(md, parsing.MDKeyLimiterCleanupInterval)
-		limiterScope = mdutil.GetString(md, parsing.MDKeyLimiterScope)
-	}
-
-	if enableStats {
-		resetTraffic := true
-		if cfg.Metadata != nil {
-			md := metadata.NewMetadata(cfg.Metadata)
-			if md.IsExists(parsing.MDKeyObserverResetTraffic) {
-				resetTraffic = mdutil.GetBool(md, parsing.MDKeyObserverResetTraffic)
-			}
-		}
-		pStats = xstats.NewStats(resetTraffic)
 	}
 
 	listenerLogger := serviceLogger.WithFields(map[string]any{
@@ -167,44 +212,44 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 	})
 
 	routerOpts := []chain.RouterOption{
-		chain.TimeoutRouterOption(dialTimeout),
-		chain.InterfaceRouterOption(ifce),
-		chain.NetnsRouterOption(netnsOut),
-		chain.SockOptsRouterOption(sockOpts),
+		chain.TimeoutRouterOption(p.dialTimeout),
+		chain.InterfaceRouterOption(p.ifce),
+		chain.NetnsRouterOption(p.netnsOut),
+		chain.SockOptsRouterOption(p.sockOpts),
 		chain.ResolverRouterOption(registry.ResolverRegistry().Get(cfg.Resolver)),
 		chain.HostMapperRouterOption(registry.HostsRegistry().Get(cfg.Hosts)),
 		chain.LoggerRouterOption(listenerLogger),
 	}
-	if !ignoreChain {
+	if !p.ignoreChain {
 		routerOpts = append(routerOpts,
 			chain.ChainRouterOption(chainGroup(cfg.Listener.Chain, cfg.Listener.ChainGroup)),
 		)
 	}
 
+	// 准入、限速和连接数限制可以在服务更新时原地替换，无需重新监听
+	components := &xservice.ListenerComponents{
+		Admission:      xservice.NewSwapAdmission(xadmission.AdmissionGroup(admissions...)),
+		TrafficLimiter: xservice.NewSwapTrafficLimiter(trafficLimiter(cfg, p)),
+		ConnLimiter:    xservice.NewSwapConnLimiter(registry.ConnLimiterRegistry().Get(cfg.CLimiter)),
+	}
+
 	listenOpts := []listener.Option{
 		listener.AddrOption(cfg.Addr),
 		listener.RouterOption(xchain.NewRouter(routerOpts...)),
 		listener.AutherOption(auther),
 		listener.AuthOption(auth_parser.Info(cfg.Listener.Auth)),
 		listener.TLSConfigOption(tlsConfig),
-		listener.AdmissionOption(xadmission.AdmissionGroup(admissions...)),
-		listener.TrafficLimiterOption(
-			cache_limiter.NewCachedTrafficLimiter(
-				registry.TrafficLimiterRegistry().Get(cfg.Limiter),
-				cache_limiter.RefreshIntervalOption(limiterRefreshInterval),
-				cache_limiter.CleanupIntervalOption(limiterCleanupInterval),
-				cache_limiter.ScopeOption(limiterScope),
-			),
-		),
-		listener.ConnLimiterOption(registry.ConnLimiterRegistry().Get(cfg.CLimiter)),
+		listener.AdmissionOption(components.Admission),
+		listener.TrafficLimiterOption(components.TrafficLimiter),
+		listener.ConnLimiterOption(components.ConnLimiter),
 		listener.ServiceOption(cfg.Name),
-		listener.ProxyProtocolOption(ppv),
+		listener.ProxyProtocolOption(p.ppv),
 		listener.StatsOption(pStats),
-		listener.NetnsOption(netnsIn),
+		listener.NetnsOption(p.netnsIn),
 		listener.LoggerOption(listenerLogger),
 	}
 
-	if netnsIn != "" {
+	if p.netnsIn != "" {
 		runtime.LockOSThread()
 		defer runtime.UnlockOSThread()
 
@@ -216,18 +261,18 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 
 		var ns netns.NsHandle
 
-		if strings.HasPrefix(netnsIn, "/") {
-			ns, err = netns.GetFromPath(netnsIn)
+		if strings.HasPrefix(p.netnsIn, "/") {
+			ns, err = netns.GetFromPath(p.netnsIn)
 		} else {
-			ns, err = netns.GetFromName(netnsIn)
+			ns, err = netns.GetFromName(p.netnsIn)
 		}
 		if err != nil {
-			return nil, fmt.Errorf("netns.Get(%s): %v", netnsIn, err)
+			return nil, fmt.Errorf("netns.Get(%s): %v", p.netnsIn, err)
 		}
 		defer ns.Close()
 
 		if err := netns.Set(ns); err != nil {
-			return nil, fmt.Errorf("netns.Set(%s): %v", netnsIn, err)
+			return nil, fmt.Errorf("netns.Set(%s): %v", p.netnsIn, err)
 		}
 	}
 
@@ -247,32 +292,170 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 		return nil, err
 	}
 
-	handlerLogger := serviceLogger.WithFields(map[string]any{
+	h, recorders, err := parseHandler(cfg, p)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	serviceOpts := append(serviceOptions(cfg, p, admissions, recorders, pStats != nil),
+		xservice.StatsOption(pStats),
+		xservice.ListenerComponentsOption(components),
+	)
+	s := xservice.NewService(cfg.Name, ln, h, serviceOpts...)
+
+	serviceLogger.Infof("listening on %s/%s", s.Addr().String(), s.Addr().Network())
+	return s, nil
+}
+
+// ReloadService 用新配置原地更新正在运行的服务
+//
+// 处理器、转发节点、选择策略、准入、限速、连接数限制和服务选项即时生效，
+// 监听器和统计保持不变。调用前应通过 NeedsRebind 确认新配置不涉及监听器。
+func ReloadService(svc service.Service, cfg *config.ServiceConfig) error {
+	r, ok := svc.(xservice.Reloader)
+	if !ok {
+		return xservice.ErrReloadUnsupported
+	}
+
+	p := parseServiceParams(cfg)
+	if cfg.Listener.Metadata == nil {
+		cfg.Listener.Metadata = make(map[string]any)
+	}
+
+	h, recorders, err := parseHandler(cfg, p)
+	if err != nil {
+		return err
+	}
+
+	admissions := admission_parser.List(cfg.Admission, cfg.Admissions...)
+	err = r.Reload(xservice.HotConfig{
+		Handler:        h,
+		Admission:      xadmission.AdmissionGroup(admissions...),
+		TrafficLimiter: trafficLimiter(cfg, p),
+		ConnLimiter:    registry.ConnLimiterRegistry().Get(cfg.CLimiter),
+		Options:        serviceOptions(cfg, p, admissions, recorders, xservice.StatsOf(svc) != nil),
+	})
+	if err != nil {
+		if closer, ok := h.(io.Closer); ok {
+			closer.Close()
+		}
+		return err
+	}
+
+	p.serviceLogger.Infof("reloaded")
+	return nil
+}
+
+// NeedsRebind 判断从 old 更新到 cfg 是否必须重新监听
+//
+// 地址、监听器、网络命名空间、接口、统计开关等绑定在监听器上的配置变化时需要重建服务，
+// 其余变化都可以通过 ReloadService 原地生效。
+func NeedsRebind(old, cfg *config.ServiceConfig) bool {
+	a, err1 := json.Marshal(rebindKey(old))
+	b, err2 := json.Marshal(rebindKey(cfg))
+	if err1 != nil || err2 != nil {
+		return true
+	}
+
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return true
+	}
+	return !reflect.DeepEqual(va, vb)
+}
+
+// listenerMetadataKeys 影响监听器的服务元数据
+var listenerMetadataKeys = []string{
+	parsing.MDKeyProxyProtocol,
+	parsing.MDKeyInterface,
+	parsing.MDKeySoMark,
+	parsing.MDKeyIgnoreChain,
+	parsing.MDKeyEnableStats,
+	parsing.MDKeyObserverResetTraffic,
+	parsing.MDKeyNetns,
+	parsing.MDKeyNetnsOut,
+	parsing.MDKeyDialTimeout,
+}
+
+func rebindKey(cfg *config.ServiceConfig) any {
+	ln := config.ListenerConfig{Type: "tcp"}
+	if cfg.Listener != nil {
+		ln = *cfg.Listener
+		if strings.TrimSpace(ln.Type) == "" {
+			ln.Type = "tcp"
+		}
+	}
+
+	md := make(map[string]any)
+	for k, v := range cfg.Metadata {
+		for _, key := range listenerMetadataKeys {
+			if strings.EqualFold(k, key) {
+				md[strings.ToLower(key)] = v
+			}
+		}
+	}
+
+	return struct {
+		Addr      string
+		Interface string
+		SockOpts  *config.SockOptsConfig
+		Listener  config.ListenerConfig
+		Resolver  string
+		Hosts     string
+		Logger    string
+		Loggers   []string
+		Metadata  map[string]any
+	}{
+		Addr:      cfg.Addr,
+		Interface: cfg.Interface,
+		SockOpts:  cfg.SockOpts,
+		Listener:  ln,
+		Resolver:  cfg.Resolver,
+		Hosts:     cfg.Hosts,
+		Logger:    cfg.Logger,
+		Loggers:   cfg.Loggers,
+		Metadata:  md,
+	}
+}
+
+func trafficLimiter(cfg *config.ServiceConfig, p *serviceParams) traffic.TrafficLimiter {
+	return cache_limiter.NewCachedTrafficLimiter(
+		registry.TrafficLimiterRegistry().Get(cfg.Limiter),
+		cache_limiter.RefreshIntervalOption(p.limiterRefreshInterval),
+		cache_limiter.CleanupIntervalOption(p.limiterCleanupInterval),
+		cache_limiter.ScopeOption(p.limiterScope),
+	)
+}
+
+// parseHandler 创建并初始化服务的处理器
+func parseHandler(cfg *config.ServiceConfig, p *serviceParams) (handler.Handler, []recorder.RecorderObject, error) {
+	handlerLogger := p.serviceLogger.WithFields(map[string]any{
 		"kind": "handler",
 	})
 
-	tlsCfg = cfg.Handler.TLS
+	tlsCfg := cfg.Handler.TLS
 	if tlsCfg == nil {
 		tlsCfg = &config.TLSConfig{}
 	}
-	tlsConfig, err = tls_util.LoadServerConfig(tlsCfg)
+	tlsConfig, err := tls_util.LoadServerConfig(tlsCfg)
 	if err != nil {
 		handlerLogger.Error(err)
-		return nil, err
+		return nil, nil, err
 	}
 	if tlsConfig == nil {
 		tlsConfig = parsing.DefaultTLSConfig().Clone()
 		tls_util.SetTLSOptions(tlsConfig, tlsCfg.Options)
 	}
 
-	authers = auth_parser.List(cfg.Handler.Auther, cfg.Handler.Authers...)
+	authers := auth_parser.List(cfg.Handler.Auther, cfg.Handler.Authers...)
 	if len(authers) == 0 {
 		if auther := auth_parser.ParseAutherFromAuth(cfg.Handler.Auth); auther != nil {
 			authers = append(authers, auther)
 		}
 	}
 
-	auther = nil
+	var auther auth.Authenticator
 	if len(authers) > 0 {
 		auther = xauth.AuthenticatorGroup(authers...)
 	}
@@ -293,18 +476,18 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 		})
 	}
 
-	routerOpts = []chain.RouterOption{
+	routerOpts := []chain.RouterOption{
 		chain.RetriesRouterOption(cfg.Handler.Retries),
-		chain.TimeoutRouterOption(dialTimeout),
-		chain.InterfaceRouterOption(ifce),
-		chain.NetnsRouterOption(netnsOut),
-		chain.SockOptsRouterOption(sockOpts),
+		chain.TimeoutRouterOption(p.dialTimeout),
+		chain.InterfaceRouterOption(p.ifce),
+		chain.NetnsRouterOption(p.netnsOut),
+		chain.SockOptsRouterOption(p.sockOpts),
 		chain.ResolverRouterOption(registry.ResolverRegistry().Get(cfg.Resolver)),
 		chain.HostMapperRouterOption(registry.HostsRegistry().Get(cfg.Hosts)),
 		chain.RecordersRouterOption(recorders...),
 		chain.LoggerRouterOption(handlerLogger),
 	}
-	if !ignoreChain {
+	if !p.ignoreChain {
 		routerOpts = append(routerOpts,
 			chain.ChainRouterOption(chainGroup(cfg.Handler.Chain, cfg.Handler.ChainGroup)),
 		)
@@ -324,16 +507,16 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 			handler.RecordersOption(recorders...),
 			handler.LoggerOption(handlerLogger),
 			handler.ServiceOption(cfg.Name),
-			handler.NetnsOption(netnsIn),
+			handler.NetnsOption(p.netnsIn),
 		)
 	} else {
-		return nil, fmt.Errorf("unknown handler: %s", cfg.Handler.Type)
+		return nil, nil, fmt.Errorf("unknown handler: %s", cfg.Handler.Type)
 	}
 
 	if forwarder, ok := h.(handler.Forwarder); ok {
-		hop, err := parseForwarder(cfg.Forwarder, log)
+		hop, err := parseForwarder(cfg.Forwarder, cfg.Name, p.log)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		forwarder.Forward(hop)
 	}
@@ -344,8 +527,14 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 	handlerLogger.Debugf("metadata: %v", cfg.Handler.Metadata)
 	if err := h.Init(metadata.NewMetadata(cfg.Handler.Metadata)); err != nil {
 		handlerLogger.Error("init: ", err)
-		return nil, err
+		return nil, nil, err
 	}
+
+	return h, recorders, nil
+}
+
+// serviceOptions 服务选项（不含统计和监听器组件）
+func serviceOptions(cfg *config.ServiceConfig, p *serviceParams, admissions []admission.Admission, recorders []recorder.RecorderObject, hasStats bool) []xservice.Option {
 	var observer observer.Observer
 	// 如果服务名以_tls结尾，则不启用观察器
 	if strings.HasSuffix(cfg.Name, "_tls") {
@@ -353,28 +542,26 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 		fmt.Println("服务名以_tls结尾，跳过观察器启用")
 	} else if cfg.Observer != "" {
 		observer = registry.ObserverRegistry().Get(cfg.Observer)
-	} else if pStats != nil {
+	} else if hasStats {
 		observer = registry.ObserverRegistry().Get("console")
 	}
 
-	s := xservice.NewService(cfg.Name, ln, h,
+	return []xservice.Option{
 		xservice.AdmissionOption(xadmission.AdmissionGroup(admissions...)),
-		xservice.PreUpOption(preUp),
-		xservice.PreDownOption(preDown),
-		xservice.PostUpOption(postUp),
-		xservice.PostDownOption(postDown),
+		xservice.PreUpOption(p.preUp),
+		xservice.PreDownOption(p.preDown),
+		xservice.PostUpOption(p.postUp),
+		xservice.PostDownOption(p.postDown),
 		xservice.RecordersOption(recorders...),
-		xservice.StatsOption(pStats),
 		xservice.ObserverOption(observer),
-		xservice.ObserverPeriodOption(observerPeriod),
-		xservice.LoggerOption(serviceLogger),
-	)
-
-	serviceLogger.Infof("listening on %s/%s", s.Addr().String(), s.Addr().Network())
-	return s, nil
+		xservice.ObserverPeriodOption(p.observerPeriod),
+		xservice.LoggerOption(p.serviceLogger),
+	}
 }
 
-func parseForwarder(cfg *config.ForwarderConfig, log logger.Logger) (hop.Hop, error) {
+// parseForwarder 解析服务的转发目标，未引用已有 hop 时创建以 service 命名的内联 hop，
+// 健康检查状态按该名称查询
+func parseForwarder(cfg *config.ForwarderConfig, service string, log logger.Logger) (hop.Hop, error) {
 	if cfg == nil {
 		return nil, nil
 	}
@@ -388,8 +575,9 @@ func parseForwarder(cfg *config.ForwarderConfig, log logger.Logger) (hop.Hop, er
 	}
 
 	hc := config.HopConfig{
-		Name:     cfg.Name,
-		Selector: cfg.Selector,
+		Name:        service,
+		Selector:    cfg.Selector,
+		HealthCheck: cfg.HealthCheck,
 	}
 	for _, node := range cfg.Nodes {
 		if node == nil {
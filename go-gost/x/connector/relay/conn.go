@@ -9,6 +9,7 @@ import (
 	"math"
 	"net"
 	"sync"
+	"sync/atomic"
 
 	"github.com/go-gost/core/common/bufpool"
 	mdata "github.com/go-gost/core/metadata"
@@ -20,7 +21,9 @@ type tcpConn struct {
 	net.Conn
 	wbuf *bytes.Buffer
 	once sync.Once
-	mu   sync.Mutex
+	// responded is set after the response is read
+	responded atomic.Bool
+	mu        sync.Mutex
 }
 
 func (c *tcpConn) Read(b []byte) (n int, err error) {
@@ -28,6 +31,9 @@ func (c *tcpConn) Read(b []byte) (n int, err error) {
 		if c.wbuf != nil {
 			err = readResponse(c.Conn)
 		}
+		if err == nil {
+			c.responded.Store(true)
+		}
 	})
 
 	if err != nil {
@@ -52,6 +58,29 @@ func (c *tcpConn) Write(b []byte) (n int, err error) {
 	return
 }
 
+// Unwrap implements xnet.Splicer.
+func (c *tcpConn) Unwrap() io.ReadWriter {
+	return c.Conn
+}
+
+// Spliceable implements xnet.Splicer, the connection can be bypassed
+// after the response is read and the cached request is sent.
+func (c *tcpConn) Spliceable(read bool) bool {
+	if read {
+		return c.responded.Load()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.wbuf == nil || c.wbuf.Len() == 0
+}
+
+// Spliced implements xnet.Splicer.
+func (c *tcpConn) Spliced(n int, read bool) error {
+	return nil
+}
+
 type udpConn struct {
 	net.Conn
 	wbuf *bytes.Buffer
@@ -11,6 +11,7 @@ import (
 	md "github.com/go-gost/core/metadata"
 	"github.com/go-gost/relay"
 	ctxvalue "github.com/go-gost/x/ctx"
+	"github.com/go-gost/x/internal/net/proxyproto"
 	relay_util "github.com/go-gost/x/internal/util/relay"
 	"github.com/go-gost/x/registry"
 )
@@ -54,6 +55,16 @@ func (c *relayConnector) Connect(ctx context.Context, conn net.Conn, network, ad
 		defer conn.SetDeadline(time.Time{})
 	}
 
+	// pass the address of the original client to the next relay node,
+	// which uses it for the PROXY header sent to the target when proxyProtocol.accept is enabled.
+	if c.md.proxyProtocol > 0 {
+		if src := proxyproto.ClientAddr(ctx, nil); src != nil {
+			if _, err := proxyproto.WrapConn(c.md.proxyProtocol, "tcp", src, conn.RemoteAddr(), conn); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	req := relay.Request{
 		Version: relay.Version1,
 		Cmd:     relay.CmdConnect,
@@ -12,6 +12,7 @@ type metadata struct {
 	connectTimeout time.Duration
 	noDelay        bool
 	muxCfg         *mux.Config
+	proxyProtocol  int
 }
 
 func (c *relayConnector) parseMetadata(md mdata.Metadata) (err error) {
@@ -22,6 +23,7 @@ func (c *relayConnector) parseMetadata(md mdata.Metadata) (err error) {
 
 	c.md.connectTimeout = mdutil.GetDuration(md, connectTimeout)
 	c.md.noDelay = mdutil.GetBool(md, noDelay)
+	c.md.proxyProtocol = mdutil.GetInt(md, "proxyProtocol")
 
 	c.md.muxCfg = &mux.Config{
 		Version:           mdutil.GetInt(md, "mux.version"),
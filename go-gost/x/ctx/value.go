@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 
+	"github.com/go-gost/core/chain"
 	"github.com/go-gost/core/logger"
 )
 
@@ -109,3 +110,31 @@ func LoggerFromContext(ctx context.Context) logger.Logger {
 	v, _ := ctx.Value(keyLogger).(logger.Logger)
 	return v
 }
+
+// excludedNodesKey saves the nodes that have been tried for the current connection.
+type excludedNodesKey struct{}
+
+var (
+	keyExcludedNodes = &excludedNodesKey{}
+)
+
+// ContextWithExcludedNodes sets the nodes which should be skipped by Hop.Select.
+func ContextWithExcludedNodes(ctx context.Context, nodes []*chain.Node) context.Context {
+	return context.WithValue(ctx, keyExcludedNodes, nodes)
+}
+
+func ExcludedNodesFromContext(ctx context.Context) []*chain.Node {
+	v, _ := ctx.Value(keyExcludedNodes).([]*chain.Node)
+	return v
+}
+
+// IsNodeExcluded reports whether the node is in the excluded list of the context.
+// Nodes are compared by name and address, so nodes re-created by a hop plugin are also matched.
+func IsNodeExcluded(ctx context.Context, node *chain.Node) bool {
+	for _, n := range ExcludedNodesFromContext(ctx) {
+		if n == node || (n.Name == node.Name && n.Addr == node.Addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,7 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"errors"
+	"io"
 	"net"
 	"time"
 
@@ -16,6 +16,8 @@ import (
 	"github.com/go-gost/core/recorder"
 	ctxvalue "github.com/go-gost/x/ctx"
 	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/net/proxyproto"
+	"github.com/go-gost/x/internal/util/failover"
 	"github.com/go-gost/x/internal/util/forwarder"
 	"github.com/go-gost/x/internal/util/sniffing"
 	tls_util "github.com/go-gost/x/internal/util/tls"
@@ -75,6 +77,15 @@ func (h *forwardHandler) Forward(hop hop.Hop) {
 	h.hop = hop
 }
 
+// Close implements io.Closer interface.
+// The inline hop of the forwarder is closed with the handler, hops from the registry are not affected.
+func (h *forwardHandler) Close() error {
+	if closer, ok := h.hop.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.HandleOption) (err error) {
 	defer conn.Close()
 
@@ -116,7 +127,9 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 		ro.InputBytes = pStats.Get(stats.KindInputBytes)
 		ro.OutputBytes = pStats.Get(stats.KindOutputBytes)
 		ro.Duration = time.Since(start)
-
+		if err := ro.Record(ctx, h.recorder.Recorder); err != nil {
+			h.options.Logger.Errorf("record: %v", err)
+		}
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
@@ -141,7 +154,10 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 			var buf bytes.Buffer
 			cc, err := h.options.Router.Dial(ctxvalue.ContextWithBuffer(ctx, &buf), "tcp", address)
 			ro.Route = buf.String()
-			return cc, err
+			if err != nil {
+				return nil, err
+			}
+			return proxyproto.WrapConn(h.md.proxyProtocol, "tcp", proxyproto.ClientAddr(ctx, conn.RemoteAddr()), conn.LocalAddr(), cc)
 		}
 		sniffer := &forwarder.Sniffer{
 			Websocket:           h.md.sniffingWebsocket,
@@ -176,49 +192,38 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 		}
 	}
 
-	target := &chain.Node{}
-	if h.hop != nil {
-		target = h.hop.Select(ctx,
-			hop.ProtocolSelectOption(proto),
-		)
-	}
-	if target == nil {
-		err := errors.New("node not available")
-		return err
-	}
-
-	addr := target.Addr
-	if opts := target.Options(); opts != nil {
-		switch opts.Network {
-		case "unix":
-			network = opts.Network
-		default:
-			if _, _, err := net.SplitHostPort(addr); err != nil {
-				addr += ":0"
+	cc, _, err := failover.Dial(ctx, h.hop, h.md.failover, func(ctx context.Context, target *chain.Node) (net.Conn, error) {
+		network, addr := network, target.Addr
+		if opts := target.Options(); opts != nil {
+			switch opts.Network {
+			case "unix":
+				network = opts.Network
+			default:
+				if _, _, err := net.SplitHostPort(addr); err != nil {
+					addr += ":0"
+				}
 			}
 		}
-	}
 
-	ro.Network = network
-	ro.Host = addr
+		ro.Network = network
+		ro.Host = addr
 
-	var buf bytes.Buffer
-	cc, err := h.options.Router.Dial(ctxvalue.ContextWithBuffer(ctx, &buf), network, addr)
-	ro.Route = buf.String()
-	if err != nil {
-		// TODO: the router itself may be failed due to the failed node in the router,
-		// the dead marker may be a wrong operation.
-		if marker := target.Marker(); marker != nil {
-			marker.Mark()
+		var buf bytes.Buffer
+		cc, err := h.options.Router.Dial(ctxvalue.ContextWithBuffer(ctx, &buf), network, addr)
+		ro.Route = buf.String()
+		if err != nil {
+			return nil, err
 		}
+
+		return proxyproto.WrapConn(proxyproto.Version(target, h.md.proxyProtocol), network,
+			proxyproto.ClientAddr(ctx, conn.RemoteAddr()), conn.LocalAddr(), cc)
+	}, hop.ProtocolSelectOption(proto))
+	if err != nil {
 		return err
 	}
-	if marker := target.Marker(); marker != nil {
-		marker.Reset()
-	}
 	defer cc.Close()
 
-	xnet.Transport(conn, cc)
+	xnet.Transport(conn, cc, xnet.IdleTimeoutTransportOption(h.md.idleTimeout))
 
 	return nil
 }
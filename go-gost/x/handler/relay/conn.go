@@ -29,6 +29,21 @@ func (c *tcpConn) Write(b []byte) (n int, err error) {
 	return
 }
 
+// Unwrap implements xnet.Splicer.
+func (c *tcpConn) Unwrap() io.ReadWriter {
+	return c.Conn
+}
+
+// Spliceable implements xnet.Splicer, the write side can be bypassed after the cached header is sent.
+func (c *tcpConn) Spliceable(read bool) bool {
+	return read || c.wbuf.Len() == 0
+}
+
+// Spliced implements xnet.Splicer.
+func (c *tcpConn) Spliced(n int, read bool) error {
+	return nil
+}
+
 type udpConn struct {
 	net.Conn
 	wbuf bytes.Buffer
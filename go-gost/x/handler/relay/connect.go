@@ -18,6 +18,7 @@ import (
 	xbypass "github.com/go-gost/x/bypass"
 	ctxvalue "github.com/go-gost/x/ctx"
 	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/net/proxyproto"
 	serial "github.com/go-gost/x/internal/util/serial"
 	"github.com/go-gost/x/internal/util/sniffing"
 	traffic_wrapper "github.com/go-gost/x/limiter/traffic/wrapper"
@@ -106,6 +107,10 @@ func (h *relayHandler) handleConnect(ctx context.Context, conn net.Conn, network
 		var buf bytes.Buffer
 		cc, err = h.options.Router.Dial(ctxvalue.ContextWithBuffer(ctx, &buf), network, address)
 		ro.Route = buf.String()
+		if err == nil {
+			cc, err = proxyproto.WrapConn(h.md.proxyProtocol, network,
+				proxyproto.ClientAddr(ctx, conn.RemoteAddr()), conn.LocalAddr(), cc)
+		}
 	}
 	if err != nil {
 		resp.Status = relay.StatusNetworkUnreachable
@@ -199,7 +204,7 @@ func (h *relayHandler) handleConnect(ctx context.Context, conn net.Conn, network
 
 	t := time.Now()
 	log.Infof("%s <-> %s", conn.RemoteAddr(), address)
-	xnet.Transport(conn, cc)
+	xnet.Transport(conn, cc, xnet.IdleTimeoutTransportOption(h.md.idleTimeout))
 	log.WithFields(map[string]any{
 		"duration": time.Since(t),
 	}).Infof("%s >-< %s", conn.RemoteAddr(), address)
@@ -7,12 +7,15 @@ import (
 	"net"
 	"time"
 
+	"github.com/go-gost/core/chain"
 	"github.com/go-gost/core/limiter"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/observer/stats"
 	"github.com/go-gost/relay"
 	ctxvalue "github.com/go-gost/x/ctx"
 	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/net/proxyproto"
+	"github.com/go-gost/x/internal/util/failover"
 	"github.com/go-gost/x/limiter/traffic/wrapper"
 	stats_wrapper "github.com/go-gost/x/observer/stats/wrapper"
 )
@@ -22,22 +25,34 @@ func (h *relayHandler) handleForward(ctx context.Context, conn net.Conn, network
 		Version: relay.Version1,
 		Status:  relay.StatusOK,
 	}
-	target := h.hop.Select(ctx)
-	if target == nil {
-		resp.Status = relay.StatusServiceUnavailable
+	cc, target, err := failover.Dial(ctx, h.hop, h.md.failover, func(ctx context.Context, target *chain.Node) (net.Conn, error) {
+		log.Debugf("%s >> %s", conn.RemoteAddr(), target.Addr)
+		cc, err := h.options.Router.Dial(ctx, network, target.Addr)
+		if err == nil {
+			cc, err = proxyproto.WrapConn(proxyproto.Version(target, h.md.proxyProtocol), network,
+				proxyproto.ClientAddr(ctx, conn.RemoteAddr()), conn.LocalAddr(), cc)
+		}
+		if err != nil {
+			log.Warnf("dial %s: %v", target.Addr, err)
+		}
+		return cc, err
+	})
+	if err != nil {
+		resp.Status = relay.StatusHostUnreachable
+		if errors.Is(err, failover.ErrNodeUnavailable) {
+			resp.Status = relay.StatusServiceUnavailable
+		}
 		resp.WriteTo(conn)
-		err := errors.New("target not available")
 		log.Error(err)
 		return err
 	}
+	defer cc.Close()
 
 	log = log.WithFields(map[string]any{
 		"dst": fmt.Sprintf("%s/%s", target.Addr, network),
 		"cmd": "forward",
 	})
 
-	log.Debugf("%s >> %s", conn.RemoteAddr(), target.Addr)
-
 	{
 		clientID := ctxvalue.ClientIDFromContext(ctx)
 		rw := wrapper.WrapReadWriter(
@@ -61,25 +76,6 @@ func (h *relayHandler) handleForward(ctx context.Context, conn net.Conn, network
 		conn = xnet.NewReadWriteConn(rw, rw, conn)
 	}
 
-	cc, err := h.options.Router.Dial(ctx, network, target.Addr)
-	if err != nil {
-		// TODO: the router itself may be failed due to the failed node in the router,
-		// the dead marker may be a wrong operation.
-		if marker := target.Marker(); marker != nil {
-			marker.Mark()
-		}
-
-		resp.Status = relay.StatusHostUnreachable
-		resp.WriteTo(conn)
-		log.Error(err)
-
-		return err
-	}
-	defer cc.Close()
-	if marker := target.Marker(); marker != nil {
-		marker.Reset()
-	}
-
 	if h.md.noDelay {
 		if _, err := resp.WriteTo(conn); err != nil {
 			log.Error(err)
@@ -114,7 +110,7 @@ func (h *relayHandler) handleForward(ctx context.Context, conn net.Conn, network
 
 	t := time.Now()
 	log.Debugf("%s <-> %s", conn.RemoteAddr(), target.Addr)
-	xnet.Transport(conn, cc)
+	xnet.Transport(conn, cc, xnet.IdleTimeoutTransportOption(h.md.idleTimeout))
 	log.WithFields(map[string]any{
 		"duration": time.Since(t),
 	}).Debugf("%s >-< %s", conn.RemoteAddr(), target.Addr)
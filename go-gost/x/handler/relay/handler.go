@@ -3,6 +3,7 @@ package relay
 import (
 	"context"
 	"errors"
+	"io"
 	"net"
 	"strconv"
 	"time"
@@ -17,6 +18,7 @@ import (
 	"github.com/go-gost/core/recorder"
 	"github.com/go-gost/relay"
 	ctxvalue "github.com/go-gost/x/ctx"
+	"github.com/go-gost/x/internal/net/proxyproto"
 	stats_util "github.com/go-gost/x/internal/util/stats"
 	tls_util "github.com/go-gost/x/internal/util/tls"
 	rate_limiter "github.com/go-gost/x/limiter/rate"
@@ -158,6 +160,24 @@ func (h *relayHandler) Handle(ctx context.Context, conn net.Conn, opts ...handle
 		conn.SetReadDeadline(time.Now().Add(h.md.readTimeout))
 	}
 
+	// the previous relay node of a chain may send the address of the original client in a PROXY header,
+	// only trusted when accepting it is enabled, otherwise the stream is left untouched
+	if h.md.proxyProtocolAccept {
+		pc, clientAddr, err := proxyproto.ReadHeader(conn)
+		if err != nil {
+			return err
+		}
+		conn = pc
+		if clientAddr != nil {
+			ctx = ctxvalue.ContextWithClientAddr(ctx, ctxvalue.ClientAddr(clientAddr.String()))
+			ro.ClientIP = clientAddr.String()
+			if h, _, _ := net.SplitHostPort(ro.ClientIP); h != "" {
+				ro.ClientIP = h
+			}
+			log = log.WithFields(map[string]any{"client": ro.ClientIP})
+		}
+	}
+
 	req := relay.Request{}
 	if _, err := req.ReadFrom(conn); err != nil {
 		return err
@@ -240,6 +260,9 @@ func (h *relayHandler) Close() error {
 	if h.cancel != nil {
 		h.cancel()
 	}
+	if closer, ok := h.hop.(io.Closer); ok {
+		closer.Close()
+	}
 	return nil
 }
 
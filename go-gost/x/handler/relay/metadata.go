@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-gost/core/bypass"
 	mdata "github.com/go-gost/core/metadata"
+	"github.com/go-gost/x/internal/util/failover"
 	"github.com/go-gost/x/internal/util/mux"
 	mdutil "github.com/go-gost/x/metadata/util"
 	"github.com/go-gost/x/registry"
@@ -15,11 +16,15 @@ import (
 
 type metadata struct {
 	readTimeout time.Duration
+	idleTimeout time.Duration
 	enableBind  bool
 	noDelay     bool
 	hash        string
 	muxCfg      *mux.Config
 
+	proxyProtocol       int
+	proxyProtocolAccept bool
+
 	observerPeriod       time.Duration
 	observerResetTraffic bool
 
@@ -35,6 +40,8 @@ type metadata struct {
 
 	limiterRefreshInterval time.Duration
 	limiterCleanupInterval time.Duration
+
+	failover failover.Options
 }
 
 func (h *relayHandler) parseMetadata(md mdata.Metadata) (err error) {
@@ -42,11 +49,15 @@ func (h *relayHandler) parseMetadata(md mdata.Metadata) (err error) {
 	if h.md.readTimeout <= 0 {
 		h.md.readTimeout = 15 * time.Second
 	}
+	h.md.idleTimeout = mdutil.GetDuration(md, "idleTimeout")
 
 	h.md.enableBind = mdutil.GetBool(md, "bind")
 	h.md.noDelay = mdutil.GetBool(md, "nodelay")
 	h.md.hash = mdutil.GetString(md, "hash")
 
+	h.md.proxyProtocol = mdutil.GetInt(md, "proxyProtocol")
+	h.md.proxyProtocolAccept = mdutil.GetBool(md, "proxyProtocol.accept")
+
 	h.md.muxCfg = &mux.Config{
 		Version:           mdutil.GetInt(md, "mux.version"),
 		KeepAliveInterval: mdutil.GetDuration(md, "mux.keepaliveInterval"),
@@ -91,5 +102,7 @@ func (h *relayHandler) parseMetadata(md mdata.Metadata) (err error) {
 	h.md.limiterRefreshInterval = mdutil.GetDuration(md, "limiter.refreshInterval")
 	h.md.limiterCleanupInterval = mdutil.GetDuration(md, "limiter.cleanupInterval")
 
+	h.md.failover = failover.ParseOptions(md)
+
 	return
 }
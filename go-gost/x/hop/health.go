@@ -0,0 +1,464 @@
+package hop
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/core/chain"
+)
+
+// default options for HealthCheck
+const (
+	DefaultHealthCheckInterval = 10 * time.Second
+	DefaultHealthCheckTimeout  = 5 * time.Second
+	DefaultHealthCheckRise     = 2
+	DefaultHealthCheckFall     = 3
+)
+
+// probe types of HealthCheck
+const (
+	HealthCheckTCP   = "tcp"
+	HealthCheckTLS   = "tls"
+	HealthCheckHTTP  = "http"
+	HealthCheckHTTPS = "https"
+	HealthCheckSend  = "send"
+)
+
+// HealthCheck is the active health check settings of a hop.
+type HealthCheck struct {
+	Type     string
+	Interval time.Duration
+	Timeout  time.Duration
+	Rise     int
+	Fall     int
+	Path     string
+	Host     string
+	Status   int
+	Send     string
+	Expect   string
+}
+
+func HealthCheckOption(hc *HealthCheck) Option {
+	return func(o *options) {
+		o.healthCheck = hc
+	}
+}
+
+// NodeHealth is the health state of a node.
+type NodeHealth struct {
+	Node    string `json:"node"`
+	Addr    string `json:"addr"`
+	Healthy bool   `json:"healthy"`
+	// Since is the time of the last state change.
+	Since     time.Time `json:"since"`
+	LastCheck time.Time `json:"lastCheck,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+	// Latency is the duration of the last successful probe in milliseconds.
+	Latency int64 `json:"latency"`
+}
+
+type nodeHealth struct {
+	NodeHealth
+	successes int
+	failures  int
+}
+
+// healthChecker probes the nodes of a hop periodically.
+// A node becomes unhealthy after Fall consecutive failed probes
+// and healthy again after Rise consecutive successful probes.
+type healthChecker struct {
+	hop    *chainHop
+	opts   HealthCheck
+	states map[string]*nodeHealth
+	mu     sync.RWMutex
+}
+
+func newHealthChecker(hop *chainHop, opts HealthCheck) *healthChecker {
+	if opts.Type == "" {
+		opts.Type = HealthCheckTCP
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultHealthCheckInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultHealthCheckTimeout
+	}
+	if opts.Rise <= 0 {
+		opts.Rise = DefaultHealthCheckRise
+	}
+	if opts.Fall <= 0 {
+		opts.Fall = DefaultHealthCheckFall
+	}
+
+	return &healthChecker{
+		hop:    hop,
+		opts:   opts,
+		states: make(map[string]*nodeHealth),
+	}
+}
+
+func nodeKey(node *chain.Node) string {
+	return node.Name + "@" + node.Addr
+}
+
+func (c *healthChecker) run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.checkAll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *healthChecker) checkAll(ctx context.Context) {
+	nodes := c.hop.Nodes()
+
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		if node == nil || node.Addr == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(node *chain.Node) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := c.probe(ctx, node)
+			if ctx.Err() != nil {
+				return
+			}
+			c.update(node, err, time.Since(start))
+		}(node)
+	}
+	wg.Wait()
+
+	c.prune(nodes)
+}
+
+// update records the probe result and reports the state change.
+func (c *healthChecker) update(node *chain.Node, err error, latency time.Duration) {
+	now := time.Now()
+
+	c.mu.Lock()
+	key := nodeKey(node)
+	st := c.states[key]
+	if st == nil {
+		st = &nodeHealth{
+			NodeHealth: NodeHealth{
+				Node:    node.Name,
+				Addr:    node.Addr,
+				Healthy: true,
+				Since:   now,
+			},
+		}
+		c.states[key] = st
+	}
+
+	changed := false
+	st.LastCheck = now
+	if err == nil {
+		st.successes++
+		st.failures = 0
+		st.LastError = ""
+		st.Latency = latency.Milliseconds()
+		if !st.Healthy && st.successes >= c.opts.Rise {
+			st.Healthy, st.Since, changed = true, now, true
+		}
+	} else {
+		st.failures++
+		st.successes = 0
+		st.LastError = err.Error()
+		if st.Healthy && st.failures >= c.opts.Fall {
+			st.Healthy, st.Since, changed = false, now, true
+		}
+	}
+	healthy := st.Healthy
+	v := st.NodeHealth
+	c.mu.Unlock()
+
+	// a passing probe proves the node is alive, clear the failures marked by real connections
+	if healthy && err == nil {
+		if marker := node.Marker(); marker != nil {
+			marker.Reset()
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	log := c.hop.options.logger
+	if healthy {
+		log.Infof("node %s(%s) is healthy", node.Name, node.Addr)
+	} else {
+		log.Warnf("node %s(%s) is unhealthy: %v", node.Name, node.Addr, err)
+	}
+	if fn := healthNotifier.Load(); fn != nil {
+		(*fn)(c.hop.options.name, v)
+	}
+}
+
+// prune removes the states of the nodes no longer in the hop.
+func (c *healthChecker) prune(nodes []*chain.Node) {
+	keys := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		if node != nil {
+			keys[nodeKey(node)] = true
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.states {
+		if !keys[key] {
+			delete(c.states, key)
+		}
+	}
+}
+
+func (c *healthChecker) isHealthy(node *chain.Node) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if st := c.states[nodeKey(node)]; st != nil {
+		return st.Healthy
+	}
+	return true
+}
+
+// filter removes unhealthy nodes. If all nodes are unhealthy, the nodes are returned unchanged,
+// a misconfigured probe should not take the whole hop down.
+func (c *healthChecker) filter(nodes []*chain.Node) []*chain.Node {
+	if c == nil || len(nodes) == 0 {
+		return nodes
+	}
+
+	var l []*chain.Node
+	for _, node := range nodes {
+		if c.isHealthy(node) {
+			l = append(l, node)
+		}
+	}
+	if len(l) == 0 {
+		return nodes
+	}
+	return l
+}
+
+func (c *healthChecker) health() []NodeHealth {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	list := make([]NodeHealth, 0, len(c.states))
+	for _, st := range c.states {
+		list = append(list, st.NodeHealth)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Node != list[j].Node {
+			return list[i].Node < list[j].Node
+		}
+		return list[i].Addr < list[j].Addr
+	})
+	return list
+}
+
+func (c *healthChecker) probe(ctx context.Context, node *chain.Node) error {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	network := "tcp"
+	if opts := node.Options(); opts != nil && opts.Network == "unix" {
+		network = opts.Network
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, node.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	switch c.opts.Type {
+	case HealthCheckTLS:
+		return c.handshake(ctx, conn, node)
+	case HealthCheckHTTP, HealthCheckHTTPS:
+		if c.opts.Type == HealthCheckHTTPS {
+			tc := tls.Client(conn, c.tlsConfig(node))
+			if err := tc.HandshakeContext(ctx); err != nil {
+				return err
+			}
+			conn = tc
+		}
+		return c.request(conn, node)
+	case HealthCheckSend:
+		return c.sendExpect(conn)
+	default:
+		return nil
+	}
+}
+
+func (c *healthChecker) tlsConfig(node *chain.Node) *tls.Config {
+	serverName := c.opts.Host
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(node.Addr)
+	}
+	return &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	}
+}
+
+func (c *healthChecker) handshake(ctx context.Context, conn net.Conn, node *chain.Node) error {
+	return tls.Client(conn, c.tlsConfig(node)).HandshakeContext(ctx)
+}
+
+func (c *healthChecker) request(conn net.Conn, node *chain.Node) error {
+	path := c.opts.Path
+	if path == "" {
+		path = "/"
+	}
+	host := c.opts.Host
+	if host == "" {
+		host = node.Addr
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+host+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "gost-health-check")
+	req.Close = true
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if c.opts.Status > 0 {
+		if resp.StatusCode != c.opts.Status {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *healthChecker) sendExpect(conn net.Conn) error {
+	if c.opts.Send != "" {
+		if _, err := conn.Write([]byte(c.opts.Send)); err != nil {
+			return err
+		}
+	}
+	if c.opts.Expect == "" {
+		return nil
+	}
+
+	expect := []byte(c.opts.Expect)
+	buf := make([]byte, 0, 4096)
+	b := make([]byte, 1024)
+	for len(buf) < cap(buf) {
+		n, err := conn.Read(b)
+		buf = append(buf, b[:n]...)
+		if bytes.Contains(buf, expect) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("expected response not received: %v", err)
+		}
+	}
+	return errors.New("expected response not received")
+}
+
+var healthCheckers = struct {
+	m  map[string]*healthChecker
+	mu sync.RWMutex
+}{m: make(map[string]*healthChecker)}
+
+var healthNotifier atomic.Pointer[func(hop string, health NodeHealth)]
+
+// OnHealthChange sets the function called when the health state of a node changes.
+func OnHealthChange(fn func(hop string, health NodeHealth)) {
+	healthNotifier.Store(&fn)
+}
+
+// registerHealthChecker registers the checker by hop name.
+// The states of the previous checker with the same name are carried over,
+// so a hop re-created by a configuration update keeps its known unhealthy nodes.
+func registerHealthChecker(name string, c *healthChecker) {
+	healthCheckers.mu.Lock()
+	defer healthCheckers.mu.Unlock()
+
+	if old := healthCheckers.m[name]; old != nil && old != c {
+		old.mu.RLock()
+		c.mu.Lock()
+		for k, v := range old.states {
+			st := *v
+			c.states[k] = &st
+		}
+		c.mu.Unlock()
+		old.mu.RUnlock()
+	}
+	healthCheckers.m[name] = c
+}
+
+func unregisterHealthChecker(name string, c *healthChecker) {
+	healthCheckers.mu.Lock()
+	defer healthCheckers.mu.Unlock()
+
+	if healthCheckers.m[name] == c {
+		delete(healthCheckers.m, name)
+	}
+}
+
+// Health returns the health states of the nodes in the named hop,
+// false if the hop has no health check.
+func Health(name string) ([]NodeHealth, bool) {
+	healthCheckers.mu.RLock()
+	c := healthCheckers.m[name]
+	healthCheckers.mu.RUnlock()
+
+	if c == nil {
+		return nil, false
+	}
+	return c.health(), true
+}
+
+// AllHealth returns the health states of all hops with health check.
+func AllHealth() map[string][]NodeHealth {
+	healthCheckers.mu.RLock()
+	defer healthCheckers.mu.RUnlock()
+
+	m := make(map[string][]NodeHealth, len(healthCheckers.m))
+	for name, c := range healthCheckers.m {
+		m[name] = c.health()
+	}
+	return m
+}
@@ -18,7 +18,9 @@ import (
 	"github.com/go-gost/core/selector"
 	"github.com/go-gost/x/config"
 	node_parser "github.com/go-gost/x/config/parsing/node"
+	ctxvalue "github.com/go-gost/x/ctx"
 	"github.com/go-gost/x/internal/loader"
+	xs "github.com/go-gost/x/selector"
 )
 
 type options struct {
@@ -31,6 +33,7 @@ type options struct {
 	httpLoader  loader.Loader
 	period      time.Duration
 	logger      logger.Logger
+	healthCheck *HealthCheck
 }
 
 type Option func(*options)
@@ -92,6 +95,7 @@ type chainHop struct {
 	mu         sync.RWMutex
 	cancelFunc context.CancelFunc
 	options    options
+	health     *healthChecker
 }
 
 func NewHop(opts ...Option) hop.Hop {
@@ -114,6 +118,11 @@ func NewHop(opts ...Option) hop.Hop {
 	if p.options.period > 0 {
 		go p.periodReload(ctx)
 	}
+	if p.options.healthCheck != nil {
+		p.health = newHealthChecker(p, *p.options.healthCheck)
+		registerHealthChecker(p.options.name, p.health)
+		go p.health.run(ctx)
+	}
 
 	return p
 }
@@ -143,7 +152,7 @@ func (p *chainHop) Select(ctx context.Context, opts ...hop.SelectOption) *chain.
 
 	var nodes []*chain.Node
 	for _, node := range p.Nodes() {
-		if node == nil {
+		if node == nil || ctxvalue.IsNodeExcluded(ctx, node) {
 			continue
 		}
 		// node level bypass
@@ -174,6 +183,7 @@ func (p *chainHop) Select(ctx context.Context, opts ...hop.SelectOption) *chain.
 
 		nodes = append(nodes, node)
 	}
+	nodes = p.health.filter(nodes)
 	if len(nodes) == 0 {
 		return nil
 	}
@@ -292,6 +302,7 @@ func (p *chainHop) reload(ctx context.Context) (err error) {
 	defer p.mu.Unlock()
 
 	p.nodes = nodes
+	xs.RetainNodes(p, nodes)
 
 	return
 }
@@ -354,6 +365,10 @@ func (p *chainHop) parseNode(r io.Reader) ([]*chain.Node, error) {
 
 func (p *chainHop) Close() error {
 	p.cancelFunc()
+	xs.RetainNodes(p, nil)
+	if p.health != nil {
+		unregisterHealthChecker(p.options.name, p.health)
+	}
 	if p.options.fileLoader != nil {
 		p.options.fileLoader.Close()
 	}
@@ -1,6 +1,7 @@
 package net
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -119,3 +120,31 @@ func (c *readWriteConn) Read(p []byte) (int, error) {
 func (c *readWriteConn) Write(p []byte) (int, error) {
 	return c.w.Write(p)
 }
+
+// Unwrap implements Splicer.
+func (c *readWriteConn) Unwrap() io.ReadWriter {
+	if rw, ok := c.w.(io.ReadWriter); ok && any(c.r) == any(c.w) {
+		return rw
+	}
+	return c.Conn
+}
+
+// Spliceable implements Splicer. The connection can be bypassed if it reads from and writes to
+// the same object, or reads from a drained bufio.Reader, which is always a reader of the connection itself.
+func (c *readWriteConn) Spliceable(read bool) bool {
+	if any(c.r) == any(c.w) {
+		return true
+	}
+	if !read {
+		return any(c.w) == any(c.Conn)
+	}
+	if br, ok := c.r.(*bufio.Reader); ok {
+		return br.Buffered() == 0
+	}
+	return any(c.r) == any(c.Conn)
+}
+
+// Spliced implements Splicer.
+func (c *readWriteConn) Spliced(n int, read bool) error {
+	return nil
+}
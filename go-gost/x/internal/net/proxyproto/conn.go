@@ -1,17 +1,155 @@
 package proxyproto
 
 import (
+	"bufio"
+	"context"
 	"net"
+	"strconv"
 
+	"github.com/go-gost/core/chain"
+	ctxvalue "github.com/go-gost/x/ctx"
+	xnet "github.com/go-gost/x/internal/net"
+	mdutil "github.com/go-gost/x/metadata/util"
 	proxyproto "github.com/pires/go-proxyproto"
 )
 
-func WrapClientConn(ppv int, src, dst net.Addr, c net.Conn) net.Conn {
-	if ppv <= 0 {
-		return c
+// the first bytes of the version 1 ("PROXY ") and version 2 ("\r\n\r\n\x00\r\nQUIT\n") signatures
+const (
+	sigV1 = 'P'
+	sigV2 = '\r'
+)
+
+// WrapConn sends a PROXY header carrying the src and dst addresses to the server side of c.
+// For TCP the header of version ppv is written once before any data,
+// for UDP every datagram is prefixed with a version 2 header, as version 1 does not support UDP.
+// c is closed if the header can not be sent.
+func WrapConn(ppv int, network string, src, dst net.Addr, c net.Conn) (net.Conn, error) {
+	if ppv <= 0 || c == nil {
+		return c, nil
+	}
+
+	switch network {
+	case "udp", "udp4", "udp6":
+		src, dst = convertAddrs(src, dst, true)
+		b, err := proxyproto.HeaderProxyFromAddrs(2, src, dst).Format()
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		return &packetConn{Conn: c, header: b}, nil
+	default:
+		src, dst = convertAddrs(src, dst, false)
+		if _, err := proxyproto.HeaderProxyFromAddrs(byte(ppv), src, dst).WriteTo(c); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+}
+
+// Version returns the PROXY protocol version used for the node,
+// the proxyProtocol metadata of the node overrides the version ppv of the handler.
+func Version(node *chain.Node, ppv int) int {
+	if node == nil {
+		return ppv
+	}
+	if md := node.Metadata(); mdutil.IsExists(md, "proxyProtocol") {
+		return mdutil.GetInt(md, "proxyProtocol")
+	}
+	return ppv
+}
+
+// ClientAddr returns the address of the original client of the connection in ctx, or addr if unknown.
+func ClientAddr(ctx context.Context, addr net.Addr) net.Addr {
+	if v := ctxvalue.ClientAddrFromContext(ctx); v != "" {
+		host, port, _ := net.SplitHostPort(string(v))
+		if ip := net.ParseIP(host); ip != nil {
+			p, _ := strconv.Atoi(port)
+			return &net.TCPAddr{IP: ip, Port: p}
+		}
+	}
+	return addr
+}
+
+// convertAddrs converts the addresses to the same transport and IP family,
+// otherwise the header falls back to the LOCAL command without addresses.
+func convertAddrs(src, dst net.Addr, udp bool) (net.Addr, net.Addr) {
+	srcIP, srcPort := splitAddr(src)
+	dstIP, dstPort := splitAddr(dst)
+	if srcIP == nil {
+		return src, dst
+	}
+	if dstIP == nil || dstIP.IsUnspecified() {
+		dstIP = net.IPv4zero
+	}
+
+	if ip4 := srcIP.To4(); ip4 != nil {
+		srcIP = ip4
+		if dstIP = dstIP.To4(); dstIP == nil {
+			dstIP = net.IPv4zero
+		}
+	} else {
+		dstIP = dstIP.To16()
 	}
 
-	header := proxyproto.HeaderProxyFromAddrs(byte(ppv), src, dst)
-	header.WriteTo(c)
-	return c
+	if udp {
+		return &net.UDPAddr{IP: srcIP, Port: srcPort}, &net.UDPAddr{IP: dstIP, Port: dstPort}
+	}
+	return &net.TCPAddr{IP: srcIP, Port: srcPort}, &net.TCPAddr{IP: dstIP, Port: dstPort}
+}
+
+func splitAddr(addr net.Addr) (net.IP, int) {
+	switch v := addr.(type) {
+	case *net.TCPAddr:
+		return v.IP, v.Port
+	case *net.UDPAddr:
+		return v.IP, v.Port
+	case nil:
+		return nil, 0
+	}
+
+	host, port, _ := net.SplitHostPort(addr.String())
+	p, _ := strconv.Atoi(port)
+	return net.ParseIP(host), p
+}
+
+// packetConn prefixes every datagram written with the header.
+type packetConn struct {
+	net.Conn
+	header []byte
+}
+
+func (c *packetConn) Write(b []byte) (int, error) {
+	buf := make([]byte, 0, len(c.header)+len(b))
+	buf = append(buf, c.header...)
+	buf = append(buf, b...)
+	if _, err := c.Conn.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// ReadHeader reads the optional PROXY header at the beginning of c.
+// It returns the connection with the header consumed and the source address in the header,
+// the address is nil if c does not start with a header or the header carries no address.
+func ReadHeader(c net.Conn) (net.Conn, net.Addr, error) {
+	br := bufio.NewReader(c)
+	// look at the first byte only, the protocol following may send less than a header length and wait
+	b, err := br.Peek(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b[0] != sigV1 && b[0] != sigV2 {
+		return xnet.NewReadWriteConn(br, c, c), nil, nil
+	}
+
+	header, err := proxyproto.Read(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	c = xnet.NewReadWriteConn(br, c, c)
+	if header.Command != proxyproto.PROXY {
+		return c, nil, nil
+	}
+	return c, header.SourceAddr, nil
 }
@@ -0,0 +1,239 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	proxyproto "github.com/pires/go-proxyproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bufConn records the data written to it.
+type bufConn struct {
+	net.Conn
+	writes [][]byte
+	closed bool
+}
+
+func (c *bufConn) Write(b []byte) (int, error) {
+	c.writes = append(c.writes, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func (c *bufConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *bufConn) data() []byte {
+	return bytes.Join(c.writes, nil)
+}
+
+func TestWrapConnTCP(t *testing.T) {
+	testCases := []struct {
+		desc string
+		ppv  int
+		src  net.Addr
+		dst  net.Addr
+		// expect is the version 1 header, empty to check the addresses of the parsed header
+		expect    string
+		expectSrc string
+		expectDst string
+	}{
+		{
+			desc:   "version 1 IPv4",
+			ppv:    1,
+			src:    &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1000},
+			dst:    &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 80},
+			expect: "PROXY TCP4 192.0.2.1 198.51.100.1 1000 80\r\n",
+		},
+		{
+			desc:   "version 1 IPv6",
+			ppv:    1,
+			src:    &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1000},
+			dst:    &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 80},
+			expect: "PROXY TCP6 2001:db8::1 2001:db8::2 1000 80\r\n",
+		},
+		{
+			desc:   "unspecified destination",
+			ppv:    1,
+			src:    &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1000},
+			dst:    &net.TCPAddr{IP: net.IPv6unspecified, Port: 80},
+			expect: "PROXY TCP4 192.0.2.1 0.0.0.0 1000 80\r\n",
+		},
+		{
+			desc:   "IPv4 client on an IPv6 listener",
+			ppv:    1,
+			src:    &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1000},
+			dst:    &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 80},
+			expect: "PROXY TCP4 192.0.2.1 0.0.0.0 1000 80\r\n",
+		},
+		{
+			desc:      "version 2",
+			ppv:       2,
+			src:       &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1000},
+			dst:       &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 80},
+			expectSrc: "192.0.2.1:1000",
+			expectDst: "198.51.100.1:80",
+		},
+		{
+			desc:      "string addresses",
+			ppv:       2,
+			src:       &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1000},
+			dst:       &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443},
+			expectSrc: "[2001:db8::1]:1000",
+			expectDst: "[2001:db8::2]:443",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			c := &bufConn{}
+			conn, err := WrapConn(test.ppv, "tcp", test.src, test.dst, c)
+			require.NoError(t, err)
+			_, err = conn.Write([]byte("data"))
+			require.NoError(t, err)
+
+			if test.expect != "" {
+				assert.Equal(t, test.expect+"data", string(c.data()))
+				return
+			}
+
+			br := bufio.NewReader(bytes.NewReader(c.data()))
+			header, err := proxyproto.Read(br)
+			require.NoError(t, err)
+			assert.Equal(t, byte(test.ppv), header.Version)
+			assert.Equal(t, proxyproto.PROXY, header.Command)
+			assert.Equal(t, test.expectSrc, header.SourceAddr.String())
+			assert.Equal(t, test.expectDst, header.DestinationAddr.String())
+
+			rest, _ := io.ReadAll(br)
+			assert.Equal(t, "data", string(rest))
+		})
+	}
+}
+
+func TestWrapConnDisabled(t *testing.T) {
+	c := &bufConn{}
+	conn, err := WrapConn(0, "tcp", &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1000}, nil, c)
+	require.NoError(t, err)
+	assert.Same(t, c, conn)
+	assert.Empty(t, c.writes)
+}
+
+func TestWrapConnUDP(t *testing.T) {
+	c := &bufConn{}
+	src := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1000}
+	dst := &net.UDPAddr{IP: net.ParseIP("198.51.100.1"), Port: 53}
+
+	// version 1 does not support UDP, version 2 is used for every datagram
+	conn, err := WrapConn(1, "udp", src, dst, c)
+	require.NoError(t, err)
+
+	for _, payload := range []string{"first", "second"} {
+		n, err := conn.Write([]byte(payload))
+		require.NoError(t, err)
+		assert.Equal(t, len(payload), n)
+	}
+	require.Len(t, c.writes, 2)
+
+	for i, payload := range []string{"first", "second"} {
+		br := bufio.NewReader(bytes.NewReader(c.writes[i]))
+		header, err := proxyproto.Read(br)
+		require.NoError(t, err)
+		assert.Equal(t, byte(2), header.Version)
+		assert.Equal(t, proxyproto.UDPv4, header.TransportProtocol)
+		assert.Equal(t, src.String(), header.SourceAddr.String())
+
+		rest, _ := io.ReadAll(br)
+		assert.Equal(t, payload, string(rest))
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1000}
+	dst := &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 80}
+
+	header := func(version byte) string {
+		b, err := proxyproto.HeaderProxyFromAddrs(version, src, dst).Format()
+		require.NoError(t, err)
+		return string(b)
+	}
+	local := func() string {
+		b, err := (&proxyproto.Header{Version: 2, Command: proxyproto.LOCAL}).Format()
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	testCases := []struct {
+		desc       string
+		data       string
+		expectSrc  string
+		expectData string
+		expectErr  bool
+	}{
+		{
+			desc:       "no header",
+			data:       "\x01relay request",
+			expectData: "\x01relay request",
+		},
+		{
+			desc:       "version 1",
+			data:       header(1) + "data",
+			expectSrc:  src.String(),
+			expectData: "data",
+		},
+		{
+			desc:       "version 2",
+			data:       header(2) + "data",
+			expectSrc:  src.String(),
+			expectData: "data",
+		},
+		{
+			desc:       "local command",
+			data:       local() + "data",
+			expectData: "data",
+		},
+		{
+			desc:      "malformed header",
+			data:      "PROXY TCP4 192.0.2.1\r\n",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client, server := net.Pipe()
+			defer server.Close()
+			go func() {
+				client.Write([]byte(test.data))
+				client.Close()
+			}()
+
+			conn, addr, err := ReadHeader(server)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if test.expectSrc == "" {
+				assert.Nil(t, addr)
+			} else {
+				require.NotNil(t, addr)
+				assert.Equal(t, test.expectSrc, addr.String())
+			}
+
+			rest, _ := io.ReadAll(conn)
+			assert.Equal(t, test.expectData, string(rest))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+//go:build linux
+
+package net
+
+import (
+	"errors"
+	"io"
+	"syscall"
+)
+
+const (
+	spliceMove     = 0x1 // SPLICE_F_MOVE
+	spliceNonblock = 0x2 // SPLICE_F_NONBLOCK
+)
+
+// splicer moves the data from src to dst through a pipe with splice(2).
+type splicer struct {
+	src, dst   spliceChain
+	rsrc, rdst syscall.RawConn
+	pipe       [2]int
+	hasPipe    bool
+}
+
+// newSplicer returns nil if src and dst are not TCP connections, or wrappers of TCP connections implementing Splicer.
+func newSplicer(dst io.Writer, src io.Reader) *splicer {
+	sc, ok := unwrapTCP(src)
+	if !ok {
+		return nil
+	}
+	dc, ok := unwrapTCP(dst)
+	if !ok {
+		return nil
+	}
+
+	rsrc, err := sc.conn.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	rdst, err := dc.conn.SyscallConn()
+	if err != nil {
+		return nil
+	}
+
+	return &splicer{
+		src:  sc,
+		dst:  dc,
+		rsrc: rsrc,
+		rdst: rdst,
+	}
+}
+
+func (s *splicer) ready() bool {
+	return s.src.spliceable(true) && s.dst.spliceable(false)
+}
+
+// splice moves the data available in src to dst, up to the pipe size.
+// It returns io.EOF when src reaches EOF, errSpliceUnsupported if nothing was moved because splice is not available.
+func (s *splicer) splice() (int, error) {
+	if !s.hasPipe {
+		if err := syscall.Pipe2(s.pipe[:], syscall.O_CLOEXEC|syscall.O_NONBLOCK); err != nil {
+			return 0, errSpliceUnsupported
+		}
+		s.hasPipe = true
+	}
+
+	var n int64
+	var serr error
+	err := s.rsrc.Read(func(fd uintptr) bool {
+		n, serr = syscall.Splice(int(fd), nil, s.pipe[1], nil, bufferSize, spliceMove|spliceNonblock)
+		return serr != syscall.EAGAIN && serr != syscall.EINTR
+	})
+	if err == nil {
+		err = serr
+	}
+	if err != nil {
+		if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EOPNOTSUPP) {
+			return 0, errSpliceUnsupported
+		}
+		return 0, err
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+
+	if err := s.src.spliced(int(n), true); err != nil {
+		return 0, err
+	}
+	if err := s.dst.spliced(int(n), false); err != nil {
+		return 0, err
+	}
+
+	for remain := n; remain > 0; {
+		var m int64
+		err := s.rdst.Write(func(fd uintptr) bool {
+			m, serr = syscall.Splice(s.pipe[0], nil, int(fd), nil, int(remain), spliceMove|spliceNonblock)
+			return serr != syscall.EAGAIN && serr != syscall.EINTR
+		})
+		if err == nil {
+			err = serr
+		}
+		if err != nil {
+			return int(n - remain), err
+		}
+		remain -= m
+	}
+
+	return int(n), nil
+}
+
+func (s *splicer) close() {
+	if s.hasPipe {
+		syscall.Close(s.pipe[0])
+		syscall.Close(s.pipe[1])
+		s.hasPipe = false
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package net
+
+import "io"
+
+type splicer struct{}
+
+func newSplicer(dst io.Writer, src io.Reader) *splicer {
+	return nil
+}
+
+func (s *splicer) ready() bool {
+	return false
+}
+
+func (s *splicer) splice() (int, error) {
+	return 0, errSpliceUnsupported
+}
+
+func (s *splicer) close() {}
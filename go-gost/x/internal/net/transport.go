@@ -1,29 +1,103 @@
 package net
 
 import (
+	"errors"
 	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-gost/core/common/bufpool"
 )
 
 const (
 	bufferSize = 64 * 1024
+	// DefaultHalfCloseTimeout is the idle timeout of the remaining direction after the other direction is half-closed,
+	// used when Transport has no idle timeout.
+	DefaultHalfCloseTimeout = 60 * time.Second
 )
 
-func Transport(rw1, rw2 io.ReadWriter) error {
-	errc := make(chan error, 1)
+var (
+	ErrIdleTimeout = errors.New("idle timeout")
+
+	errSpliceUnsupported = errors.New("splice not supported")
+)
+
+// Splicer is implemented by connection wrappers which only observe or throttle the data passing through,
+// so the data can bypass them when it is moved between the underlying TCP connections by the kernel.
+type Splicer interface {
+	// Unwrap returns the wrapped connection.
+	Unwrap() io.ReadWriter
+	// Spliceable reports whether the read (read is true) or write side of the wrapper can be bypassed now,
+	// e.g. false while the wrapper holds buffered data.
+	Spliceable(read bool) bool
+	// Spliced is called with the number of bytes bypassing the wrapper, read is true for the data read from it.
+	// Rate limiters wait here, a non-nil error stops the transfer.
+	Spliced(n int, read bool) error
+}
+
+type TransportOptions struct {
+	IdleTimeout time.Duration
+}
+
+type TransportOption func(opts *TransportOptions)
+
+// IdleTimeoutTransportOption closes the transport when no data is transferred in either direction for d.
+func IdleTimeoutTransportOption(d time.Duration) TransportOption {
+	return func(opts *TransportOptions) {
+		opts.IdleTimeout = d
+	}
+}
+
+// Transport copies the data between rw1 and rw2 in both directions.
+//
+// When one direction reaches EOF, the write side of its destination is closed (half-close) if supported,
+// and the opposite direction continues until it is finished too, or idle longer than the idle timeout
+// (DefaultHalfCloseTimeout if not set). If the half-close is not supported, Transport returns
+// as soon as the first direction is finished.
+//
+// On Linux the data between two TCP connections is moved with splice(2) without copying to user space,
+// wrappers implementing Splicer are bypassed and notified of the transferred bytes.
+func Transport(rw1, rw2 io.ReadWriter, opts ...TransportOption) error {
+	var options TransportOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	w := newIdleWatcher(options.IdleTimeout, rw1, rw2)
+	defer w.stop()
+
+	type result struct {
+		halfClosed bool
+		err        error
+	}
+	resc := make(chan result, 2)
 	go func() {
-		errc <- CopyBuffer(rw1, rw2, bufferSize)
+		halfClosed, err := copyHalf(rw1, rw2, w)
+		resc <- result{halfClosed, err}
 	}()
-
 	go func() {
-		errc <- CopyBuffer(rw2, rw1, bufferSize)
+		halfClosed, err := copyHalf(rw2, rw1, w)
+		resc <- result{halfClosed, err}
 	}()
 
-	if err := <-errc; err != nil && err != io.EOF {
-		return err
+	res := <-resc
+	if res.err == nil && res.halfClosed {
+		if options.IdleTimeout <= 0 {
+			w.reset(DefaultHalfCloseTimeout)
+		}
+		res = <-resc
 	}
 
+	if w.expired() {
+		return ErrIdleTimeout
+	}
+	if res.err != nil && res.err != io.EOF {
+		return res.err
+	}
 	return nil
 }
 
@@ -34,3 +108,201 @@ func CopyBuffer(dst io.Writer, src io.Reader, bufSize int) error {
 	_, err := io.CopyBuffer(dst, src, buf)
 	return err
 }
+
+// copyHalf copies src to dst until EOF, then closes the write side of dst.
+func copyHalf(dst io.Writer, src io.Reader, w *idleWatcher) (halfClosed bool, err error) {
+	if err = copyData(dst, src, w); err != nil {
+		return
+	}
+	return closeWrite(dst) == nil, nil
+}
+
+func copyData(dst io.Writer, src io.Reader, w *idleWatcher) error {
+	sp := newSplicer(dst, src)
+	if sp != nil {
+		defer sp.close()
+	}
+
+	var buf []byte
+	defer func() {
+		if buf != nil {
+			bufpool.Put(buf)
+		}
+	}()
+
+	for {
+		if sp != nil && sp.ready() {
+			n, err := sp.splice()
+			if n > 0 {
+				w.touch()
+			}
+			if err == errSpliceUnsupported {
+				sp.close()
+				sp = nil
+				continue
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if buf == nil {
+			buf = bufpool.Get(bufferSize)
+		}
+		nr, er := src.Read(buf)
+		if nr > 0 {
+			w.touch()
+			nw, ew := dst.Write(buf[:nr])
+			if ew != nil {
+				return ew
+			}
+			if nw != nr {
+				return io.ErrShortWrite
+			}
+		}
+		if er == io.EOF {
+			return nil
+		}
+		if er != nil {
+			return er
+		}
+	}
+}
+
+// closeWrite closes the write side of the connection behind the wrappers.
+func closeWrite(v any) error {
+	for v != nil {
+		switch t := v.(type) {
+		case interface{ CloseWrite() error }:
+			return t.CloseWrite()
+		case Splicer:
+			v = t.Unwrap()
+		default:
+			return errors.ErrUnsupported
+		}
+	}
+	return errors.ErrUnsupported
+}
+
+// spliceChain is a connection unwrapped down to the TCP connection.
+type spliceChain struct {
+	wrappers []Splicer
+	conn     *net.TCPConn
+}
+
+func unwrapTCP(v any) (c spliceChain, ok bool) {
+	for v != nil {
+		switch t := v.(type) {
+		case *net.TCPConn:
+			c.conn = t
+			return c, true
+		case Splicer:
+			c.wrappers = append(c.wrappers, t)
+			v = t.Unwrap()
+		default:
+			return
+		}
+	}
+	return
+}
+
+func (c *spliceChain) spliceable(read bool) bool {
+	for _, w := range c.wrappers {
+		if !w.Spliceable(read) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *spliceChain) spliced(n int, read bool) error {
+	for _, w := range c.wrappers {
+		if err := w.Spliced(n, read); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// idleWatcher interrupts the transport when no data is transferred for the timeout.
+type idleWatcher struct {
+	rws      []io.ReadWriter
+	timeout  time.Duration
+	last     atomic.Int64
+	timer    *time.Timer
+	timedOut bool
+	stopped  bool
+	mu       sync.Mutex
+}
+
+func newIdleWatcher(timeout time.Duration, rws ...io.ReadWriter) *idleWatcher {
+	w := &idleWatcher{
+		rws: rws,
+	}
+	w.touch()
+	w.reset(timeout)
+	return w
+}
+
+func (w *idleWatcher) touch() {
+	w.last.Store(time.Now().UnixNano())
+}
+
+func (w *idleWatcher) reset(timeout time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopped || timeout <= 0 {
+		return
+	}
+	w.timeout = timeout
+	if w.timer == nil {
+		w.timer = time.AfterFunc(timeout, w.check)
+		return
+	}
+	w.timer.Reset(timeout)
+}
+
+func (w *idleWatcher) check() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopped {
+		return
+	}
+	if idle := time.Since(time.Unix(0, w.last.Load())); idle < w.timeout {
+		w.timer.Reset(w.timeout - idle)
+		return
+	}
+
+	w.timedOut = true
+	for _, rw := range w.rws {
+		switch v := rw.(type) {
+		case interface{ SetReadDeadline(time.Time) error }:
+			v.SetReadDeadline(time.Now())
+		case io.Closer:
+			v.Close()
+		}
+	}
+}
+
+func (w *idleWatcher) expired() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.timedOut
+}
+
+func (w *idleWatcher) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stopped = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
@@ -0,0 +1,173 @@
+package net
+
+import (
+	"io"
+	"net"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spliceConn is a Splicer counting the bytes bypassing it.
+type spliceConn struct {
+	net.Conn
+	spliceable bool
+	read       atomic.Int64
+	written    atomic.Int64
+}
+
+func (c *spliceConn) Unwrap() io.ReadWriter {
+	return c.Conn
+}
+
+func (c *spliceConn) Spliceable(read bool) bool {
+	return c.spliceable
+}
+
+func (c *spliceConn) Spliced(n int, read bool) error {
+	if read {
+		c.read.Add(int64(n))
+	} else {
+		c.written.Add(int64(n))
+	}
+	return nil
+}
+
+// tcpPair returns the two ends of a loopback TCP connection.
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	server, err := ln.Accept()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client.(*net.TCPConn), server.(*net.TCPConn)
+}
+
+func TestTransport(t *testing.T) {
+	const (
+		request  = "request"
+		response = "response"
+	)
+
+	testCases := []struct {
+		desc       string
+		wrap       bool
+		spliceable bool
+	}{
+		{
+			desc: "TCP connections",
+		},
+		{
+			desc:       "spliceable wrappers",
+			wrap:       true,
+			spliceable: true,
+		},
+		{
+			desc: "wrappers holding data",
+			wrap: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client, in := tcpPair(t)
+			out, upstream := tcpPair(t)
+
+			var rw1, rw2 io.ReadWriter = in, out
+			var w1, w2 *spliceConn
+			if test.wrap {
+				w1 = &spliceConn{Conn: in, spliceable: test.spliceable}
+				w2 = &spliceConn{Conn: out, spliceable: test.spliceable}
+				rw1, rw2 = w1, w2
+			}
+
+			errc := make(chan error, 1)
+			go func() {
+				errc <- Transport(rw1, rw2)
+			}()
+
+			// the client half-closes after the request, the upstream still sends the response
+			_, err := client.Write([]byte(request))
+			require.NoError(t, err)
+			require.NoError(t, client.CloseWrite())
+
+			b, err := io.ReadAll(upstream)
+			require.NoError(t, err)
+			assert.Equal(t, request, string(b))
+
+			_, err = upstream.Write([]byte(response))
+			require.NoError(t, err)
+			require.NoError(t, upstream.CloseWrite())
+
+			b, err = io.ReadAll(client)
+			require.NoError(t, err)
+			assert.Equal(t, response, string(b))
+
+			select {
+			case err := <-errc:
+				assert.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("transport is not finished")
+			}
+
+			if !test.wrap {
+				return
+			}
+			var expectRequest, expectResponse int64
+			if runtime.GOOS == "linux" && test.spliceable {
+				expectRequest, expectResponse = int64(len(request)), int64(len(response))
+			}
+			assert.Equal(t, expectRequest, w1.read.Load())
+			assert.Equal(t, expectRequest, w2.written.Load())
+			assert.Equal(t, expectResponse, w2.read.Load())
+			assert.Equal(t, expectResponse, w1.written.Load())
+		})
+	}
+}
+
+func TestTransportWithoutHalfClose(t *testing.T) {
+	client, in := net.Pipe()
+	out, upstream := net.Pipe()
+	defer upstream.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Transport(in, out)
+	}()
+
+	// the pipes cannot be half-closed, the transport is finished with the first direction
+	client.Close()
+
+	select {
+	case err := <-errc:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("transport is not finished")
+	}
+}
+
+func TestTransportIdleTimeout(t *testing.T) {
+	_, in := tcpPair(t)
+	out, _ := tcpPair(t)
+
+	start := time.Now()
+	err := Transport(in, out, IdleTimeoutTransportOption(100*time.Millisecond))
+	assert.ErrorIs(t, err, ErrIdleTimeout)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -23,16 +23,28 @@ func NewAESCrypto(secret string) (*AESCrypto, error) {
 
 	// 使用 SHA256 将密码转换为 32 字节密钥
 	hash := sha256.Sum256([]byte(secret))
-	
+
 	return &AESCrypto{
 		key: hash[:],
 	}, nil
 }
 
+// AdditionalData 构造绑定到密文的附加认证数据（AAD）
+// 密钥ID、消息类型、请求ID和时间戳明文放在帧头中，通过 AAD 防止被篡改或挪用到其他命令
+func AdditionalData(keyId, msgType, requestId string, timestamp int64) []byte {
+	return []byte(fmt.Sprintf("%s\n%s\n%s\n%d", keyId, msgType, requestId, timestamp))
+}
+
 // Encrypt 加密数据
 // data: 要加密的原始数据
 // 返回: base64编码的加密数据
 func (a *AESCrypto) Encrypt(data []byte) (string, error) {
+	return a.EncryptWithAD(data, nil)
+}
+
+// EncryptWithAD 加密数据并认证附加数据
+// ad: 附加认证数据，解密时必须提供相同的值
+func (a *AESCrypto) EncryptWithAD(data []byte, ad []byte) (string, error) {
 	if len(data) == 0 {
 		return "", fmt.Errorf("待加密数据不能为空")
 	}
@@ -56,7 +68,7 @@ func (a *AESCrypto) Encrypt(data []byte) (string, error) {
 	}
 
 	// 加密数据
-	ciphertext := gcm.Seal(nil, nonce, data, nil)
+	ciphertext := gcm.Seal(nil, nonce, data, ad)
 
 	// 组合 nonce + ciphertext
 	encrypted := append(nonce, ciphertext...)
@@ -69,6 +81,11 @@ func (a *AESCrypto) Encrypt(data []byte) (string, error) {
 // encryptedData: base64编码的加密数据
 // 返回: 解密后的原始数据
 func (a *AESCrypto) Decrypt(encryptedData string) ([]byte, error) {
+	return a.DecryptWithAD(encryptedData, nil)
+}
+
+// DecryptWithAD 解密数据并校验附加认证数据
+func (a *AESCrypto) DecryptWithAD(encryptedData string, ad []byte) ([]byte, error) {
 	if encryptedData == "" {
 		return nil, fmt.Errorf("加密数据不能为空")
 	}
@@ -101,7 +118,7 @@ func (a *AESCrypto) Decrypt(encryptedData string) ([]byte, error) {
 	ciphertext := encrypted[nonceSize:]
 
 	// 解密数据
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, ad)
 	if err != nil {
 		return nil, fmt.Errorf("解密失败: %v", err)
 	}
@@ -121,4 +138,4 @@ func (a *AESCrypto) DecryptString(encryptedData string) (string, error) {
 		return "", err
 	}
 	return string(plaintext), nil
-} 
\ No newline at end of file
+}
@@ -0,0 +1,292 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// 密钥用途，不同用途使用独立派生的密钥
+const (
+	PurposeCommand = "command" // WebSocket 命令通道
+	PurposeTraffic = "traffic" // 流量上报
+	PurposeConfig  = "config"  // 配置上报
+)
+
+// DefaultKeyId 初始密钥的 ID，由 config.json 中的节点密钥派生
+const DefaultKeyId = "0"
+
+// DefaultKeyringFile 轮换后的密钥状态文件（相对于 agent 工作目录）
+const DefaultKeyringFile = "keyring.json"
+
+var purposes = []string{PurposeCommand, PurposeTraffic, PurposeConfig}
+
+// DeriveKey 使用 HKDF-SHA256 派生指定用途的 32 字节密钥
+// 密钥 ID 作为盐，用途作为 info，同一密钥材料在不同 ID 和用途下得到互不相关的密钥
+func DeriveKey(secret, keyId, purpose string) ([]byte, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("密钥不能为空")
+	}
+	if keyId == "" {
+		return nil, fmt.Errorf("密钥ID不能为空")
+	}
+
+	key := make([]byte, 32)
+	r := hkdf.New(sha256.New, []byte(secret), []byte(keyId), []byte("flux-agent/"+purpose))
+	if _, err := io.ReadFull(r, key); err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %v", err)
+	}
+	return key, nil
+}
+
+// NewAESCryptoWithKey 使用已派生的 32 字节密钥创建 AES 加密器
+func NewAESCryptoWithKey(key []byte) (*AESCrypto, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("密钥长度必须为 32 字节")
+	}
+	return &AESCrypto{key: append([]byte(nil), key...)}, nil
+}
+
+// keySet 同一密钥 ID 下各用途的加密器
+type keySet struct {
+	id      string
+	secret  string // 轮换时下发的新密钥材料，为空表示沿用节点密钥
+	cryptos map[string]*AESCrypto
+}
+
+func newKeySet(base, id, secret string) (*keySet, error) {
+	material := secret
+	if material == "" {
+		material = base
+	}
+
+	ks := &keySet{
+		id:      id,
+		secret:  secret,
+		cryptos: make(map[string]*AESCrypto, len(purposes)),
+	}
+	for _, purpose := range purposes {
+		key, err := DeriveKey(material, id, purpose)
+		if err != nil {
+			return nil, err
+		}
+		ks.cryptos[purpose], _ = NewAESCryptoWithKey(key)
+	}
+	return ks, nil
+}
+
+// keyringState 落盘的密钥状态
+type keyringState struct {
+	Current keyringEntry  `json:"current"`
+	Next    *keyringEntry `json:"next,omitempty"`
+	// Upgraded 面板已表明支持认证帧，重启后也不再接受旧格式
+	Upgraded bool `json:"upgraded,omitempty"`
+}
+
+type keyringEntry struct {
+	Id     string `json:"id"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// Keyring 节点的密钥环
+//
+// 加密始终使用当前密钥；轮换期间同时持有下一个密钥，收到使用下一个密钥的
+// 合法消息后原子地切换为当前密钥，连接无需中断。轮换状态写入文件，
+// 重启后继续使用轮换后的密钥。
+//
+// 旧版面板只认识 SHA-256(secret) 密钥且不使用附加认证数据，
+// 在面板发来第一条派生密钥加密的合法消息之前，节点使用旧格式与面板通信。
+// 升级状态同样写入文件，重启后不会重新接受可被重放的旧格式。
+type Keyring struct {
+	base     string
+	path     string
+	current  *keySet
+	next     *keySet
+	legacy   *AESCrypto
+	upgraded atomic.Bool
+	mu       sync.RWMutex
+}
+
+// NewKeyring 根据节点密钥创建密钥环，path 不为空时从中恢复轮换后的状态
+func NewKeyring(secret, path string) (*Keyring, error) {
+	current, err := newKeySet(secret, DefaultKeyId, "")
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := NewAESCrypto(secret)
+	if err != nil {
+		return nil, err
+	}
+	k := &Keyring{
+		base:    secret,
+		path:    path,
+		current: current,
+		legacy:  legacy,
+	}
+
+	if path == "" {
+		return k, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥状态失败: %v", err)
+	}
+
+	var state keyringState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析密钥状态失败: %v", err)
+	}
+	if k.current, err = newKeySet(secret, state.Current.Id, state.Current.Secret); err != nil {
+		return nil, fmt.Errorf("恢复当前密钥失败: %v", err)
+	}
+	if state.Next != nil {
+		if k.next, err = newKeySet(secret, state.Next.Id, state.Next.Secret); err != nil {
+			return nil, fmt.Errorf("恢复待切换密钥失败: %v", err)
+		}
+	}
+	// 记录升级状态之前的文件只由轮换写入，只有支持派生密钥的面板才会发起轮换
+	k.upgraded.Store(state.Upgraded || state.Current.Id != DefaultKeyId || state.Next != nil)
+	return k, nil
+}
+
+// Legacy 返回旧版面板使用的加密器，密钥为 SHA-256(secret)
+func (k *Keyring) Legacy() *AESCrypto {
+	return k.legacy
+}
+
+// Upgraded 判断面板是否已表明支持派生密钥和认证帧头
+func (k *Keyring) Upgraded() bool {
+	return k.upgraded.Load()
+}
+
+// MarkUpgraded 记录面板支持派生密钥，此后不再接受和发送旧格式，首次记录时返回 true
+// 升级状态立即写入文件，写入失败时本次运行仍按已升级处理，并返回错误
+func (k *Keyring) MarkUpgraded() (bool, error) {
+	if !k.upgraded.CompareAndSwap(false, true) {
+		return false, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return true, k.saveLocked()
+}
+
+// DisableLegacy 完全关闭旧格式，不再等待面板表明支持派生密钥
+// 由节点配置决定，本身不写入文件
+func (k *Keyring) DisableLegacy() {
+	k.upgraded.Store(true)
+}
+
+// Current 返回当前密钥 ID 及指定用途的加密器
+func (k *Keyring) Current(purpose string) (string, *AESCrypto) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.current.id, k.current.cryptos[purpose]
+}
+
+// Lookup 按密钥 ID 查找指定用途的加密器，只接受当前密钥和待切换的密钥
+func (k *Keyring) Lookup(keyId, purpose string) (*AESCrypto, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, ks := range []*keySet{k.current, k.next} {
+		if ks != nil && ks.id == keyId {
+			return ks.cryptos[purpose], nil
+		}
+	}
+	return nil, fmt.Errorf("未知的密钥ID: %s", keyId)
+}
+
+// IsNext 判断密钥 ID 是否为待切换的密钥
+func (k *Keyring) IsNext(keyId string) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.next != nil && k.next.id == keyId
+}
+
+// Stage 登记下一个密钥，secret 为空时从节点密钥派生
+func (k *Keyring) Stage(keyId, secret string) error {
+	if keyId == "" {
+		return errors.New("密钥ID不能为空")
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if keyId == k.current.id {
+		return fmt.Errorf("密钥ID %s 正在使用中", keyId)
+	}
+	next, err := newKeySet(k.base, keyId, secret)
+	if err != nil {
+		return err
+	}
+
+	prev := k.next
+	k.next = next
+	if err := k.saveLocked(); err != nil {
+		k.next = prev
+		return err
+	}
+	return nil
+}
+
+// Promote 将待切换的密钥设为当前密钥，keyId 与待切换密钥不符时返回 false
+func (k *Keyring) Promote(keyId string) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.next == nil || k.next.id != keyId {
+		return false, nil
+	}
+
+	prev := k.current
+	k.current, k.next = k.next, nil
+	if err := k.saveLocked(); err != nil {
+		k.current, k.next = prev, k.current
+		return false, err
+	}
+	return true, nil
+}
+
+// saveLocked 通过临时文件替换的方式写入密钥状态
+func (k *Keyring) saveLocked() error {
+	if k.path == "" {
+		return nil
+	}
+
+	state := keyringState{
+		Current:  keyringEntry{Id: k.current.id, Secret: k.current.secret},
+		Upgraded: k.upgraded.Load(),
+	}
+	if k.next != nil {
+		state.Next = &keyringEntry{Id: k.next.id, Secret: k.next.secret}
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := k.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("保存密钥状态失败: %v", err)
+	}
+	if err := os.Rename(tmp, k.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("保存密钥状态失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveKey(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		secret    string
+		keyId     string
+		purpose   string
+		expect    string
+		expectErr bool
+	}{
+		{
+			desc:    "initial command key",
+			secret:  "sec",
+			keyId:   DefaultKeyId,
+			purpose: PurposeCommand,
+			// HKDF-SHA256(ikm="sec", salt="0", info="flux-agent/command"), shared with the panel
+			expect: "f2984b1deed0e8acb157f0aef758e127a0895e4fcedbae0f0f121b4d842fe7f7",
+		},
+		{
+			desc:      "empty secret",
+			keyId:     DefaultKeyId,
+			purpose:   PurposeCommand,
+			expectErr: true,
+		},
+		{
+			desc:      "empty key id",
+			secret:    "sec",
+			purpose:   PurposeCommand,
+			expectErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			key, err := DeriveKey(test.secret, test.keyId, test.purpose)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expect, hex.EncodeToString(key))
+		})
+	}
+}
+
+func TestDeriveKeyIndependent(t *testing.T) {
+	seen := make(map[string]string)
+	for _, keyId := range []string{DefaultKeyId, "1"} {
+		for _, purpose := range purposes {
+			key, err := DeriveKey("sec", keyId, purpose)
+			require.NoError(t, err)
+
+			name := keyId + "/" + purpose
+			if other, ok := seen[string(key)]; ok {
+				t.Fatalf("%s and %s derive the same key", name, other)
+			}
+			seen[string(key)] = name
+		}
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+
+	k, err := NewKeyring("sec", path)
+	require.NoError(t, err)
+	assert.False(t, k.Upgraded())
+
+	kid, current := k.Current(PurposeTraffic)
+	assert.Equal(t, DefaultKeyId, kid)
+
+	assert.Error(t, k.Stage(DefaultKeyId, ""), "staging the current key id")
+	assert.Error(t, k.Stage("", ""), "staging an empty key id")
+
+	require.NoError(t, k.Stage("1", ""))
+	assert.True(t, k.IsNext("1"))
+
+	// both keys are accepted while the rotation is pending
+	_, err = k.Lookup(DefaultKeyId, PurposeTraffic)
+	assert.NoError(t, err)
+	next, err := k.Lookup("1", PurposeTraffic)
+	require.NoError(t, err)
+	_, err = k.Lookup("2", PurposeTraffic)
+	assert.Error(t, err)
+
+	sealed, err := next.Encrypt([]byte("report"))
+	require.NoError(t, err)
+	_, err = current.Decrypt(sealed)
+	assert.Error(t, err, "keys of different ids must differ")
+
+	ok, err := k.Promote("2")
+	require.NoError(t, err)
+	assert.False(t, ok, "promoting a key that is not staged")
+
+	ok, err = k.Promote("1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	kid, current = k.Current(PurposeTraffic)
+	assert.Equal(t, "1", kid)
+	plain, err := current.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "report", string(plain))
+
+	_, err = k.Lookup(DefaultKeyId, PurposeTraffic)
+	assert.Error(t, err, "the old key is retired after promotion")
+
+	// the rotated state survives a restart and implies an upgraded panel
+	restored, err := NewKeyring("sec", path)
+	require.NoError(t, err)
+	kid, _ = restored.Current(PurposeTraffic)
+	assert.Equal(t, "1", kid)
+	assert.True(t, restored.Upgraded())
+}
+
+func TestKeyringStagedSecret(t *testing.T) {
+	k, err := NewKeyring("sec", "")
+	require.NoError(t, err)
+
+	require.NoError(t, k.Stage("1", "new-material"))
+	staged, err := k.Lookup("1", PurposeCommand)
+	require.NoError(t, err)
+
+	key, err := DeriveKey("new-material", "1", PurposeCommand)
+	require.NoError(t, err)
+	expect, err := NewAESCryptoWithKey(key)
+	require.NoError(t, err)
+
+	sealed, err := expect.Encrypt([]byte("command"))
+	require.NoError(t, err)
+	plain, err := staged.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "command", string(plain))
+}
+
+func TestKeyringLegacy(t *testing.T) {
+	k, err := NewKeyring("sec", "")
+	require.NoError(t, err)
+
+	legacy, err := NewAESCrypto("sec")
+	require.NoError(t, err)
+	sealed, err := legacy.Encrypt([]byte("frame"))
+	require.NoError(t, err)
+
+	plain, err := k.Legacy().Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "frame", string(plain))
+
+	ok, err := k.MarkUpgraded()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = k.MarkUpgraded()
+	require.NoError(t, err)
+	assert.False(t, ok, "the upgrade is recorded once")
+	assert.True(t, k.Upgraded())
+}
+
+func TestKeyringUpgradePersisted(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		upgrade func(k *Keyring)
+		expect  bool
+	}{
+		{
+			desc:    "not upgraded",
+			upgrade: func(k *Keyring) {},
+		},
+		{
+			desc: "upgraded by the panel",
+			upgrade: func(k *Keyring) {
+				_, err := k.MarkUpgraded()
+				require.NoError(t, err)
+			},
+			expect: true,
+		},
+		{
+			desc:    "legacy disabled by the configuration",
+			upgrade: func(k *Keyring) { k.DisableLegacy() },
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "keyring.json")
+			k, err := NewKeyring("sec", path)
+			require.NoError(t, err)
+			test.upgrade(k)
+
+			restored, err := NewKeyring("sec", path)
+			require.NoError(t, err)
+			assert.Equal(t, test.expect, restored.Upgraded())
+
+			kid, _ := restored.Current(PurposeCommand)
+			assert.Equal(t, DefaultKeyId, kid, "the upgrade does not change the key")
+		})
+	}
+}
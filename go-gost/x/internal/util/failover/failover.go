@@ -0,0 +1,106 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/hop"
+	mdata "github.com/go-gost/core/metadata"
+	ctxvalue "github.com/go-gost/x/ctx"
+	mdutil "github.com/go-gost/x/metadata/util"
+	xs "github.com/go-gost/x/selector"
+)
+
+var (
+	ErrNodeUnavailable = errors.New("node not available")
+)
+
+// Options controls in-connection failover.
+type Options struct {
+	// Attempts is the maximum number of nodes tried for one connection,
+	// values less than 2 disable failover.
+	Attempts int
+	// Timeout is the total time budget for all attempts, 0 means no limit.
+	Timeout time.Duration
+}
+
+// ParseOptions parses the failover.attempts and failover.timeout metadata.
+func ParseOptions(md mdata.Metadata) Options {
+	return Options{
+		Attempts: mdutil.GetInt(md, "failover.attempts"),
+		Timeout:  mdutil.GetDuration(md, "failover.timeout"),
+	}
+}
+
+// DialFunc dials the selected node.
+type DialFunc func(ctx context.Context, node *chain.Node) (net.Conn, error)
+
+// Dial selects a node from the hop and dials it. When the dial fails, the node is marked as failed
+// and another node not yet tried is selected, until a dial succeeds, the attempts are used up,
+// the time budget expires or the hop has no more nodes.
+// The returned connection is counted as an active connection of the node until it is closed,
+// and the dial time is recorded for latency aware selector strategies.
+// Without a hop, an empty node is dialed once.
+// It returns the connected node, or the last dial error.
+func Dial(ctx context.Context, h hop.Hop, opts Options, dial DialFunc, selectOpts ...hop.SelectOption) (net.Conn, *chain.Node, error) {
+	if h == nil {
+		// no forwarder, the target is left to the chain, e.g. the exit node of a relay tunnel
+		node := &chain.Node{}
+		conn, err := dial(ctx, node)
+		if err != nil {
+			return nil, nil, err
+		}
+		return conn, node, nil
+	}
+
+	attempts := max(opts.Attempts, 1)
+	if opts.Timeout > 0 && attempts > 1 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	var tried []*chain.Node
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		sctx := ctxvalue.ContextWithExcludedNodes(ctx, tried)
+		node := h.Select(sctx, selectOpts...)
+		// hop plugins may not honor the excluded nodes
+		if node == nil || ctxvalue.IsNodeExcluded(sctx, node) {
+			break
+		}
+
+		// count the connection before dialing so concurrent selections see it
+		release := xs.Acquire(node)
+		start := time.Now()
+		conn, err := dial(ctx, node)
+		if err == nil {
+			xs.ObserveLatency(node, time.Since(start))
+			if marker := node.Marker(); marker != nil {
+				marker.Reset()
+			}
+			return xs.TrackConn(conn, release), node, nil
+		}
+		release()
+
+		// TODO: the router itself may be failed due to the failed node in the router,
+		// the dead marker may be a wrong operation.
+		if marker := node.Marker(); marker != nil {
+			marker.Mark()
+		}
+		lastErr = err
+		tried = append(tried, node)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = ErrNodeUnavailable
+	}
+	return nil, nil, lastErr
+}
@@ -0,0 +1,213 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/hop"
+	ctxvalue "github.com/go-gost/x/ctx"
+	xs "github.com/go-gost/x/selector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listHop selects the first node which is not excluded, as the built-in hop does.
+type listHop struct {
+	nodes []*chain.Node
+}
+
+func (h *listHop) Select(ctx context.Context, opts ...hop.SelectOption) *chain.Node {
+	for _, node := range h.nodes {
+		if !ctxvalue.IsNodeExcluded(ctx, node) {
+			return node
+		}
+	}
+	return nil
+}
+
+// pluginHop ignores the excluded nodes and re-creates the node on every selection,
+// as a hop plugin may do.
+type pluginHop struct {
+	name, addr string
+}
+
+func (h *pluginHop) Select(ctx context.Context, opts ...hop.SelectOption) *chain.Node {
+	return chain.NewNode(h.name, h.addr)
+}
+
+func newNodes(names ...string) []*chain.Node {
+	var nodes []*chain.Node
+	for _, name := range names {
+		nodes = append(nodes, chain.NewNode(name, name+":80"))
+	}
+	return nodes
+}
+
+func TestDial(t *testing.T) {
+	errDial := errors.New("dial failed")
+
+	testCases := []struct {
+		desc   string
+		hop    func(t *testing.T) hop.Hop
+		opts   Options
+		fail   map[string]bool // names of the nodes which fail to dial
+		dialed []string
+		expect string // name of the connected node, empty when the dial fails
+	}{
+		{
+			desc:   "first node",
+			hop:    func(t *testing.T) hop.Hop { return &listHop{nodes: newNodes("a", "b")} },
+			opts:   Options{Attempts: 3},
+			dialed: []string{"a"},
+			expect: "a",
+		},
+		{
+			desc:   "fail over to the next node",
+			hop:    func(t *testing.T) hop.Hop { return &listHop{nodes: newNodes("a", "b", "c")} },
+			opts:   Options{Attempts: 3},
+			fail:   map[string]bool{"a": true},
+			dialed: []string{"a", "b"},
+			expect: "b",
+		},
+		{
+			desc:   "failed nodes are not retried",
+			hop:    func(t *testing.T) hop.Hop { return &listHop{nodes: newNodes("a", "b")} },
+			opts:   Options{Attempts: 5},
+			fail:   map[string]bool{"a": true, "b": true},
+			dialed: []string{"a", "b"},
+		},
+		{
+			desc:   "attempts used up",
+			hop:    func(t *testing.T) hop.Hop { return &listHop{nodes: newNodes("a", "b", "c")} },
+			opts:   Options{Attempts: 2},
+			fail:   map[string]bool{"a": true, "b": true},
+			dialed: []string{"a", "b"},
+		},
+		{
+			desc:   "failover disabled",
+			hop:    func(t *testing.T) hop.Hop { return &listHop{nodes: newNodes("a", "b")} },
+			fail:   map[string]bool{"a": true},
+			dialed: []string{"a"},
+		},
+		{
+			desc:   "hop ignoring the excluded nodes",
+			hop:    func(t *testing.T) hop.Hop { return &pluginHop{name: "a", addr: "a:80"} },
+			opts:   Options{Attempts: 3},
+			fail:   map[string]bool{"a": true},
+			dialed: []string{"a"},
+		},
+		{
+			desc:   "empty hop",
+			hop:    func(t *testing.T) hop.Hop { return &listHop{} },
+			opts:   Options{Attempts: 3},
+			dialed: nil,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var dialed []string
+			dial := func(ctx context.Context, node *chain.Node) (net.Conn, error) {
+				dialed = append(dialed, node.Name)
+				if test.fail[node.Name] {
+					return nil, errDial
+				}
+				c1, c2 := net.Pipe()
+				t.Cleanup(func() { c2.Close() })
+				return c1, nil
+			}
+
+			conn, node, err := Dial(context.Background(), test.hop(t), test.opts, dial)
+			assert.Equal(t, test.dialed, dialed)
+			if test.expect == "" {
+				assert.Error(t, err)
+				assert.Nil(t, conn)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expect, node.Name)
+			conn.Close()
+		})
+	}
+}
+
+func TestDialActiveConns(t *testing.T) {
+	nodes := newNodes("active-a")
+	dial := func(ctx context.Context, node *chain.Node) (net.Conn, error) {
+		c1, c2 := net.Pipe()
+		t.Cleanup(func() { c2.Close() })
+		return c1, nil
+	}
+
+	conn, node, err := Dial(context.Background(), &listHop{nodes: nodes}, Options{}, dial)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), xs.ActiveConns(node))
+	assert.Greater(t, xs.Latency(node), time.Duration(0))
+
+	conn.Close()
+	assert.Equal(t, int64(0), xs.ActiveConns(node))
+}
+
+func TestDialActiveConnsPerOwner(t *testing.T) {
+	dial := func(ctx context.Context, node *chain.Node) (net.Conn, error) {
+		c1, c2 := net.Pipe()
+		t.Cleanup(func() { c2.Close() })
+		return c1, nil
+	}
+
+	// a forwarder and a chain hop with a node of the same name and address
+	forwarder := &listHop{nodes: newNodes("owner-a")}
+	hop := &listHop{nodes: newNodes("owner-a")}
+	xs.RetainNodes(forwarder, forwarder.nodes)
+	xs.RetainNodes(hop, hop.nodes)
+	t.Cleanup(func() {
+		xs.RetainNodes(forwarder, nil)
+		xs.RetainNodes(hop, nil)
+	})
+
+	conn, node, err := Dial(context.Background(), forwarder, Options{}, dial)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, int64(1), xs.ActiveConns(node))
+	assert.Equal(t, int64(0), xs.ActiveConns(hop.nodes[0]))
+
+	// nodes re-created by a reload keep the statistics of the owner
+	reloaded := newNodes("owner-a")
+	xs.RetainNodes(forwarder, reloaded)
+	assert.Equal(t, int64(1), xs.ActiveConns(reloaded[0]))
+
+	conn.Close()
+	assert.Equal(t, int64(0), xs.ActiveConns(reloaded[0]))
+}
+
+func TestDialTimeout(t *testing.T) {
+	var attempts int
+	dial := func(ctx context.Context, node *chain.Node) (net.Conn, error) {
+		attempts++
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	_, _, err := Dial(context.Background(), &listHop{nodes: newNodes("t-a", "t-b", "t-c")},
+		Options{Attempts: 3, Timeout: 20 * time.Millisecond}, dial)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, attempts, "no more attempts after the time budget expires")
+}
+
+func TestDialWithoutHop(t *testing.T) {
+	var dialed int
+	dial := func(ctx context.Context, node *chain.Node) (net.Conn, error) {
+		dialed++
+		return nil, errors.New("dial failed")
+	}
+
+	_, _, err := Dial(context.Background(), nil, Options{Attempts: 3}, dial)
+	assert.Error(t, err)
+	assert.Equal(t, 1, dialed)
+}
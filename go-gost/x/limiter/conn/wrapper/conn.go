@@ -2,6 +2,7 @@ package wrapper
 
 import (
 	"errors"
+	"io"
 	"net"
 	"syscall"
 
@@ -29,6 +30,21 @@ func WrapConn(limiter limiter.Limiter, c net.Conn) net.Conn {
 	}
 }
 
+// Unwrap implements xnet.Splicer.
+func (c *serverConn) Unwrap() io.ReadWriter {
+	return c.Conn
+}
+
+// Spliceable implements xnet.Splicer.
+func (c *serverConn) Spliceable(read bool) bool {
+	return true
+}
+
+// Spliced implements xnet.Splicer.
+func (c *serverConn) Spliced(n int, read bool) error {
+	return nil
+}
+
 func (c *serverConn) SyscallConn() (rc syscall.RawConn, err error) {
 	if sc, ok := c.Conn.(syscall.Conn); ok {
 		rc, err = sc.SyscallConn()
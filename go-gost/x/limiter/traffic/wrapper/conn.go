@@ -90,6 +90,26 @@ func (c *limitConn) Write(b []byte) (n int, err error) {
 	return
 }
 
+// Unwrap implements xnet.Splicer.
+func (c *limitConn) Unwrap() io.ReadWriter {
+	return c.Conn
+}
+
+// Spliceable implements xnet.Splicer.
+func (c *limitConn) Spliceable(read bool) bool {
+	return !read || c.rbuf.Len() == 0
+}
+
+// Spliced implements xnet.Splicer, it waits until the limiter allows n bytes.
+func (c *limitConn) Spliced(n int, read bool) error {
+	limiter := c.limiter.Out(context.Background(), c.key, c.opts...)
+	if read {
+		limiter = c.limiter.In(context.Background(), c.key, c.opts...)
+	}
+	wait(limiter, n)
+	return nil
+}
+
 func (c *limitConn) SyscallConn() (rc syscall.RawConn, err error) {
 	if sc, ok := c.Conn.(syscall.Conn); ok {
 		rc, err = sc.SyscallConn()
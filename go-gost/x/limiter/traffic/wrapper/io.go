@@ -79,3 +79,37 @@ func (p *readWriter) Write(b []byte) (n int, err error) {
 
 	return
 }
+
+// Unwrap implements xnet.Splicer.
+func (p *readWriter) Unwrap() io.ReadWriter {
+	return p.ReadWriter
+}
+
+// Spliceable implements xnet.Splicer.
+func (p *readWriter) Spliceable(read bool) bool {
+	return !read || p.rbuf.Len() == 0
+}
+
+// Spliced implements xnet.Splicer, it waits until the limiter allows n bytes.
+func (p *readWriter) Spliced(n int, read bool) error {
+	limiter := p.limiter.Out(context.Background(), p.key, p.opts...)
+	if read {
+		limiter = p.limiter.In(context.Background(), p.key, p.opts...)
+	}
+	wait(limiter, n)
+	return nil
+}
+
+// wait waits until the limiter allows n bytes, the limiter allows at most its burst size at once.
+func wait(limiter traffic.Limiter, n int) {
+	if limiter == nil || limiter.Limit() <= 0 {
+		return
+	}
+	for n > 0 {
+		v := limiter.Wait(context.Background(), n)
+		if v <= 0 {
+			return
+		}
+		n -= v
+	}
+}
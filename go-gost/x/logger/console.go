@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// consoleFields are the fields of the records published by Printf.
+var consoleFields = logrus.Fields{"kind": "agent"}
+
+// Printf writes an agent message to stdout and publishes it to the log subscriptions
+// with the field kind=agent.
+// Messages starting with ❌ are published at error level, ⚠️ at warn level and others at info level.
+func Printf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprint(os.Stdout, msg)
+
+	level := consoleLevel(msg)
+	if subscribed(level) {
+		publish(level, consoleFields, strings.TrimRight(msg, "\n"))
+	}
+}
+
+// Println is like Printf but formats its arguments as fmt.Println does.
+func Println(args ...any) {
+	Printf("%s", fmt.Sprintln(args...))
+}
+
+func consoleLevel(msg string) logrus.Level {
+	switch {
+	case strings.HasPrefix(msg, "❌"):
+		return logrus.ErrorLevel
+	case strings.HasPrefix(msg, "⚠️"):
+		return logrus.WarnLevel
+	default:
+		return logrus.InfoLevel
+	}
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-gost/core/logger"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	serviceLevels     sync.Map // service name -> logrus.Level
+	serviceLevelCount atomic.Int32
+)
+
+// SetServiceLevel overrides the log level of all loggers carrying the given service field.
+// An empty level removes the override and restores the level of the underlying logger.
+// The previous override is returned, empty if there was none.
+func SetServiceLevel(service string, level logger.LogLevel) (logger.LogLevel, error) {
+	if level == "" {
+		v, ok := serviceLevels.LoadAndDelete(service)
+		if !ok {
+			return "", nil
+		}
+		serviceLevelCount.Add(-1)
+		return logger.LogLevel(v.(logrus.Level).String()), nil
+	}
+
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return "", err
+	}
+	v, loaded := serviceLevels.Swap(service, lvl)
+	if !loaded {
+		serviceLevelCount.Add(1)
+		return "", nil
+	}
+	return logger.LogLevel(v.(logrus.Level).String()), nil
+}
+
+// ServiceLevels returns the current service level overrides.
+func ServiceLevels() map[string]logger.LogLevel {
+	levels := make(map[string]logger.LogLevel)
+	serviceLevels.Range(func(k, v any) bool {
+		levels[k.(string)] = logger.LogLevel(v.(logrus.Level).String())
+		return true
+	})
+	return levels
+}
+
+// ParseLevel parses one of the levels defined by the core logger package.
+func ParseLevel(level logger.LogLevel) (logrus.Level, error) {
+	switch level {
+	case logger.TraceLevel,
+		logger.DebugLevel,
+		logger.InfoLevel,
+		logger.WarnLevel,
+		logger.ErrorLevel,
+		logger.FatalLevel:
+		return logrus.ParseLevel(string(level))
+	default:
+		return logrus.InfoLevel, fmt.Errorf("invalid log level %q", level)
+	}
+}
+
+func serviceLevel(service string) (logrus.Level, bool) {
+	if service == "" || serviceLevelCount.Load() == 0 {
+		return 0, false
+	}
+	v, ok := serviceLevels.Load(service)
+	if !ok {
+		return 0, false
+	}
+	return v.(logrus.Level), true
+}
@@ -45,6 +45,10 @@ func LevelOption(level logger.LogLevel) Option {
 
 type logrusLogger struct {
 	logger *logrus.Entry
+	// verbose shares the output of logger but logs at any level,
+	// used when a service level override is more verbose than logger.
+	verbose *logrus.Logger
+	service string
 }
 
 func NewLogger(opts ...Option) logger.Logger {
@@ -86,6 +90,13 @@ func NewLogger(opts ...Option) logger.Logger {
 
 	l := &logrusLogger{
 		logger: logrus.NewEntry(log),
+		verbose: &logrus.Logger{
+			Out:       log.Out,
+			Hooks:     log.Hooks,
+			Formatter: log.Formatter,
+			Level:     logrus.TraceLevel,
+			ExitFunc:  log.ExitFunc,
+		},
 	}
 	if options.Name != "" {
 		l.logger = l.logger.WithField("logger", options.Name)
@@ -96,8 +107,14 @@ func NewLogger(opts ...Option) logger.Logger {
 
 // WithFields adds new fields to log.
 func (l *logrusLogger) WithFields(fields map[string]any) logger.Logger {
+	service := l.service
+	if v, ok := fields["service"].(string); ok {
+		service = v
+	}
 	return &logrusLogger{
-		logger: l.logger.WithFields(logrus.Fields(fields)),
+		logger:  l.logger.WithFields(logrus.Fields(fields)),
+		verbose: l.verbose,
+		service: service,
 	}
 }
 
@@ -164,28 +181,55 @@ func (l *logrusLogger) Fatalf(format string, args ...any) {
 }
 
 func (l *logrusLogger) GetLevel() logger.LogLevel {
-	return logger.LogLevel(l.logger.Logger.GetLevel().String())
+	return logger.LogLevel(l.level().String())
 }
 
 func (l *logrusLogger) IsLevelEnabled(level logger.LogLevel) bool {
 	lvl, _ := logrus.ParseLevel(string(level))
-	return l.logger.Logger.IsLevelEnabled(lvl)
+	return lvl <= l.level()
+}
+
+// level returns the service level override if any, otherwise the level of the underlying logger.
+func (l *logrusLogger) level() logrus.Level {
+	if lvl, ok := serviceLevel(l.service); ok {
+		return lvl
+	}
+	return l.logger.Logger.GetLevel()
 }
 
 func (l *logrusLogger) log(level logrus.Level, args ...any) {
-	lg := l.logger
-	if l.logger.Logger.IsLevelEnabled(logrus.DebugLevel) {
-		lg = lg.WithField("caller", l.caller(3))
+	enabled := level <= l.level()
+	if !enabled && !subscribed(level) {
+		return
 	}
-	lg.Log(level, args...)
+	l.emit(level, fmt.Sprint(args...), enabled)
 }
 
 func (l *logrusLogger) logf(level logrus.Level, format string, args ...any) {
+	enabled := level <= l.level()
+	if !enabled && !subscribed(level) {
+		return
+	}
+	l.emit(level, fmt.Sprintf(format, args...), enabled)
+}
+
+// emit writes the message if enabled and publishes it to the matching subscriptions.
+func (l *logrusLogger) emit(level logrus.Level, msg string, enabled bool) {
 	lg := l.logger
-	if l.logger.Logger.IsLevelEnabled(logrus.DebugLevel) {
-		lg = lg.WithField("caller", l.caller(3))
+	lvl := l.level()
+	if lvl > lg.Logger.GetLevel() {
+		lg = &logrus.Entry{Logger: l.verbose, Data: lg.Data}
+	}
+	if lvl >= logrus.DebugLevel {
+		lg = lg.WithField("caller", l.caller(4))
+	}
+	if enabled {
+		lg.Log(level, msg)
+	}
+
+	if subscribed(level) {
+		publish(level, lg.Data, msg)
 	}
-	lg.Logf(level, format, args...)
 }
 
 func (l *logrusLogger) caller(skip int) string {
@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/core/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// Record is a log record delivered to subscribers.
+type Record struct {
+	Time    time.Time
+	Level   logger.LogLevel
+	Message string
+	Fields  map[string]any
+}
+
+// Filter selects the records delivered to a subscription. Empty fields match all records.
+type Filter struct {
+	Service string
+	SID     string
+	// Level is the least severe level delivered.
+	Level logger.LogLevel
+}
+
+// Subscription receives the records matching its filter.
+// Records are dropped instead of blocking the logger when the subscriber falls behind.
+type Subscription struct {
+	service string
+	sid     string
+	level   logrus.Level
+	ch      chan Record
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+var (
+	subsMu sync.RWMutex
+	subs   = make(map[*Subscription]struct{})
+	// subsLevel is the most verbose level of all subscriptions, -1 when there are none.
+	subsLevel atomic.Int32
+)
+
+func init() {
+	subsLevel.Store(-1)
+}
+
+// Subscribe registers a subscription buffering up to size records.
+func Subscribe(filter Filter, size int) (*Subscription, error) {
+	level := logrus.TraceLevel
+	if filter.Level != "" {
+		lvl, err := ParseLevel(filter.Level)
+		if err != nil {
+			return nil, err
+		}
+		level = lvl
+	}
+	if size <= 0 {
+		size = 1
+	}
+
+	s := &Subscription{
+		service: filter.Service,
+		sid:     filter.SID,
+		level:   level,
+		ch:      make(chan Record, size),
+	}
+
+	subsMu.Lock()
+	subs[s] = struct{}{}
+	updateSubsLevel()
+	subsMu.Unlock()
+
+	return s, nil
+}
+
+// Records returns the channel of matching records. It is closed by Close.
+func (s *Subscription) Records() <-chan Record {
+	return s.ch
+}
+
+// Dropped returns the number of records dropped because the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unregisters the subscription.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		subsMu.Lock()
+		delete(subs, s)
+		updateSubsLevel()
+		close(s.ch)
+		subsMu.Unlock()
+	})
+}
+
+func (s *Subscription) match(level logrus.Level, fields logrus.Fields) bool {
+	if level > s.level {
+		return false
+	}
+	if s.service != "" && fields["service"] != s.service {
+		return false
+	}
+	if s.sid != "" && fields["sid"] != s.sid {
+		return false
+	}
+	return true
+}
+
+// updateSubsLevel must be called with subsMu held.
+func updateSubsLevel() {
+	level := int32(-1)
+	for s := range subs {
+		level = max(level, int32(s.level))
+	}
+	subsLevel.Store(level)
+}
+
+// subscribed reports whether any subscription may accept a record at level.
+// It is checked before the logger level so subscriptions also see records the logger does not write.
+func subscribed(level logrus.Level) bool {
+	return int32(level) <= subsLevel.Load()
+}
+
+// publish delivers a record to the matching subscriptions.
+func publish(level logrus.Level, fields logrus.Fields, msg string) {
+	subsMu.RLock()
+	defer subsMu.RUnlock()
+
+	var record *Record
+	for s := range subs {
+		if !s.match(level, fields) {
+			continue
+		}
+		if record == nil {
+			record = &Record{
+				Time:    time.Now(),
+				Level:   logger.LogLevel(level.String()),
+				Message: msg,
+				Fields:  recordFields(fields),
+			}
+		}
+		select {
+		case s.ch <- *record:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// recordFields copies the entry fields so the record does not share them with the logger,
+// converting errors to their message as the JSON formatter does.
+func recordFields(fields logrus.Fields) map[string]any {
+	m := make(map[string]any, len(fields))
+	for k, v := range fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		m[k] = v
+	}
+	return m
+}
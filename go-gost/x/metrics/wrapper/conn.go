@@ -64,6 +64,33 @@ func (c *serverConn) Write(b []byte) (n int, err error) {
 	return
 }
 
+// Unwrap implements xnet.Splicer.
+func (c *serverConn) Unwrap() io.ReadWriter {
+	return c.Conn
+}
+
+// Spliceable implements xnet.Splicer.
+func (c *serverConn) Spliceable(read bool) bool {
+	return true
+}
+
+// Spliced implements xnet.Splicer.
+func (c *serverConn) Spliced(n int, read bool) error {
+	name := xmetrics.MetricServiceTransferOutputBytesCounter
+	if read {
+		name = xmetrics.MetricServiceTransferInputBytesCounter
+	}
+	if counter := xmetrics.GetCounter(
+		name,
+		metrics.Labels{
+			"service": c.service,
+			"client":  c.clientIP,
+		}); counter != nil {
+		counter.Add(float64(n))
+	}
+	return nil
+}
+
 func (c *serverConn) SyscallConn() (rc syscall.RawConn, err error) {
 	if sc, ok := c.Conn.(syscall.Conn); ok {
 		rc, err = sc.SyscallConn()
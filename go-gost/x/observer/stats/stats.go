@@ -14,6 +14,9 @@ type Stats struct {
 	inputBytes   atomic.Uint64
 	outputBytes  atomic.Uint64
 	totalErrs    atomic.Uint64
+	// 累计传输字节数，不受流量上报扣减的影响，用于计算速率
+	inputTotal   atomic.Uint64
+	outputTotal  atomic.Uint64
 	resetTraffic bool
 }
 
@@ -36,8 +39,10 @@ func (s *Stats) Add(kind stats.Kind, n int64) {
 		s.currentConns.Add(uint64(n))
 	case stats.KindInputBytes:
 		s.inputBytes.Add(uint64(n))
+		s.inputTotal.Add(uint64(n))
 	case stats.KindOutputBytes:
 		s.outputBytes.Add(uint64(n))
+		s.outputTotal.Add(uint64(n))
 	case stats.KindTotalErrs:
 		if n > 0 {
 			s.totalErrs.Add(uint64(n))
@@ -66,11 +71,28 @@ func (s *Stats) Get(kind stats.Kind) uint64 {
 	return 0
 }
 
+// TrafficTotals 返回累计的输入和输出字节数，只增不减
+func (s *Stats) TrafficTotals() (inputBytes, outputBytes uint64) {
+	if s == nil {
+		return 0, 0
+	}
+	return s.inputTotal.Load(), s.outputTotal.Load()
+}
+
 func (s *Stats) ResetTraffic(reportedInputBytes, reportedOutputBytes uint64) {
 	s.inputBytes.Store(reportedInputBytes)
 	s.outputBytes.Store(reportedOutputBytes)
 }
 
+// DeductTraffic 扣减已确认上报的流量，期间新增的流量不受影响
+func (s *Stats) DeductTraffic(inputBytes, outputBytes uint64) {
+	if s == nil {
+		return
+	}
+	s.inputBytes.Add(^(inputBytes - 1))
+	s.outputBytes.Add(^(outputBytes - 1))
+}
+
 func (s *Stats) Reset() {
 	s.updated.Store(false)
 	s.totalConns.Store(0)
@@ -78,6 +100,8 @@ func (s *Stats) Reset() {
 	s.inputBytes.Store(0)
 	s.outputBytes.Store(0)
 	s.totalErrs.Store(0)
+	s.inputTotal.Store(0)
+	s.outputTotal.Store(0)
 }
 
 func (s *Stats) IsUpdated() bool {
@@ -66,6 +66,26 @@ func (c *conn) Close() error {
 	return c.Conn.Close()
 }
 
+// Unwrap implements xnet.Splicer.
+func (c *conn) Unwrap() io.ReadWriter {
+	return c.Conn
+}
+
+// Spliceable implements xnet.Splicer.
+func (c *conn) Spliceable(read bool) bool {
+	return true
+}
+
+// Spliced implements xnet.Splicer.
+func (c *conn) Spliced(n int, read bool) error {
+	if read {
+		c.stats.Add(stats.KindInputBytes, int64(n))
+	} else {
+		c.stats.Add(stats.KindOutputBytes, int64(n))
+	}
+	return nil
+}
+
 func (c *conn) SyscallConn() (rc syscall.RawConn, err error) {
 	if sc, ok := c.Conn.(syscall.Conn); ok {
 		rc, err = sc.SyscallConn()
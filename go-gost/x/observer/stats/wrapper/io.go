@@ -36,3 +36,23 @@ func (p *readWriter) Write(b []byte) (n int, err error) {
 
 	return
 }
+
+// Unwrap implements xnet.Splicer.
+func (p *readWriter) Unwrap() io.ReadWriter {
+	return p.ReadWriter
+}
+
+// Spliceable implements xnet.Splicer.
+func (p *readWriter) Spliceable(read bool) bool {
+	return true
+}
+
+// Spliced implements xnet.Splicer.
+func (p *readWriter) Spliced(n int, read bool) error {
+	if read {
+		p.stats.Add(stats.KindInputBytes, int64(n))
+	} else {
+		p.stats.Add(stats.KindOutputBytes, int64(n))
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package recorder
+
+import (
+	"sync"
+
+	"github.com/go-gost/core/recorder"
+)
+
+var builtins = struct {
+	m  map[string]recorder.Recorder
+	mu sync.RWMutex
+}{m: make(map[string]recorder.Recorder)}
+
+// RegisterBuiltin registers a recorder provided by the program rather than the configuration.
+// Built-in recorders are registered again each time the configuration is loaded,
+// a recorder in the configuration with the same name takes precedence.
+func RegisterBuiltin(name string, r recorder.Recorder) {
+	builtins.mu.Lock()
+	defer builtins.mu.Unlock()
+
+	builtins.m[name] = r
+}
+
+// Builtins returns all built-in recorders.
+func Builtins() map[string]recorder.Recorder {
+	builtins.mu.RLock()
+	defer builtins.mu.RUnlock()
+
+	m := make(map[string]recorder.Recorder, len(builtins.m))
+	for k, v := range builtins.m {
+		m[k] = v
+	}
+	return m
+}
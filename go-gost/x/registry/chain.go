@@ -32,6 +32,10 @@ type chainWrapper struct {
 	r    *chainRegistry
 }
 
+func (w *chainWrapper) Name() string {
+	return w.name
+}
+
 func (w *chainWrapper) Marker() selector.Marker {
 	v := w.r.get(w.name)
 	if v == nil {
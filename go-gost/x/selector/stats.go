@@ -0,0 +1,214 @@
+package selector
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/core/chain"
+)
+
+// latencyDecay is the weight of the history in the latency EWMA.
+const latencyDecay = 0.7
+
+// nodeStats holds the runtime statistics of a selectable object.
+type nodeStats struct {
+	active  atomic.Int64
+	latency atomic.Uint64 // float64 bits of the EWMA in nanoseconds, 0 means no sample
+}
+
+func (s *nodeStats) observe(d time.Duration) {
+	for {
+		old := s.latency.Load()
+		v := float64(d)
+		if old != 0 {
+			v = latencyDecay*math.Float64frombits(old) + (1-latencyDecay)*v
+		}
+		if v <= 0 {
+			v = 1
+		}
+		if s.latency.CompareAndSwap(old, math.Float64bits(v)) {
+			return
+		}
+	}
+}
+
+func (s *nodeStats) ewma() float64 {
+	return math.Float64frombits(s.latency.Load())
+}
+
+var (
+	// nodeStatsMap holds the statistics of the nodes retained by an owner keyed by the node,
+	// and the statistics of chains and of nodes without an owner keyed by statsKey.
+	nodeStatsMap sync.Map
+
+	// ownerStats holds the statistics of the nodes of each owner, e.g. a hop, keyed by statsKey,
+	// so nodes with the same name and address in different hops are counted separately.
+	ownerStatsMu sync.Mutex
+	ownerStats   = make(map[any]map[string]*nodeStats)
+	ownerNodes   = make(map[any][]*chain.Node)
+)
+
+type namer interface {
+	Name() string
+}
+
+// statsKey identifies a node or a chain across re-creation, e.g. nodes rebuilt by a hop reload
+// or a configuration update share the statistics as long as the name and address are unchanged.
+// The returned key is empty for other objects.
+func statsKey(v any) string {
+	switch t := v.(type) {
+	case *chain.Node:
+		if t != nil {
+			return fmt.Sprintf("node:%s@%s", t.Name, t.Addr)
+		}
+	case chain.Chainer:
+		if n, ok := t.(namer); ok && n.Name() != "" {
+			return "chain:" + n.Name()
+		}
+	}
+	return ""
+}
+
+func statsOf(v any) *nodeStats {
+	if node, ok := v.(*chain.Node); ok && node != nil {
+		if s, ok := nodeStatsMap.Load(node); ok {
+			return s.(*nodeStats)
+		}
+	}
+
+	key := statsKey(v)
+	if key == "" {
+		return nil
+	}
+	if s, ok := nodeStatsMap.Load(key); ok {
+		return s.(*nodeStats)
+	}
+	s, _ := nodeStatsMap.LoadOrStore(key, &nodeStats{})
+	return s.(*nodeStats)
+}
+
+// RetainNodes records nodes as the current nodes of owner, it is called by a hop after each reload.
+// The statistics of the nodes are scoped to owner and kept for the nodes with an unchanged name and address,
+// the statistics of the other nodes are removed. A nil nodes releases all the nodes of owner.
+func RetainNodes(owner any, nodes []*chain.Node) {
+	ownerStatsMu.Lock()
+	defer ownerStatsMu.Unlock()
+
+	old := ownerStats[owner]
+	stats := make(map[string]*nodeStats, len(nodes))
+	retained := make(map[*chain.Node]struct{}, len(nodes))
+	for _, node := range nodes {
+		key := statsKey(node)
+		if key == "" {
+			continue
+		}
+		s := stats[key]
+		if s == nil {
+			s = old[key]
+		}
+		if s == nil {
+			s = &nodeStats{}
+		}
+		stats[key] = s
+		retained[node] = struct{}{}
+		nodeStatsMap.Store(node, s)
+	}
+	for _, node := range ownerNodes[owner] {
+		if _, ok := retained[node]; !ok {
+			nodeStatsMap.Delete(node)
+		}
+	}
+
+	if len(stats) == 0 {
+		delete(ownerStats, owner)
+		delete(ownerNodes, owner)
+		return
+	}
+	ownerStats[owner] = stats
+	ownerNodes[owner] = append([]*chain.Node(nil), nodes...)
+}
+
+// RemoveStats removes the statistics of v, it is called when a chain is closed.
+func RemoveStats(v any) {
+	if key := statsKey(v); key != "" {
+		nodeStatsMap.Delete(key)
+	}
+}
+
+// Acquire records a new active connection of v, the returned function must be called when the connection ends.
+func Acquire(v any) (release func()) {
+	s := statsOf(v)
+	if s == nil {
+		return func() {}
+	}
+	s.active.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { s.active.Add(-1) })
+	}
+}
+
+// ObserveLatency records a dial or connect time of v.
+func ObserveLatency(v any, d time.Duration) {
+	if s := statsOf(v); s != nil {
+		s.observe(d)
+	}
+}
+
+// ActiveConns returns the number of active connections of v.
+func ActiveConns(v any) int64 {
+	if s := statsOf(v); s != nil {
+		return s.active.Load()
+	}
+	return 0
+}
+
+// Latency returns the EWMA of the dial times of v, 0 if v has not been dialed yet.
+func Latency(v any) time.Duration {
+	if s := statsOf(v); s != nil {
+		return time.Duration(s.ewma())
+	}
+	return 0
+}
+
+type activeConn struct {
+	net.Conn
+	release func()
+}
+
+func (c *activeConn) Close() error {
+	c.release()
+	return c.Conn.Close()
+}
+
+// Unwrap implements xnet.Splicer.
+func (c *activeConn) Unwrap() io.ReadWriter {
+	return c.Conn
+}
+
+// Spliceable implements xnet.Splicer.
+func (c *activeConn) Spliceable(read bool) bool {
+	return true
+}
+
+// Spliced implements xnet.Splicer.
+func (c *activeConn) Spliced(n int, read bool) error {
+	return nil
+}
+
+// TrackConn wraps conn so that the active connection acquired by release is released on Close.
+func TrackConn(conn net.Conn, release func()) net.Conn {
+	if conn == nil || release == nil {
+		return conn
+	}
+	return &activeConn{
+		Conn:    conn,
+		release: release,
+	}
+}
@@ -2,8 +2,13 @@ package selector
 
 import (
 	"context"
+	"fmt"
 	"hash/crc32"
+	"math"
 	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -113,3 +118,168 @@ func (s *hashStrategy[T]) Apply(ctx context.Context, vs ...T) (v T) {
 
 	return vs[s.r.Intn(len(vs))]
 }
+
+func weightOf(v any) int {
+	weight := 0
+	if md, _ := v.(metadata.Metadatable); md != nil {
+		weight = mdutil.GetInt(md.Metadata(), labelWeight)
+	}
+	if weight <= 0 {
+		weight = 1
+	}
+	return weight
+}
+
+type leastConnStrategy[T any] struct {
+	counter uint64
+}
+
+// LeastConnStrategy is a strategy for node selector.
+// The node with the fewest active connections relative to its weight will be selected,
+// ties are broken by round-robin.
+func LeastConnStrategy[T any]() selector.Strategy[T] {
+	return &leastConnStrategy[T]{}
+}
+
+func (s *leastConnStrategy[T]) Apply(ctx context.Context, vs ...T) (v T) {
+	if len(vs) == 0 {
+		return
+	}
+
+	n := int(atomic.AddUint64(&s.counter, 1) - 1)
+	best, min := -1, 0.0
+	for i := range vs {
+		j := (n + i) % len(vs)
+		score := float64(ActiveConns(vs[j])) / float64(weightOf(vs[j]))
+		if best < 0 || score < min {
+			best, min = j, score
+		}
+	}
+	return vs[best]
+}
+
+type ewmaStrategy[T any] struct {
+	counter uint64
+}
+
+// EWMAStrategy is a strategy for node selector.
+// The node with the lowest cost will be selected, the cost is the EWMA of the dial times
+// multiplied by the number of active connections plus one.
+// Nodes which have not been dialed yet are selected first, ties are broken by round-robin.
+func EWMAStrategy[T any]() selector.Strategy[T] {
+	return &ewmaStrategy[T]{}
+}
+
+func (s *ewmaStrategy[T]) Apply(ctx context.Context, vs ...T) (v T) {
+	if len(vs) == 0 {
+		return
+	}
+
+	n := int(atomic.AddUint64(&s.counter, 1) - 1)
+	best, min := -1, 0.0
+	for i := range vs {
+		j := (n + i) % len(vs)
+		score := float64(Latency(vs[j])) * float64(ActiveConns(vs[j])+1)
+		if best < 0 || score < min {
+			best, min = j, score
+		}
+	}
+	return vs[best]
+}
+
+// default options for ConsistentHashStrategy
+const (
+	DefaultHashReplicas  = 100
+	DefaultHashLoadBound = 1.25
+)
+
+type hashRingEntry struct {
+	hash  uint32
+	index int
+}
+
+type consistentHashStrategy[T any] struct {
+	replicas  int
+	loadBound float64
+	keys      []string
+	ring      []hashRingEntry
+	r         *rand.Rand
+	mu        sync.Mutex
+}
+
+// ConsistentHashStrategy is a strategy for node selector.
+// The node will be selected by consistent hashing with bounded loads:
+// requests with the same hash source go to the same node as long as its active connections
+// do not exceed DefaultHashLoadBound times the average, otherwise the next node on the ring is used.
+// The hash source falls back to a random value if it is absent.
+func ConsistentHashStrategy[T any]() selector.Strategy[T] {
+	return &consistentHashStrategy[T]{
+		replicas:  DefaultHashReplicas,
+		loadBound: DefaultHashLoadBound,
+		r:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (s *consistentHashStrategy[T]) Apply(ctx context.Context, vs ...T) (v T) {
+	if len(vs) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	ring := s.buildRing(vs)
+	var value uint32
+	if h := ctxvalue.HashFromContext(ctx); h != nil {
+		value = crc32.ChecksumIEEE([]byte(h.Source))
+	} else {
+		value = s.r.Uint32()
+	}
+	s.mu.Unlock()
+
+	var total int64
+	for i := range vs {
+		total += ActiveConns(vs[i])
+	}
+	bound := int64(math.Ceil(s.loadBound * float64(total+1) / float64(len(vs))))
+
+	start := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= value })
+	for k := 0; k < len(ring); k++ {
+		e := ring[(start+k)%len(ring)]
+		if ActiveConns(vs[e.index]) < bound {
+			return vs[e.index]
+		}
+	}
+	return vs[ring[start%len(ring)].index]
+}
+
+// ringKey identifies v on the hash ring, nodes and chains keep their position across re-creation.
+func ringKey(v any) string {
+	if key := statsKey(v); key != "" {
+		return key
+	}
+	return fmt.Sprintf("%p", v)
+}
+
+// buildRing returns the hash ring of the nodes, the ring is rebuilt only when the nodes change.
+func (s *consistentHashStrategy[T]) buildRing(vs []T) []hashRingEntry {
+	keys := make([]string, len(vs))
+	for i := range vs {
+		keys[i] = ringKey(vs[i])
+	}
+	if slices.Equal(keys, s.keys) {
+		return s.ring
+	}
+
+	ring := make([]hashRingEntry, 0, len(vs)*s.replicas)
+	for i, key := range keys {
+		for r := 0; r < s.replicas; r++ {
+			ring = append(ring, hashRingEntry{
+				hash:  crc32.ChecksumIEEE([]byte(key + "#" + strconv.Itoa(r))),
+				index: i,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	s.keys, s.ring = keys, ring
+	return ring
+}
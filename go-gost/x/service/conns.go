@@ -0,0 +1,68 @@
+package service
+
+import (
+	"net"
+	"sync"
+
+	"github.com/go-gost/core/service"
+)
+
+// connTracker 记录服务当前正在处理的连接（TCP 连接或 UDP 会话）
+type connTracker struct {
+	conns map[net.Conn]struct{}
+	mu    sync.Mutex
+}
+
+func (t *connTracker) add(conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conns == nil {
+		t.conns = make(map[net.Conn]struct{})
+	}
+	t.conns[conn] = struct{}{}
+}
+
+func (t *connTracker) remove(conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.conns, conn)
+}
+
+func (t *connTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.conns)
+}
+
+// closeAll 关闭所有连接，返回关闭的数量
+func (t *connTracker) closeAll() int {
+	t.mu.Lock()
+	conns := t.conns
+	t.conns = nil
+	t.mu.Unlock()
+
+	for conn := range conns {
+		conn.Close()
+	}
+	return len(conns)
+}
+
+// ConnectionCloser 可以主动断开自身连接的服务
+type ConnectionCloser interface {
+	// CloseConnections 关闭服务当前所有的客户端连接，返回关闭的数量
+	CloseConnections() int
+}
+
+// CloseConnections 关闭服务当前所有的客户端连接，返回关闭的数量
+//
+// 只影响该服务自己接受的连接和 UDP 会话，不会波及同端口上的其他连接；
+// 服务不支持时返回 0。
+func CloseConnections(svc service.Service) int {
+	if c, ok := svc.(ConnectionCloser); ok {
+		return c.CloseConnections()
+	}
+	return 0
+}
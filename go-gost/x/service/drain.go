@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/service"
+)
+
+const (
+	// DefaultDrainTimeout 未指定排空期限时的默认值
+	DefaultDrainTimeout = 5 * time.Minute
+	// drainCheckInterval 检查剩余连接数的间隔
+	drainCheckInterval = time.Second
+	// drainReportInterval 排空进度回调的最小间隔
+	drainReportInterval = 5 * time.Second
+)
+
+// DrainStatus 服务排空进度
+type DrainStatus struct {
+	Service string `json:"service"`
+	// Remaining 尚未结束的连接数
+	Remaining int       `json:"remaining"`
+	Deadline  time.Time `json:"deadline"`
+	Done      bool      `json:"done"`
+	// Forced 到期后被强制关闭的连接数
+	Forced int `json:"forced,omitempty"`
+}
+
+type drainTask struct {
+	svc    service.Service
+	status DrainStatus
+	cancel context.CancelFunc
+}
+
+var drains = struct {
+	m  map[string]*drainTask
+	mu sync.Mutex
+}{m: make(map[string]*drainTask)}
+
+// ActiveConnections 返回服务当前的连接数，服务不支持时返回 0
+func ActiveConnections(svc service.Service) int {
+	if s, ok := svc.(*defaultService); ok {
+		return s.conns.count()
+	}
+	return 0
+}
+
+// Drain 在后台排空服务的连接
+//
+// 调用前服务应已关闭（不再接受新连接）。已建立的连接可以继续传输，
+// 全部结束或超过 timeout 后强制关闭剩余连接。progress 在剩余连接数变化时收到进度
+// （至多每 5 秒一次），结束时收到 Done 为 true 的最终状态，可以为 nil。
+// 同一服务已有排空任务时，旧任务被新任务取代；同名但已重建的旧服务继续按原期限排空，
+// 只是不再上报进度。返回开始时的连接数。
+func Drain(name string, svc service.Service, timeout time.Duration, progress func(DrainStatus)) int {
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+
+	remaining := ActiveConnections(svc)
+	ctx, cancel := context.WithCancel(context.Background())
+	task := &drainTask{
+		svc: svc,
+		status: DrainStatus{
+			Service:   name,
+			Remaining: remaining,
+			Deadline:  time.Now().Add(timeout),
+		},
+		cancel: cancel,
+	}
+
+	drains.mu.Lock()
+	if old := drains.m[name]; old != nil && old.svc == svc {
+		old.cancel()
+	}
+	drains.m[name] = task
+	drains.mu.Unlock()
+
+	go task.run(ctx, progress)
+
+	return remaining
+}
+
+func (t *drainTask) run(ctx context.Context, progress func(DrainStatus)) {
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+
+	timer := time.NewTimer(time.Until(t.status.Deadline))
+	defer timer.Stop()
+
+	var lastReport time.Time
+	lastRemaining := -1
+	for {
+		remaining := ActiveConnections(t.svc)
+		if remaining == 0 {
+			t.finish(0, progress)
+			return
+		}
+		st, ok := t.update(remaining)
+		if ok && progress != nil && remaining != lastRemaining && time.Since(lastReport) >= drainReportInterval {
+			progress(st)
+			lastReport = time.Now()
+			lastRemaining = remaining
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+			t.finish(CloseConnections(t.svc), progress)
+			return
+		case <-ctx.Done():
+			// 被同一服务新的排空任务取代
+			return
+		}
+	}
+}
+
+func (t *drainTask) update(remaining int) (DrainStatus, bool) {
+	drains.mu.Lock()
+	defer drains.mu.Unlock()
+
+	if drains.m[t.status.Service] != t {
+		return DrainStatus{}, false
+	}
+	t.status.Remaining = remaining
+	return t.status, true
+}
+
+func (t *drainTask) finish(forced int, progress func(DrainStatus)) {
+	drains.mu.Lock()
+	if drains.m[t.status.Service] != t {
+		drains.mu.Unlock()
+		return
+	}
+	delete(drains.m, t.status.Service)
+	t.status.Remaining = 0
+	t.status.Forced = forced
+	t.status.Done = true
+	st := t.status
+	drains.mu.Unlock()
+
+	t.cancel()
+	if progress != nil {
+		progress(st)
+	}
+}
+
+// DrainStatusOf 返回服务的排空进度，没有进行中的排空时返回 false
+func DrainStatusOf(name string) (DrainStatus, bool) {
+	drains.mu.Lock()
+	defer drains.mu.Unlock()
+
+	if t := drains.m[name]; t != nil {
+		return t.status, true
+	}
+	return DrainStatus{}, false
+}
+
+// Draining 返回所有进行中的排空任务
+func Draining() []DrainStatus {
+	drains.mu.Lock()
+	defer drains.mu.Unlock()
+
+	list := make([]DrainStatus, 0, len(drains.m))
+	for _, t := range drains.m {
+		list = append(list, t.status)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Service < list[j].Service })
+	return list
+}
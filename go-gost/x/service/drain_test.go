@@ -0,0 +1,123 @@
+package service
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDrainService returns a service with n tracked connections.
+func newDrainService(t *testing.T, n int) (*defaultService, []net.Conn) {
+	svc := &defaultService{}
+	var conns []net.Conn
+	for i := 0; i < n; i++ {
+		c1, c2 := net.Pipe()
+		t.Cleanup(func() {
+			c1.Close()
+			c2.Close()
+		})
+		svc.conns.add(c1)
+		conns = append(conns, c1)
+	}
+	return svc, conns
+}
+
+// drainResult collects the progress of a drain until it is done.
+func drainResult(progress <-chan DrainStatus, timeout time.Duration) (DrainStatus, bool) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case st := <-progress:
+			if st.Done {
+				return st, true
+			}
+		case <-deadline:
+			return DrainStatus{}, false
+		}
+	}
+}
+
+func TestDrain(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		conns   int
+		end     int // connections which end by themselves during the drain
+		timeout time.Duration
+		forced  int
+	}{
+		{
+			desc:    "no connections",
+			timeout: time.Minute,
+		},
+		{
+			desc:    "connections end before the deadline",
+			conns:   2,
+			end:     2,
+			timeout: time.Minute,
+		},
+		{
+			desc:    "remaining connections are closed at the deadline",
+			conns:   3,
+			end:     1,
+			timeout: 100 * time.Millisecond,
+			forced:  2,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			name := "drain-" + test.desc
+			svc, conns := newDrainService(t, test.conns)
+			progress := make(chan DrainStatus, 16)
+
+			assert.Equal(t, test.conns, Drain(name, svc, test.timeout, func(st DrainStatus) { progress <- st }))
+			for _, conn := range conns[:test.end] {
+				svc.conns.remove(conn)
+			}
+
+			st, ok := drainResult(progress, 5*time.Second)
+			require.True(t, ok, "drain did not finish")
+			assert.Equal(t, name, st.Service)
+			assert.Equal(t, 0, st.Remaining)
+			assert.Equal(t, test.forced, st.Forced)
+			assert.Equal(t, 0, ActiveConnections(svc))
+
+			_, ok = DrainStatusOf(name)
+			assert.False(t, ok, "finished drains are removed")
+
+			for _, conn := range conns[test.end:] {
+				_, err := conn.Write([]byte{0})
+				assert.Error(t, err, "forced connections are closed")
+			}
+		})
+	}
+}
+
+func TestDrainSuperseded(t *testing.T) {
+	svc, conns := newDrainService(t, 1)
+
+	first := make(chan DrainStatus, 16)
+	Drain("drain-superseded", svc, time.Minute, func(st DrainStatus) { first <- st })
+
+	second := make(chan DrainStatus, 16)
+	Drain("drain-superseded", svc, time.Minute, func(st DrainStatus) { second <- st })
+
+	st, ok := DrainStatusOf("drain-superseded")
+	require.True(t, ok)
+	assert.Equal(t, 1, st.Remaining)
+	assert.Contains(t, Draining(), st)
+
+	svc.conns.remove(conns[0])
+
+	st, ok = drainResult(second, 5*time.Second)
+	require.True(t, ok, "the new drain reports the result")
+	assert.Equal(t, 0, st.Forced)
+
+	_, ok = drainResult(first, 100*time.Millisecond)
+	assert.False(t, ok, "the superseded drain does not report")
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/core/admission"
+	"github.com/go-gost/core/handler"
+	"github.com/go-gost/core/limiter"
+	"github.com/go-gost/core/limiter/conn"
+	"github.com/go-gost/core/limiter/traffic"
+	"github.com/go-gost/core/observer/stats"
+	"github.com/go-gost/core/service"
+)
+
+var ErrReloadUnsupported = errors.New("service does not support reload")
+
+// swappable 可原子替换的组件
+type swappable[T any] struct {
+	v atomic.Pointer[T]
+}
+
+func (s *swappable[T]) load() (v T) {
+	if p := s.v.Load(); p != nil {
+		v = *p
+	}
+	return
+}
+
+func (s *swappable[T]) store(v T) {
+	s.v.Store(&v)
+}
+
+// SwapAdmission 监听器使用的准入控制，服务更新时原地替换
+type SwapAdmission struct {
+	swappable[admission.Admission]
+}
+
+func NewSwapAdmission(v admission.Admission) *SwapAdmission {
+	s := &SwapAdmission{}
+	s.store(v)
+	return s
+}
+
+func (s *SwapAdmission) Admit(ctx context.Context, addr string, opts ...admission.Option) bool {
+	if v := s.load(); v != nil {
+		return v.Admit(ctx, addr, opts...)
+	}
+	return true
+}
+
+// SwapTrafficLimiter 监听器使用的流量限速器，服务更新时原地替换
+//
+// 连接每次读写都会重新获取限速器，新的限速对已建立的连接同样生效。
+type SwapTrafficLimiter struct {
+	swappable[traffic.TrafficLimiter]
+}
+
+func NewSwapTrafficLimiter(v traffic.TrafficLimiter) *SwapTrafficLimiter {
+	s := &SwapTrafficLimiter{}
+	s.store(v)
+	return s
+}
+
+func (s *SwapTrafficLimiter) In(ctx context.Context, key string, opts ...limiter.Option) traffic.Limiter {
+	if v := s.load(); v != nil {
+		return v.In(ctx, key, opts...)
+	}
+	return nil
+}
+
+func (s *SwapTrafficLimiter) Out(ctx context.Context, key string, opts ...limiter.Option) traffic.Limiter {
+	if v := s.load(); v != nil {
+		return v.Out(ctx, key, opts...)
+	}
+	return nil
+}
+
+// SwapConnLimiter 监听器使用的连接数限制，服务更新时原地替换
+type SwapConnLimiter struct {
+	swappable[conn.ConnLimiter]
+}
+
+func NewSwapConnLimiter(v conn.ConnLimiter) *SwapConnLimiter {
+	s := &SwapConnLimiter{}
+	s.store(v)
+	return s
+}
+
+func (s *SwapConnLimiter) Limiter(key string) conn.Limiter {
+	if v := s.load(); v != nil {
+		return v.Limiter(key)
+	}
+	return nil
+}
+
+// ListenerComponents 传给监听器的可替换组件
+type ListenerComponents struct {
+	Admission      *SwapAdmission
+	TrafficLimiter *SwapTrafficLimiter
+	ConnLimiter    *SwapConnLimiter
+}
+
+func ListenerComponentsOption(c *ListenerComponents) Option {
+	return func(opts *options) {
+		opts.components = c
+	}
+}
+
+// HotConfig 不需要重新监听即可生效的服务配置
+type HotConfig struct {
+	Handler        handler.Handler
+	Admission      admission.Admission
+	TrafficLimiter traffic.TrafficLimiter
+	ConnLimiter    conn.ConnLimiter
+	// Options 服务选项，统计和监听器组件沿用原服务的
+	Options []Option
+}
+
+// Reloader 支持原地更新的服务
+type Reloader interface {
+	Reload(cfg HotConfig) error
+}
+
+// Reload 原地更新服务
+//
+// 监听器和统计保持不变，端口不会释放，未上报的流量也不会丢失。
+// 新连接立即使用新的处理器；已建立的连接继续由旧处理器处理，
+// 旧处理器在最后一个连接结束后关闭。
+func (s *defaultService) Reload(cfg HotConfig) error {
+	if cfg.Handler == nil {
+		return errors.New("handler is required")
+	}
+
+	var o options
+	for _, opt := range cfg.Options {
+		opt(&o)
+	}
+
+	s.mu.Lock()
+	components := s.options.components
+	if components == nil {
+		s.mu.Unlock()
+		return ErrReloadUnsupported
+	}
+	o.stats = s.options.stats
+	o.components = components
+	s.options = o
+
+	old := s.handler
+	s.handler = &handlerRef{h: cfg.Handler}
+	old.retired = true
+	idle := old.active == 0
+	s.mu.Unlock()
+
+	components.Admission.store(cfg.Admission)
+	components.TrafficLimiter.store(cfg.TrafficLimiter)
+	components.ConnLimiter.store(cfg.ConnLimiter)
+
+	if idle {
+		closeHandler(old.h)
+	}
+
+	s.status.addEvent(Event{
+		Time:    time.Now(),
+		Message: fmt.Sprintf("service %s is reloaded", s.name),
+	})
+	return nil
+}
+
+// handlerRef 一代处理器及其正在处理的连接数
+type handlerRef struct {
+	h       handler.Handler
+	active  int
+	retired bool
+}
+
+// acquireHandler 获取当前处理器，处理结束后必须调用 releaseHandler
+func (s *defaultService) acquireHandler() *handlerRef {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handler.active++
+	return s.handler
+}
+
+func (s *defaultService) releaseHandler(ref *handlerRef) {
+	s.mu.Lock()
+	ref.active--
+	idle := ref.retired && ref.active == 0
+	s.mu.Unlock()
+
+	if idle {
+		closeHandler(ref.h)
+	}
+}
+
+func closeHandler(h handler.Handler) {
+	if closer, ok := h.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// StatsOf 返回服务的统计对象，服务未启用统计时返回 nil
+func StatsOf(svc service.Service) stats.Stats {
+	if s, ok := svc.(*defaultService); ok {
+		return s.status.Stats()
+	}
+	return nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/go-gost/core/service"
 	ctxvalue "github.com/go-gost/x/ctx"
 	xnet "github.com/go-gost/x/internal/net"
+	xlogger "github.com/go-gost/x/logger"
 	xmetrics "github.com/go-gost/x/metrics"
 	xstats "github.com/go-gost/x/observer/stats"
 	"github.com/rs/xid"
@@ -42,6 +43,7 @@ type options struct {
 	observer       observer.Observer
 	observerPeriod time.Duration
 	logger         logger.Logger
+	components     *ListenerComponents
 }
 
 var isTls = 0
@@ -55,8 +57,12 @@ var needWrap = false
 type Option func(opts *options)
 
 func init() {
+	// 配置文件不存在时使用默认值（如单元测试），agent 启动时由 main 检查配置文件
+	if _, err := os.Stat("config.json"); os.IsNotExist(err) {
+		return
+	}
 	_, err := LoadConfig("config.json")
-	fmt.Println("config.json loaded")
+	xlogger.Println("config.json loaded")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -126,9 +132,11 @@ func LoggerOption(logger logger.Logger) Option {
 type defaultService struct {
 	name     string
 	listener listener.Listener
-	handler  handler.Handler
+	handler  *handlerRef
 	status   *Status
 	options  options
+	conns    connTracker
+	mu       sync.Mutex
 }
 
 func NewService(name string, ln listener.Listener, h handler.Handler, opts ...Option) service.Service {
@@ -139,7 +147,7 @@ func NewService(name string, ln listener.Listener, h handler.Handler, opts ...Op
 	s := &defaultService{
 		name:     name,
 		listener: ln,
-		handler:  h,
+		handler:  &handlerRef{h: h},
 		options:  options,
 		status: &Status{
 			createTime: time.Now(),
@@ -202,14 +210,14 @@ func (s *defaultService) Serve() error {
 
 				s.setState(StateFailed)
 
-				s.options.logger.Warnf("accept: %v, retrying in %v", e, tempDelay)
+				s.opts().logger.Warnf("accept: %v, retrying in %v", e, tempDelay)
 				time.Sleep(tempDelay)
 				continue
 			}
 			s.setState(StateClosed)
 
 			if !errors.Is(e, net.ErrClosed) {
-				s.options.logger.Errorf("accept: %v", e)
+				s.opts().logger.Errorf("accept: %v", e)
 			}
 
 			return e
@@ -236,11 +244,12 @@ func (s *defaultService) Serve() error {
 		ctx = ctxvalue.ContextWithClientAddr(ctx, ctxvalue.ClientAddr(clientAddr))
 		ctx = ctxvalue.ContextWithHash(ctx, &ctxvalue.Hash{Source: clientIP})
 
-		log := s.options.logger.WithFields(map[string]any{
+		opts := s.opts()
+		log := opts.logger.WithFields(map[string]any{
 			"sid": sid,
 		})
 
-		for _, rec := range s.options.recorders {
+		for _, rec := range opts.recorders {
 			if rec.Record == recorder.RecorderServiceClientAddress {
 				if err := rec.Recorder.Record(ctx, []byte(clientIP)); err != nil {
 					log.Errorf("record %s: %v", rec.Record, err)
@@ -248,17 +257,21 @@ func (s *defaultService) Serve() error {
 				break
 			}
 		}
-		if s.options.admission != nil &&
-			!s.options.admission.Admit(ctx, clientAddr) {
+		if opts.admission != nil &&
+			!opts.admission.Admit(ctx, clientAddr) {
 			conn.Close()
 			log.Debugf("admission: %s is denied", clientAddr)
 			continue
 		}
 
 		wg.Add(1)
+		s.conns.add(conn)
+		ref := s.acquireHandler()
 
 		go func() {
 			defer wg.Done()
+			defer s.conns.remove(conn)
+			defer s.releaseHandler(ref)
 
 			if v := xmetrics.GetCounter(xmetrics.MetricServiceRequestsCounter,
 				metrics.Labels{"service": s.name, "client": clientIP}); v != nil {
@@ -283,7 +296,7 @@ func (s *defaultService) Serve() error {
 				conn = wrapConnPDetection(conn)
 			}
 
-			if err := s.handler.Handle(ctx, conn); err != nil {
+			if err := ref.h.Handle(ctx, conn); err != nil {
 				log.Error(err)
 				if v := xmetrics.GetCounter(xmetrics.MetricServiceHandlerErrorsCounter,
 					metrics.Labels{"service": s.name, "client": clientIP}); v != nil {
@@ -301,26 +314,42 @@ func (s *defaultService) Status() *Status {
 	return s.status
 }
 
+// CloseConnections 关闭服务当前所有的客户端连接，返回关闭的数量
+func (s *defaultService) CloseConnections() int {
+	return s.conns.closeAll()
+}
+
 func (s *defaultService) Close() error {
-	s.execCmds("pre-down", s.options.preDown)
-	defer s.execCmds("post-down", s.options.postDown)
+	opts := s.opts()
+	s.execCmds("pre-down", opts.preDown)
+	defer s.execCmds("post-down", opts.postDown)
 
-	if closer, ok := s.handler.(io.Closer); ok {
-		closer.Close()
-	}
+	s.mu.Lock()
+	h := s.handler.h
+	s.mu.Unlock()
+
+	closeHandler(h)
 	return s.listener.Close()
 }
 
+// opts 返回当前的服务选项，服务更新时会被整体替换
+func (s *defaultService) opts() options {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.options
+}
+
 func (s *defaultService) execCmds(phase string, cmds []string) {
 	for _, cmd := range cmds {
 		cmd := strings.TrimSpace(cmd)
 		if cmd == "" {
 			continue
 		}
-		s.options.logger.Info(cmd)
+		s.opts().logger.Info(cmd)
 
 		if err := exec.Command("/bin/sh", "-c", cmd).Run(); err != nil {
-			s.options.logger.Warnf("[%s] %s: %v", phase, cmd, err)
+			s.opts().logger.Warnf("[%s] %s: %v", phase, cmd, err)
 		}
 	}
 }
@@ -334,7 +363,7 @@ func (s *defaultService) setState(state State) {
 		Message: msg,
 	})
 
-	if obs := s.options.observer; obs != nil {
+	if obs := s.opts().observer; obs != nil {
 		obs.Observe(context.Background(), []observer.Event{ServiceEvent{
 			Kind:    "service",
 			Service: s.name,
@@ -345,11 +374,11 @@ func (s *defaultService) setState(state State) {
 }
 
 func (s *defaultService) observeStats(ctx context.Context) {
-	if s.options.observer == nil {
+	if s.opts().observer == nil {
 		return
 	}
 
-	d := s.options.observerPeriod
+	d := s.opts().observerPeriod
 	if d == 0 {
 		d = 5 * time.Second
 	}
@@ -368,7 +397,7 @@ func (s *defaultService) observeStats(ctx context.Context) {
 
 			// First, try to send any pending events
 			if len(events) > 0 {
-				if err := s.options.observer.Observe(ctx, events); err == nil {
+				if err := s.opts().observer.Observe(ctx, events); err == nil {
 					events = nil
 				}
 				continue
@@ -381,38 +410,20 @@ func (s *defaultService) observeStats(ctx context.Context) {
 
 			isUpdated := st.IsUpdated()
 			if isUpdated {
-				inputBytes := st.Get(stats.KindInputBytes)
-				outputBytes := st.Get(stats.KindOutputBytes)
-
 				evs := []observer.Event{
 					xstats.StatsEvent{
 						Kind:         "service",
 						Service:      s.name,
 						TotalConns:   st.Get(stats.KindTotalConns),
 						CurrentConns: st.Get(stats.KindCurrentConns),
-						InputBytes:   inputBytes,
-						OutputBytes:  outputBytes,
+						InputBytes:   st.Get(stats.KindInputBytes),
+						OutputBytes:  st.Get(stats.KindOutputBytes),
 						TotalErrs:    st.Get(stats.KindTotalErrs),
 					},
 				}
-				if outputBytes > 0 || inputBytes > 0 {
-					reportItems := TrafficReportItem{
-						N: s.name,
-						U: int64(outputBytes),
-						D: int64(inputBytes),
-					}
-					success, err := sendTrafficReport(ctx, reportItems)
-					if err != nil {
-						fmt.Printf("发送流量报告失败: %v", err)
-					} else if success {
-						if xstats, ok := st.(*xstats.Stats); ok {
-							xstats.ResetTraffic(st.Get(stats.KindInputBytes)-inputBytes, st.Get(stats.KindOutputBytes)-outputBytes)
-						}
-					}
-				}
 
-				if err := s.options.observer.Observe(ctx, evs); err != nil {
-					fmt.Printf("发送观察器事件失败: %v", err)
+				if err := s.opts().observer.Observe(ctx, evs); err != nil {
+					xlogger.Printf("发送观察器事件失败: %v", err)
 					events = evs
 				}
 			}
@@ -458,6 +469,22 @@ func (c *detectConn) Read(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap implements xnet.Splicer.
+func (c *detectConn) Unwrap() io.ReadWriter {
+	return c.Conn
+}
+
+// Spliceable implements xnet.Splicer, the connection is bypassed once the protocol is detected
+// and the buffered data is consumed.
+func (c *detectConn) Spliceable(read bool) bool {
+	return !read || (c.detected && c.reader.Buffered() == 0)
+}
+
+// Spliced implements xnet.Splicer.
+func (c *detectConn) Spliced(n int, read bool) error {
+	return nil
+}
+
 func detectProtocol(data []byte, conn net.Conn) (blocked bool) {
 	if isHttp == 1 && detectHTTP(data) {
 		conn.Close()
@@ -556,6 +583,8 @@ type Config struct {
 	Http   int    `json:"http"`
 	Tls    int    `json:"tls"`
 	Socks  int    `json:"socks"`
+	// TrafficGzip 批量流量报告是否启用 gzip 压缩
+	TrafficGzip int `json:"traffic_gzip"`
 }
 
 func LoadConfig(configPath string) (string, error) {
@@ -576,6 +605,7 @@ func LoadConfig(configPath string) (string, error) {
 	isTls = config.Tls
 	isSocks = config.Socks
 	isHttp = config.Http
+	trafficGzip = config.TrafficGzip == 1
 
 	return "", nil
 
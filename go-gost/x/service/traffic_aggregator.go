@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	xlogger "github.com/go-gost/x/logger"
+	xstats "github.com/go-gost/x/observer/stats"
+	"github.com/go-gost/x/registry"
+)
+
+const (
+	// trafficReportInterval 批量上报周期
+	trafficReportInterval = 5 * time.Second
+	// trafficBatchMaxItems 单次上报的最大条目数，积压较多时分多个周期补发
+	trafficBatchMaxItems = 1000
+)
+
+// trafficAck 面板对单条流量记录的确认
+type trafficAck struct {
+	N string `json:"n"`
+	S uint64 `json:"s"`
+}
+
+// StartTrafficAggregator 启动流量汇总上报器
+//
+// 每个周期从所有服务收集流量增量写入持久化队列，
+// 再把所有队列（包括重启前遗留的）中的待确认记录合并成一次请求上报，
+// 只有面板确认的记录才会从对应服务的统计中扣减。
+// 服务删除后，其队列在全部确认后删除。
+func StartTrafficAggregator(ctx context.Context) {
+	ticker := time.NewTicker(trafficReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			collectTraffic()
+			if err := reportTraffic(ctx); err != nil {
+				xlogger.Printf("发送流量报告失败: %v\n", err)
+			}
+			pruneTrafficSpools()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// collectTraffic 将所有服务的流量增量写入各自的持久化队列
+func collectTraffic() {
+	for name, svc := range registry.ServiceRegistry().GetAll() {
+		s, ok := svc.(*defaultService)
+		// 未启用观察器的服务（如 _tls 结尾的内部服务）不参与流量上报
+		if !ok || s.opts().observer == nil {
+			continue
+		}
+		st, ok := s.status.Stats().(*xstats.Stats)
+		if !ok || st == nil {
+			continue
+		}
+
+		sp, err := openTrafficSpool(name)
+		if err != nil {
+			xlogger.Printf("⚠️ 打开服务 %s 的流量队列失败: %v\n", name, err)
+			continue
+		}
+		if err := sp.collect(st); err != nil {
+			xlogger.Printf("⚠️ 记录服务 %s 的流量失败: %v\n", name, err)
+		}
+	}
+}
+
+// reportTraffic 合并所有队列的待确认记录并上报一次
+func reportTraffic(ctx context.Context) error {
+	spools := allTrafficSpools()
+
+	var items []TrafficReportItem
+	for _, sp := range spools {
+		if len(items) >= trafficBatchMaxItems {
+			break
+		}
+		items = append(items, sp.pending(trafficBatchMaxItems-len(items))...)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	acks, err := sendTrafficBatch(ctx, items)
+	if err != nil {
+		return err
+	}
+
+	acked := make(map[string]map[uint64]bool)
+	for _, ack := range acks {
+		if acked[ack.N] == nil {
+			acked[ack.N] = make(map[uint64]bool)
+		}
+		acked[ack.N][ack.S] = true
+	}
+	for _, sp := range spools {
+		if m := acked[sp.name]; m != nil {
+			if err := sp.ackItems(m); err != nil {
+				xlogger.Printf("⚠️ 确认服务 %s 的流量记录失败: %v\n", sp.name, err)
+			}
+		}
+	}
+
+	if len(acks) < len(items) {
+		xlogger.Printf("⚠️ 流量报告 %d 条中仅 %d 条被确认，其余将重发\n", len(items), len(acks))
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -12,104 +15,249 @@ import (
 	"github.com/go-gost/core/observer/stats"
 	"github.com/go-gost/x/config"
 	"github.com/go-gost/x/internal/util/crypto"
+	xlogger "github.com/go-gost/x/logger"
 	"github.com/go-gost/x/registry"
 )
 
 var httpReportURL string
 var configReportURL string
-var httpAESCrypto *crypto.AESCrypto // 新增：HTTP上报加密器
+var httpReportSecret string     // 通过请求头发送的节点密钥，不再出现在 URL 中
+var httpKeyring *crypto.Keyring // HTTP上报使用的密钥环，流量和配置报告使用各自派生的密钥
+
+// httpReportTransport 面板使用 https 时携带自定义 TLS 配置的传输层，nil 表示默认
+var httpReportTransport http.RoundTripper
+
+// trafficGzip 是否压缩批量流量报告（config.json 中 traffic_gzip=1 开启）
+var trafficGzip = false
+
+// trafficGzipThreshold 小于该大小的报告不压缩
+const trafficGzipThreshold = 1024
+
+// ErrReportTransportUnavailable 上报通道当前不可用（如 WebSocket 未连接），调用方应回退到 HTTP
+var ErrReportTransportUnavailable = errors.New("report transport unavailable")
+
+// ReportTransport 可选的上报通道，例如与面板之间已认证的 WebSocket 长连接
+type ReportTransport interface {
+	// Report 发送一条类型化的上报消息并等待面板确认，返回确认中携带的数据
+	Report(ctx context.Context, msgType string, data []byte) (json.RawMessage, error)
+}
+
+var reportTransport ReportTransport
+
+// SetReportTransport 设置上报通道，设置后流量和配置报告优先通过该通道发送，
+// 仅在通道不可用时回退到 HTTP
+func SetReportTransport(t ReportTransport) {
+	reportTransport = t
+}
 
 // TrafficReportItem 流量报告项（压缩格式）
 type TrafficReportItem struct {
-	N string `json:"n"` // 服务名（name缩写）
-	U int64  `json:"u"` // 上行流量（up缩写）
-	D int64  `json:"d"` // 下行流量（down缩写）
+	N string `json:"n"`           // 服务名（name缩写）
+	U int64  `json:"u"`           // 上行流量（up缩写）
+	D int64  `json:"d"`           // 下行流量（down缩写）
+	S uint64 `json:"s,omitempty"` // 持久化队列序号（seq缩写），面板据此去重
 }
 
-func SetHTTPReportURL(addr string, secret string) {
-	httpReportURL = "http://" + addr + "/flow/upload?secret=" + secret
-	configReportURL = "http://" + addr + "/flow/config?secret=" + secret
+// SetHTTPReportURL 设置HTTP上报地址，tlsConfig 不为 nil 时使用 https
+func SetHTTPReportURL(addr string, secret string, tlsConfig *tls.Config) {
+	scheme := "http"
+	httpReportTransport = nil
+	if tlsConfig != nil {
+		scheme = "https"
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig.Clone()
+		httpReportTransport = transport
+	}
+
+	httpReportURL = scheme + "://" + addr + "/flow/upload"
+	configReportURL = scheme + "://" + addr + "/flow/config"
+	httpReportSecret = secret
 
-	// 创建 AES 加密器
-	var err error
-	httpAESCrypto, err = crypto.NewAESCrypto(secret)
+	// 创建密钥环
+	keyring, err := crypto.NewKeyring(secret, crypto.DefaultKeyringFile)
 	if err != nil {
-		fmt.Printf("❌ 创建 HTTP AES 加密器失败: %v\n", err)
-		httpAESCrypto = nil
+		xlogger.Printf("❌ 创建 HTTP 上报密钥环失败: %v\n", err)
+		keyring = nil
 	} else {
-		fmt.Printf("🔐 HTTP AES 加密器创建成功\n")
+		xlogger.Printf("🔐 HTTP 上报密钥环创建成功\n")
 	}
+	SetReportKeyring(keyring)
 }
 
-// sendTrafficReport 发送流量报告到HTTP接口
-func sendTrafficReport(ctx context.Context, reportItems TrafficReportItem) (bool, error) {
-	jsonData, err := json.Marshal(reportItems)
+// SetReportKeyring 设置 HTTP 上报使用的密钥环
+// 与 WebSocket 共用同一个密钥环时，通过 WebSocket 完成的密钥轮换对 HTTP 上报同时生效
+func SetReportKeyring(keyring *crypto.Keyring) {
+	httpKeyring = keyring
+}
+
+// nodeSecretHeader 携带节点密钥的请求头
+const nodeSecretHeader = "X-Node-Secret"
+
+// sealReport 使用指定用途的当前密钥加密上报数据，返回加密消息包装器
+// 密钥ID、报告类型和时间戳作为附加认证数据绑定到密文；
+// 面板表明支持派生密钥之前使用旧版格式（SHA-256(secret)，不带 kid）
+func sealReport(purpose, msgType string, data []byte) (map[string]interface{}, error) {
+	if !httpKeyring.Upgraded() {
+		encryptedData, err := httpKeyring.Legacy().Encrypt(data)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"encrypted": true,
+			"data":      encryptedData,
+			"timestamp": time.Now().Unix(),
+		}, nil
+	}
+
+	kid, aesCrypto := httpKeyring.Current(purpose)
+	timestamp := time.Now().Unix()
+
+	encryptedData, err := aesCrypto.EncryptWithAD(data, crypto.AdditionalData(kid, msgType, "", timestamp))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"encrypted": true,
+		"data":      encryptedData,
+		"timestamp": timestamp,
+		"kid":       kid,
+		"type":      msgType,
+	}, nil
+}
+
+// sendTrafficBatch 将多条流量记录合并为一次请求发送到HTTP接口
+//
+// 面板返回 "ok" 表示全部确认；也可以返回 {"acks":[{"n":..,"s":..}]}
+// 逐条确认，未出现在列表中的记录会在下个周期重发。
+func sendTrafficBatch(ctx context.Context, items []TrafficReportItem) ([]trafficAck, error) {
+	jsonData, err := json.Marshal(items)
 	if err != nil {
-		return false, fmt.Errorf("序列化报告数据失败: %v", err)
+		return nil, fmt.Errorf("序列化报告数据失败: %v", err)
+	}
+
+	if reportTransport != nil {
+		data, err := reportTransport.Report(ctx, "TrafficReport", jsonData)
+		if err == nil {
+			// 确认未携带数据表示整批确认
+			if len(data) == 0 || string(data) == "null" {
+				data = []byte("ok")
+			}
+			return parseTrafficAcks(items, data)
+		}
+		if !errors.Is(err, ErrReportTransportUnavailable) {
+			return nil, err
+		}
+	}
+
+	return postTrafficBatch(ctx, items, jsonData)
+}
+
+// postTrafficBatch 通过 HTTP 发送批量流量报告
+func postTrafficBatch(ctx context.Context, items []TrafficReportItem, jsonData []byte) ([]trafficAck, error) {
+
+	// 超过阈值时先压缩再加密，加密后的数据无法再压缩
+	compressed := false
+	if trafficGzip && len(jsonData) > trafficGzipThreshold {
+		if data, err := gzipBytes(jsonData); err == nil {
+			jsonData = data
+			compressed = true
+		} else {
+			xlogger.Printf("⚠️ 压缩流量报告失败，发送未压缩数据: %v\n", err)
+		}
 	}
 
 	var requestBody []byte
+	contentEncoding := ""
 
 	// 如果有加密器，则加密数据
-	if httpAESCrypto != nil {
-		encryptedData, err := httpAESCrypto.Encrypt(jsonData)
+	if httpKeyring != nil {
+		encryptedMessage, err := sealReport(crypto.PurposeTraffic, "TrafficReport", jsonData)
 		if err != nil {
-			fmt.Printf("⚠️ 加密流量报告失败，发送原始数据: %v\n", err)
-			requestBody = jsonData
-		} else {
-			// 创建加密消息包装器
-			encryptedMessage := map[string]interface{}{
-				"encrypted": true,
-				"data":      encryptedData,
-				"timestamp": time.Now().Unix(),
-			}
-			requestBody, err = json.Marshal(encryptedMessage)
-			if err != nil {
-				fmt.Printf("⚠️ 序列化加密流量报告失败，发送原始数据: %v\n", err)
-				requestBody = jsonData
-			}
+			return nil, fmt.Errorf("加密流量报告失败: %v", err)
+		}
+		if compressed {
+			encryptedMessage["compressed"] = true
+		}
+		requestBody, err = json.Marshal(encryptedMessage)
+		if err != nil {
+			return nil, fmt.Errorf("序列化加密流量报告失败: %v", err)
 		}
 	} else {
 		requestBody = jsonData
+		if compressed {
+			contentEncoding = "gzip"
+		}
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", httpReportURL, bytes.NewBuffer(requestBody))
 	if err != nil {
-		return false, fmt.Errorf("创建HTTP请求失败: %v", err)
+		return nil, fmt.Errorf("创建HTTP请求失败: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "GOST-Traffic-Reporter/1.0")
+	req.Header.Set(nodeSecretHeader, httpReportSecret)
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
 
 	client := &http.Client{
-		Timeout: 5 * time.Second,
+		Transport: httpReportTransport,
+		Timeout:   10 * time.Second,
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return false, fmt.Errorf("发送HTTP请求失败: %v", err)
+		return nil, fmt.Errorf("发送HTTP请求失败: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return false, fmt.Errorf("HTTP响应错误: %d %s", resp.StatusCode, resp.Status)
+		return nil, fmt.Errorf("HTTP响应错误: %d %s", resp.StatusCode, resp.Status)
 	}
 
 	// 读取响应内容
 	var responseBytes bytes.Buffer
 	_, err = responseBytes.ReadFrom(resp.Body)
 	if err != nil {
-		return false, fmt.Errorf("读取响应内容失败: %v", err)
+		return nil, fmt.Errorf("读取响应内容失败: %v", err)
 	}
 
-	responseText := strings.TrimSpace(responseBytes.String())
+	return parseTrafficAcks(items, responseBytes.Bytes())
+}
 
-	// 检查响应是否为"ok"
+// parseTrafficAcks 解析面板的确认响应
+func parseTrafficAcks(items []TrafficReportItem, response []byte) ([]trafficAck, error) {
+	responseText := strings.TrimSpace(string(response))
+
+	// "ok" 表示整批确认
 	if responseText == "ok" {
-		return true, nil
-	} else {
-		return false, fmt.Errorf("服务器响应: %s (期望: ok)", responseText)
+		acks := make([]trafficAck, 0, len(items))
+		for _, item := range items {
+			acks = append(acks, trafficAck{N: item.N, S: item.S})
+		}
+		return acks, nil
 	}
+
+	var ackResp struct {
+		Acks []trafficAck `json:"acks"`
+	}
+	if err := json.Unmarshal([]byte(responseText), &ackResp); err != nil || ackResp.Acks == nil {
+		return nil, fmt.Errorf("服务器响应: %s (期望: ok 或确认列表)", responseText)
+	}
+	return ackResp.Acks, nil
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 // sendConfigReport 发送配置报告到HTTP接口
@@ -124,24 +272,28 @@ func sendConfigReport(ctx context.Context) (bool, error) {
 		return false, fmt.Errorf("获取配置数据失败: %v", err)
 	}
 
+	if reportTransport != nil {
+		_, err := reportTransport.Report(ctx, "ConfigReport", configData)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, ErrReportTransportUnavailable) {
+			return false, err
+		}
+	}
+
 	var requestBody []byte
 
 	// 如果有加密器，则加密数据
-	if httpAESCrypto != nil {
-		encryptedData, err := httpAESCrypto.Encrypt(configData)
+	if httpKeyring != nil {
+		encryptedMessage, err := sealReport(crypto.PurposeConfig, "ConfigReport", configData)
 		if err != nil {
-			fmt.Printf("⚠️ 加密配置报告失败，发送原始数据: %v\n", err)
+			xlogger.Printf("⚠️ 加密配置报告失败，发送原始数据: %v\n", err)
 			requestBody = configData
 		} else {
-			// 创建加密消息包装器
-			encryptedMessage := map[string]interface{}{
-				"encrypted": true,
-				"data":      encryptedData,
-				"timestamp": time.Now().Unix(),
-			}
 			requestBody, err = json.Marshal(encryptedMessage)
 			if err != nil {
-				fmt.Printf("⚠️ 序列化加密配置报告失败，发送原始数据: %v\n", err)
+				xlogger.Printf("⚠️ 序列化加密配置报告失败，发送原始数据: %v\n", err)
 				requestBody = configData
 			}
 		}
@@ -156,9 +308,11 @@ func sendConfigReport(ctx context.Context) (bool, error) {
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "Config-Reporter/1.0")
+	req.Header.Set(nodeSecretHeader, httpReportSecret)
 
 	client := &http.Client{
-		Timeout: 10 * time.Second, // 配置上报可以稍长一些
+		Transport: httpReportTransport,
+		Timeout:   10 * time.Second, // 配置上报可以稍长一些
 	}
 
 	resp, err := client.Do(req)
@@ -191,11 +345,11 @@ func sendConfigReport(ctx context.Context) (bool, error) {
 // StartConfigReporter 启动配置定时上报器（每10分钟上报一次）
 func StartConfigReporter(ctx context.Context) {
 	if configReportURL == "" {
-		fmt.Printf("⚠️ 配置上报URL未设置，跳过定时上报\n")
+		xlogger.Printf("⚠️ 配置上报URL未设置，跳过定时上报\n")
 		return
 	}
 
-	fmt.Printf("🚀 配置定时上报器已启动，每10分钟上报一次（WebSocket连接稳定后启动）\n")
+	xlogger.Printf("🚀 配置定时上报器已启动，每10分钟上报一次（WebSocket连接稳定后启动）\n")
 
 	// 创建10分钟定时器
 	ticker := time.NewTicker(10 * time.Minute)
@@ -205,9 +359,9 @@ func StartConfigReporter(ctx context.Context) {
 	go func() {
 		success, err := sendConfigReport(ctx)
 		if err != nil {
-			fmt.Printf("❌ 初始配置上报失败: %v\n", err)
+			xlogger.Printf("❌ 初始配置上报失败: %v\n", err)
 		} else if success {
-			fmt.Printf("✅ 初始配置上报成功\n")
+			xlogger.Printf("✅ 初始配置上报成功\n")
 		}
 	}()
 
@@ -218,14 +372,14 @@ func StartConfigReporter(ctx context.Context) {
 			go func() {
 				success, err := sendConfigReport(ctx)
 				if err != nil {
-					fmt.Printf("❌ 定时配置上报失败: %v\n", err)
+					xlogger.Printf("❌ 定时配置上报失败: %v\n", err)
 				} else if success {
-					fmt.Printf("✅ 定时配置上报成功\n")
+					xlogger.Printf("✅ 定时配置上报成功\n")
 				}
 			}()
 
 		case <-ctx.Done():
-			fmt.Printf("⏹️ 配置定时上报器已停止\n")
+			xlogger.Printf("⏹️ 配置定时上报器已停止\n")
 			return
 		}
 	}
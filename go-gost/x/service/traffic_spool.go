@@ -0,0 +1,404 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/observer/stats"
+	xlogger "github.com/go-gost/x/logger"
+	xstats "github.com/go-gost/x/observer/stats"
+	"github.com/go-gost/x/registry"
+)
+
+const (
+	// trafficSpoolDir 未确认流量的落盘目录（相对于 agent 工作目录）
+	trafficSpoolDir = "traffic_spool"
+	// trafficSpoolExt 每个服务一个追加写文件
+	trafficSpoolExt = ".spool"
+	// trafficSpoolCompactSize 全部确认后超过该大小的文件会被压缩
+	trafficSpoolCompactSize = 64 * 1024
+)
+
+// spoolEntry 落盘的一行记录：要么是一条流量增量，要么是一条确认标记
+type spoolEntry struct {
+	TrafficReportItem
+	A uint64 `json:"a,omitempty"` // 已确认的最大序号
+}
+
+// spoolRecord 内存中待确认的流量增量
+type spoolRecord struct {
+	item TrafficReportItem
+	// stats 产生该增量的统计对象，确认后从中扣减；重启后加载的记录为 nil
+	stats *xstats.Stats
+}
+
+// trafficSpool 单个服务的流量持久化队列
+//
+// 每个观察周期的流量增量先带上递增序号追加写入文件并 fsync，
+// 再按序号顺序上报；面板确认后写入确认标记并从服务统计中扣减，
+// 因此进程重启或面板长时间不可达都不会丢失流量。
+// 上报由 trafficAggregator 统一批量完成。
+type trafficSpool struct {
+	name    string
+	path    string
+	file    *os.File
+	size    int64
+	nextSeq uint64
+	acked   uint64
+	records []*spoolRecord
+	mu      sync.Mutex
+}
+
+var trafficSpools = struct {
+	m      map[string]*trafficSpool
+	loaded bool
+	mu     sync.Mutex
+}{m: make(map[string]*trafficSpool)}
+
+// openTrafficSpool 获取（必要时创建）服务对应的持久化队列
+func openTrafficSpool(name string) (*trafficSpool, error) {
+	trafficSpools.mu.Lock()
+	defer trafficSpools.mu.Unlock()
+
+	loadTrafficSpoolsLocked()
+
+	if sp := trafficSpools.m[name]; sp != nil {
+		return sp, nil
+	}
+
+	sp, err := loadTrafficSpool(name, spoolPath(name))
+	if err != nil {
+		return nil, err
+	}
+	trafficSpools.m[name] = sp
+	return sp, nil
+}
+
+// loadTrafficSpoolsLocked 首次使用时加载上次进程遗留的所有队列文件
+func loadTrafficSpoolsLocked() {
+	if trafficSpools.loaded {
+		return
+	}
+	trafficSpools.loaded = true
+
+	files, err := filepath.Glob(filepath.Join(trafficSpoolDir, "*"+trafficSpoolExt))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		name, err := url.PathUnescape(strings.TrimSuffix(filepath.Base(file), trafficSpoolExt))
+		if err != nil || name == "" {
+			continue
+		}
+		sp, err := loadTrafficSpool(name, file)
+		if err != nil {
+			xlogger.Printf("⚠️ 加载流量队列 %s 失败: %v\n", file, err)
+			continue
+		}
+		if n := len(sp.records); n > 0 {
+			xlogger.Printf("📦 服务 %s 有 %d 条未确认的流量记录待重放\n", name, n)
+		}
+		trafficSpools.m[name] = sp
+	}
+}
+
+func spoolPath(name string) string {
+	return filepath.Join(trafficSpoolDir, url.PathEscape(name)+trafficSpoolExt)
+}
+
+func loadTrafficSpool(name, path string) (*trafficSpool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建流量队列目录失败: %v", err)
+	}
+
+	sp := &trafficSpool{
+		name: name,
+		path: path,
+	}
+
+	if f, err := os.Open(path); err == nil {
+		var maxSeq uint64
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry spoolEntry
+			// 崩溃时可能留下不完整的最后一行，直接跳过
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			if entry.A > sp.acked {
+				sp.acked = entry.A
+			}
+			if entry.S > 0 {
+				if entry.S > maxSeq {
+					maxSeq = entry.S
+				}
+				item := entry.TrafficReportItem
+				sp.records = append(sp.records, &spoolRecord{item: item})
+			}
+		}
+		f.Close()
+
+		pending := sp.records[:0]
+		for _, r := range sp.records {
+			if r.item.S > sp.acked {
+				pending = append(pending, r)
+			}
+		}
+		sp.records = pending
+		sp.nextSeq = max(maxSeq, sp.acked)
+	} else if os.IsNotExist(err) {
+		// 新建的队列从当前毫秒时间开始编号，服务删除后以相同名称重建时序号仍然递增，
+		// 不会被面板当作已处理的记录丢弃
+		sp.nextSeq = uint64(time.Now().UnixMilli())
+	} else {
+		return nil, fmt.Errorf("读取流量队列失败: %v", err)
+	}
+	sp.nextSeq++
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开流量队列失败: %v", err)
+	}
+	if fi, err := f.Stat(); err == nil {
+		sp.size = fi.Size()
+	}
+	sp.file = f
+
+	return sp, nil
+}
+
+// collect 把统计中尚未入队的流量增量写入队列
+//
+// 已入队但未确认的流量仍保留在统计里，只在确认后扣减，
+// 因此本次增量 = 当前计数 - 本统计对象待确认的总量。
+func (sp *trafficSpool) collect(st *xstats.Stats) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	var pendingIn, pendingOut uint64
+	for _, r := range sp.records {
+		if r.stats == st {
+			pendingIn += uint64(r.item.D)
+			pendingOut += uint64(r.item.U)
+		}
+	}
+
+	var up, down uint64
+	if v := st.Get(stats.KindOutputBytes); v > pendingOut {
+		up = v - pendingOut
+	}
+	if v := st.Get(stats.KindInputBytes); v > pendingIn {
+		down = v - pendingIn
+	}
+	if up == 0 && down == 0 {
+		return nil
+	}
+
+	item := TrafficReportItem{
+		N: sp.name,
+		U: int64(up),
+		D: int64(down),
+		S: sp.nextSeq,
+	}
+	if err := sp.writeLocked(spoolEntry{TrafficReportItem: item}, true); err != nil {
+		return err
+	}
+	sp.nextSeq++
+	sp.records = append(sp.records, &spoolRecord{item: item, stats: st})
+
+	return nil
+}
+
+// front 返回第一条待确认记录
+func (sp *trafficSpool) front() *spoolRecord {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if len(sp.records) == 0 {
+		return nil
+	}
+	return sp.records[0]
+}
+
+// ack 确认序号不大于 seq 的记录，并从对应统计中扣减已上报的流量
+func (sp *trafficSpool) ack(seq uint64) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if seq <= sp.acked {
+		return nil
+	}
+
+	n := 0
+	for _, r := range sp.records {
+		if r.item.S > seq {
+			break
+		}
+		if r.stats != nil {
+			r.stats.DeductTraffic(uint64(r.item.D), uint64(r.item.U))
+		}
+		n++
+	}
+	sp.records = sp.records[n:]
+	sp.acked = seq
+
+	if len(sp.records) == 0 && sp.size > trafficSpoolCompactSize {
+		return sp.compactLocked()
+	}
+	// 确认标记丢失只会导致重复上报，面板按序号去重，无需 fsync
+	return sp.writeLocked(spoolEntry{A: seq}, false)
+}
+
+// pending 按序号顺序返回至多 limit 条待确认记录
+func (sp *trafficSpool) pending(limit int) []TrafficReportItem {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	n := min(len(sp.records), limit)
+	items := make([]TrafficReportItem, 0, n)
+	for _, r := range sp.records[:n] {
+		items = append(items, r.item)
+	}
+	return items
+}
+
+// ackItems 根据面板逐条确认的序号推进确认位置
+//
+// 确认必须连续：中间某条未确认时，其后的记录即使被确认也要等下次重发，
+// 以保证落盘的确认标记始终是一个前缀。
+func (sp *trafficSpool) ackItems(acked map[uint64]bool) error {
+	sp.mu.Lock()
+	var seq uint64
+	for _, r := range sp.records {
+		if !acked[r.item.S] {
+			break
+		}
+		seq = r.item.S
+	}
+	sp.mu.Unlock()
+
+	if seq == 0 {
+		return nil
+	}
+	return sp.ack(seq)
+}
+
+func (sp *trafficSpool) writeLocked(entry spoolEntry, fsync bool) error {
+	if sp.file == nil {
+		return fmt.Errorf("流量队列 %s 已关闭", sp.name)
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	n, err := sp.file.Write(b)
+	sp.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("写入流量队列失败: %v", err)
+	}
+	if fsync {
+		if err := sp.file.Sync(); err != nil {
+			return fmt.Errorf("同步流量队列失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// compactLocked 全部确认后重写文件，只保留确认标记以延续序号
+func (sp *trafficSpool) compactLocked() error {
+	b, err := json.Marshal(spoolEntry{A: sp.acked})
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	tmp := sp.path + ".tmp"
+	if err := writeFileSync(tmp, b); err != nil {
+		return fmt.Errorf("压缩流量队列失败: %v", err)
+	}
+	if err := os.Rename(tmp, sp.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("压缩流量队列失败: %v", err)
+	}
+
+	sp.file.Close()
+	f, err := os.OpenFile(sp.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		sp.file = nil
+		return fmt.Errorf("打开流量队列失败: %v", err)
+	}
+	sp.file = f
+	sp.size = int64(len(b))
+
+	return nil
+}
+
+func writeFileSync(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// allTrafficSpools 返回当前所有持久化队列，包括上次进程遗留的
+func allTrafficSpools() []*trafficSpool {
+	trafficSpools.mu.Lock()
+	defer trafficSpools.mu.Unlock()
+
+	loadTrafficSpoolsLocked()
+
+	spools := make([]*trafficSpool, 0, len(trafficSpools.m))
+	for _, sp := range trafficSpools.m {
+		spools = append(spools, sp)
+	}
+	return spools
+}
+
+// pruneTrafficSpools 删除已删除服务的队列文件，只删除全部确认的队列
+func pruneTrafficSpools() {
+	trafficSpools.mu.Lock()
+	defer trafficSpools.mu.Unlock()
+
+	for name, sp := range trafficSpools.m {
+		if registry.ServiceRegistry().IsRegistered(name) {
+			continue
+		}
+
+		sp.mu.Lock()
+		if len(sp.records) > 0 {
+			sp.mu.Unlock()
+			continue
+		}
+		if sp.file != nil {
+			sp.file.Close()
+			sp.file = nil
+		}
+		err := os.Remove(sp.path)
+		sp.mu.Unlock()
+
+		if err != nil && !os.IsNotExist(err) {
+			xlogger.Printf("⚠️ 删除服务 %s 的流量队列失败: %v\n", name, err)
+			continue
+		}
+		delete(trafficSpools.m, name)
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-gost/core/observer/stats"
+	xstats "github.com/go-gost/x/observer/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSpool(t *testing.T, name string) *trafficSpool {
+	path := filepath.Join(t.TempDir(), trafficSpoolDir, name+trafficSpoolExt)
+	sp, err := loadTrafficSpool(name, path)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sp.file != nil {
+			sp.file.Close()
+		}
+	})
+	return sp
+}
+
+// addTraffic records traffic on st and collects it into sp, returning the sequence of the new record.
+func addTraffic(t *testing.T, sp *trafficSpool, st *xstats.Stats, down, up int64) uint64 {
+	st.Add(stats.KindInputBytes, down)
+	st.Add(stats.KindOutputBytes, up)
+	require.NoError(t, sp.collect(st))
+	return sp.records[len(sp.records)-1].item.S
+}
+
+func pendingSeqs(sp *trafficSpool) []uint64 {
+	var seqs []uint64
+	for _, item := range sp.pending(trafficBatchMaxItems) {
+		seqs = append(seqs, item.S)
+	}
+	return seqs
+}
+
+func TestTrafficSpoolReplay(t *testing.T) {
+	sp := newTestSpool(t, "svc")
+	assert.GreaterOrEqual(t, sp.nextSeq, uint64(time.Now().Add(-time.Minute).UnixMilli()),
+		"a new spool numbers its records from the current time")
+
+	st := xstats.NewStats(false).(*xstats.Stats)
+	s1 := addTraffic(t, sp, st, 100, 10)
+	s2 := addTraffic(t, sp, st, 200, 20)
+	s3 := addTraffic(t, sp, st, 300, 30)
+	assert.Equal(t, []uint64{s1, s2, s3}, pendingSeqs(sp))
+
+	require.NoError(t, sp.ackItems(map[uint64]bool{s1: true}))
+	assert.Equal(t, uint64(500), st.Get(stats.KindInputBytes), "acked traffic is deducted")
+	assert.Equal(t, uint64(50), st.Get(stats.KindOutputBytes))
+
+	// nothing new to collect while the traffic is still pending
+	require.NoError(t, sp.collect(st))
+	assert.Equal(t, []uint64{s2, s3}, pendingSeqs(sp))
+
+	// a restart replays the unacknowledged records in order and keeps numbering after them
+	sp.file.Close()
+	restored, err := loadTrafficSpool("svc", sp.path)
+	require.NoError(t, err)
+	defer restored.file.Close()
+
+	items := restored.pending(trafficBatchMaxItems)
+	require.Len(t, items, 2)
+	assert.Equal(t, TrafficReportItem{N: "svc", D: 200, U: 20, S: s2}, items[0])
+	assert.Equal(t, TrafficReportItem{N: "svc", D: 300, U: 30, S: s3}, items[1])
+	assert.Equal(t, s3+1, restored.nextSeq)
+
+	require.NoError(t, restored.ackItems(map[uint64]bool{s2: true, s3: true}))
+	assert.Empty(t, restored.pending(trafficBatchMaxItems))
+
+	// the ack marker keeps the numbering after everything is acknowledged
+	restored.file.Close()
+	again, err := loadTrafficSpool("svc", sp.path)
+	require.NoError(t, err)
+	defer again.file.Close()
+	assert.Empty(t, again.pending(trafficBatchMaxItems))
+	assert.Equal(t, s3+1, again.nextSeq)
+}
+
+func TestTrafficSpoolAckItems(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		acked  []int // indexes of the acknowledged records
+		expect []int // indexes of the records left pending
+	}{
+		{
+			desc:   "all acknowledged",
+			acked:  []int{0, 1, 2},
+			expect: nil,
+		},
+		{
+			desc:   "prefix acknowledged",
+			acked:  []int{0, 1},
+			expect: []int{2},
+		},
+		{
+			desc:   "gap keeps the later records",
+			acked:  []int{0, 2},
+			expect: []int{1, 2},
+		},
+		{
+			desc:   "first record missing",
+			acked:  []int{1, 2},
+			expect: []int{0, 1, 2},
+		},
+		{
+			desc:   "nothing acknowledged",
+			expect: []int{0, 1, 2},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			sp := newTestSpool(t, "svc")
+			st := xstats.NewStats(false).(*xstats.Stats)
+			var seqs []uint64
+			for i := 0; i < 3; i++ {
+				seqs = append(seqs, addTraffic(t, sp, st, 10, 1))
+			}
+
+			acked := make(map[uint64]bool)
+			for _, i := range test.acked {
+				acked[seqs[i]] = true
+			}
+			require.NoError(t, sp.ackItems(acked))
+
+			var expect []uint64
+			for _, i := range test.expect {
+				expect = append(expect, seqs[i])
+			}
+			assert.Equal(t, expect, pendingSeqs(sp))
+		})
+	}
+}
+
+func TestPruneTrafficSpools(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	drained, err := openTrafficSpool("deleted-drained")
+	require.NoError(t, err)
+	pending, err := openTrafficSpool("deleted-pending")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		trafficSpools.mu.Lock()
+		delete(trafficSpools.m, "deleted-pending")
+		trafficSpools.mu.Unlock()
+		pending.file.Close()
+	})
+
+	st := xstats.NewStats(false).(*xstats.Stats)
+	addTraffic(t, pending, st, 10, 1)
+
+	pruneTrafficSpools()
+
+	_, err = os.Stat(drained.path)
+	assert.True(t, os.IsNotExist(err), "the spool of a deleted service is removed once drained")
+	_, err = os.Stat(pending.path)
+	assert.NoError(t, err, "unacknowledged traffic is kept")
+
+	trafficSpools.mu.Lock()
+	_, ok := trafficSpools.m["deleted-drained"]
+	trafficSpools.mu.Unlock()
+	assert.False(t, ok)
+}
@@ -1,25 +1,263 @@
 package socket
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/go-gost/x/config"
+	xlogger "github.com/go-gost/x/logger"
 )
 
+const (
+	// stateFile 持久化的节点配置（服务、链、限速器及暂停状态）
+	stateFile = "gost.json"
+	// stateSumFile 记录各代配置文件的 SHA-256 校验和，格式与 sha256sum 相同
+	stateSumFile = stateFile + ".sha256"
+	// stateGenerations 保留的历史配置代数，分别为 gost.json.1 ~ gost.json.N
+	stateGenerations = 5
+)
+
+var (
+	stateMutex sync.Mutex
+	// stateHash 最近一次计算的配置校验和（stateHashEntry），供心跳使用
+	stateHash atomic.Value
+)
+
+// stateHashEntry 配置校验和及计算时的配置版本
+type stateHashEntry struct {
+	version uint64
+	sum     string
+}
+
 func saveConfig() {
+	if err := SaveState(); err != nil {
+		xlogger.Printf("⚠️ 保存配置失败: %v\n", err)
+	}
+}
+
+// SaveState 将当前配置原子地写入 gost.json
+//
+// 先登记新内容的校验和，再轮转历史代并通过临时文件替换写入，
+// 任意时刻崩溃，磁盘上的每一代文件都能通过校验或回退到上一代。
+// 内容没有变化时不写入，避免历史代被相同内容挤占。
+func SaveState() error {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	version := config.Version()
+	data, err := encodeState(stateConfig())
+	if err != nil {
+		return err
+	}
+	sum := checksum(data)
+	stateHash.Store(stateHashEntry{version: version, sum: sum})
+
+	if cur, err := os.ReadFile(stateFile); err == nil && checksum(cur) == sum {
+		return nil
+	}
+
+	sums := map[string]string{stateFile: sum}
+	for i := 0; i < stateGenerations; i++ {
+		if b, err := os.ReadFile(generationFile(i)); err == nil {
+			sums[generationFile(i+1)] = checksum(b)
+		}
+	}
+	if err := writeSums(sums); err != nil {
+		return err
+	}
+
+	tmp := stateFile + ".tmp"
+	if err := writeFileSync(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入临时配置失败: %v", err)
+	}
+
+	for i := stateGenerations - 1; i >= 0; i-- {
+		if _, err := os.Stat(generationFile(i)); err == nil {
+			os.Rename(generationFile(i), generationFile(i+1))
+		}
+	}
+
+	if err := os.Rename(tmp, stateFile); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("替换配置文件失败: %v", err)
+	}
+	return nil
+}
+
+// LoadState 读取最近一代通过校验的持久化配置
+//
+// 返回的 JSON 可直接作为内联配置交给解析器；没有持久化配置时返回 nil。
+// 所有代都无法通过校验时，损坏的 gost.json 会被改名保留并重置为空配置，
+// 节点以空配置启动并等待面板重新下发。
+func LoadState() ([]byte, error) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	if _, err := os.Stat(stateFile); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	sums, sumErr := readSums()
+	for i := 0; i <= stateGenerations; i++ {
+		file := generationFile(i)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		// 升级前或手工创建的配置没有校验和文件，能解析即可使用
+		if sumErr == nil && !sums[checksum(data)] {
+			xlogger.Printf("⚠️ 配置文件 %s 校验和不匹配，尝试上一代\n", file)
+			continue
+		}
+		var cfg config.Config
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			xlogger.Printf("⚠️ 配置文件 %s 解析失败: %v，尝试上一代\n", file, err)
+			continue
+		}
+
+		if i > 0 {
+			xlogger.Printf("♻️ 使用历史配置 %s 恢复\n", file)
+		}
+		return bytes.TrimSpace(data), nil
+	}
 
-	file := "gost.json"
+	corrupt := stateFile + ".corrupt"
+	os.Rename(stateFile, corrupt)
+	if err := writeFileSync(stateFile, []byte("{}\n"), 0600); err != nil {
+		return nil, fmt.Errorf("重置配置文件失败: %v", err)
+	}
+	return nil, fmt.Errorf("没有可用的持久化配置，已将损坏的配置保存为 %s", corrupt)
+}
 
-	f, err := os.Create(file)
+// StateHash 返回当前配置的校验和，与写入 gost.json 的内容一致
+func StateHash() (string, error) {
+	version := config.Version()
+	data, err := encodeState(stateConfig())
 	if err != nil {
-		return
+		return "", err
+	}
+	sum := checksum(data)
+	stateHash.Store(stateHashEntry{version: version, sum: sum})
+	return sum, nil
+}
+
+// cachedStateHash 返回最近一次计算的配置校验和，配置版本变化后重新计算
+//
+// 任何途径（面板命令、API、重新加载）修改全局配置都会改变配置版本，
+// 心跳不需要每次重新序列化配置，也不会在 API 修改配置后上报过期的校验和。
+func cachedStateHash() (string, error) {
+	if e, ok := stateHash.Load().(stateHashEntry); ok && e.version == config.Version() {
+		return e.sum, nil
+	}
+	return StateHash()
+}
+
+// stateConfig 复制当前配置并去掉服务运行状态
+//
+// 上报配置时会把运行状态写回全局配置，状态随时间变化，不能参与校验和或持久化。
+// 在配置锁内复制，避免与写入运行状态并发；只读取，不改变配置版本。
+func stateConfig() *config.Config {
+	cfg := &config.Config{}
+	config.View(func(c *config.Config) {
+		*cfg = *c
+		cfg.Services = make([]*config.ServiceConfig, 0, len(c.Services))
+		for _, svc := range c.Services {
+			if svc == nil {
+				continue
+			}
+			clone := *svc
+			clone.Status = nil
+			cfg.Services = append(cfg.Services, &clone)
+		}
+	})
+	return cfg
+}
+
+func encodeState(cfg *config.Config) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := cfg.Write(&buf, "json"); err != nil {
+		return nil, fmt.Errorf("序列化配置失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func generationFile(i int) string {
+	if i == 0 {
+		return stateFile
+	}
+	return fmt.Sprintf("%s.%d", stateFile, i)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readSums 读取校验和文件，返回所有已登记的校验和
+func readSums() (map[string]bool, error) {
+	f, err := os.Open(stateSumFile)
+	if err != nil {
+		return nil, err
 	}
 	defer f.Close()
 
-	if err := config.Global().Write(f, "json"); err != nil {
+	sums := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 {
+			sums[fields[0]] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(sums) == 0 {
+		return nil, errors.New("校验和文件为空")
+	}
+	return sums, nil
+}
 
-		return
+func writeSums(sums map[string]string) error {
+	var buf bytes.Buffer
+	for i := 0; i <= stateGenerations; i++ {
+		if sum, ok := sums[generationFile(i)]; ok {
+			fmt.Fprintf(&buf, "%s  %s\n", sum, generationFile(i))
+		}
 	}
 
-	return
+	tmp := stateSumFile + ".tmp"
+	if err := writeFileSync(tmp, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("写入配置校验和失败: %v", err)
+	}
+	if err := os.Rename(tmp, stateSumFile); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入配置校验和失败: %v", err)
+	}
+	return nil
+}
+
+func writeFileSync(name string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
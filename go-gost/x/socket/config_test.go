@@ -0,0 +1,47 @@
+package socket
+
+import (
+	"testing"
+
+	"github.com/go-gost/x/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedStateHash(t *testing.T) {
+	prev := config.Global()
+	t.Cleanup(func() { config.Set(prev) })
+
+	config.Set(&config.Config{
+		Services: []*config.ServiceConfig{{Name: "a", Addr: ":1000"}},
+	})
+	sum, err := cachedStateHash()
+	require.NoError(t, err)
+
+	cached, err := cachedStateHash()
+	require.NoError(t, err)
+	assert.Equal(t, sum, cached)
+
+	// changes made outside SaveState, e.g. by the API handlers
+	config.OnUpdate(func(c *config.Config) error {
+		c.Services = append(c.Services, &config.ServiceConfig{Name: "b", Addr: ":2000"})
+		return nil
+	})
+	changed, err := cachedStateHash()
+	require.NoError(t, err)
+	assert.NotEqual(t, sum, changed)
+
+	// the runtime status does not take part in the hash
+	config.OnUpdate(func(c *config.Config) error {
+		c.Services[0].Status = &config.ServiceStatus{State: "running"}
+		return nil
+	})
+	cached, err = cachedStateHash()
+	require.NoError(t, err)
+	assert.Equal(t, changed, cached)
+
+	config.Set(&config.Config{})
+	cached, err = cachedStateHash()
+	require.NoError(t, err)
+	assert.NotEqual(t, changed, cached)
+}
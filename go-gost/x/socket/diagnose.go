@@ -0,0 +1,858 @@
+package socket
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/logger"
+	xchain "github.com/go-gost/x/chain"
+	xlogger "github.com/go-gost/x/logger"
+	"github.com/go-gost/x/registry"
+)
+
+const (
+	// defaultDiagnoseTimeout 诊断命令未指定超时时的默认值(毫秒)
+	defaultDiagnoseTimeout = 5000
+	// defaultTracerouteHops TCP traceroute 默认的最大跳数
+	defaultTracerouteHops = 30
+	// maxProbeResponse 诊断结果中保留的响应内容长度
+	maxProbeResponse = 256
+	// maxHTTPProbeBody HTTP 探测最多读取的响应体长度
+	maxHTTPProbeBody = 1 << 20
+	// chainProbeSettle 经转发链建立连接后等待出口确认的最长时间
+	chainProbeSettle = time.Second
+)
+
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// UdpProbeRequest UDP 探测请求，向目标发送 Payload 并等待响应
+type UdpProbeRequest struct {
+	IP      string `json:"ip"`
+	Port    int    `json:"port"`
+	Count   int    `json:"count"`
+	Timeout int    `json:"timeout"` // 每次等待响应的超时(毫秒)
+	Payload string `json:"payload"`
+	// Expect 不为空时响应必须包含该内容才算成功，否则收到任意响应即成功
+	Expect string `json:"expect,omitempty"`
+	// Hex 为 true 时 Payload 和 Expect 为十六进制编码，响应内容也以十六进制返回
+	Hex       bool   `json:"hex,omitempty"`
+	Chain     string `json:"chain,omitempty"` // 经指定转发链探测
+	RequestId string `json:"requestId,omitempty"`
+}
+
+// UdpProbeResponse UDP 探测结果
+type UdpProbeResponse struct {
+	IP           string  `json:"ip"`
+	Port         int     `json:"port"`
+	Success      bool    `json:"success"`
+	Sent         int     `json:"sent"`
+	Received     int     `json:"received"`
+	AverageTime  float64 `json:"averageTime"` // 平均往返时间(ms)
+	PacketLoss   float64 `json:"packetLoss"`  // 失败率(%)
+	Response     string  `json:"response,omitempty"`
+	ErrorMessage string  `json:"errorMessage,omitempty"`
+	RequestId    string  `json:"requestId,omitempty"`
+}
+
+// TcpTracerouteRequest TCP traceroute 请求，逐跳发送 TTL 受限的 SYN
+type TcpTracerouteRequest struct {
+	IP        string `json:"ip"`
+	Port      int    `json:"port"`
+	MaxHops   int    `json:"maxHops"`
+	Timeout   int    `json:"timeout"` // 每跳超时(毫秒)
+	RequestId string `json:"requestId,omitempty"`
+}
+
+// TcpTracerouteResponse TCP traceroute 结果
+type TcpTracerouteResponse struct {
+	IP           string          `json:"ip"`
+	Port         int             `json:"port"`
+	Target       string          `json:"target"` // 解析后的目标地址
+	Success      bool            `json:"success"`
+	Hops         []TracerouteHop `json:"hops"`
+	ErrorMessage string          `json:"errorMessage,omitempty"`
+	RequestId    string          `json:"requestId,omitempty"`
+}
+
+// TracerouteHop traceroute 的一跳
+type TracerouteHop struct {
+	TTL     int     `json:"ttl"`
+	Addr    string  `json:"addr,omitempty"` // 返回 ICMP 超时的路由器或目标地址，超时时为空
+	Time    float64 `json:"time"`           // 响应时间(ms)
+	Reached bool    `json:"reached"`        // 是否已到达目标
+	Timeout bool    `json:"timeout,omitempty"`
+}
+
+// DnsResolveRequest DNS 解析请求
+type DnsResolveRequest struct {
+	Host string `json:"host"`
+	// Resolver 为节点配置的解析器名称，为空时使用系统解析器
+	Resolver string `json:"resolver,omitempty"`
+	// Network 为 ip、ip4 或 ip6，默认 ip
+	Network   string `json:"network,omitempty"`
+	Timeout   int    `json:"timeout"`
+	RequestId string `json:"requestId,omitempty"`
+}
+
+// DnsResolveResponse DNS 解析结果
+type DnsResolveResponse struct {
+	Host         string   `json:"host"`
+	Resolver     string   `json:"resolver,omitempty"`
+	Success      bool     `json:"success"`
+	IPs          []string `json:"ips,omitempty"`
+	Time         float64  `json:"time"` // 解析耗时(ms)
+	ErrorMessage string   `json:"errorMessage,omitempty"`
+	RequestId    string   `json:"requestId,omitempty"`
+}
+
+// TlsProbeRequest TLS 握手检查请求
+type TlsProbeRequest struct {
+	IP         string   `json:"ip"`
+	Port       int      `json:"port"`
+	ServerName string   `json:"serverName,omitempty"` // SNI，默认为 IP
+	ALPN       []string `json:"alpn,omitempty"`
+	Timeout    int      `json:"timeout"`
+	Chain      string   `json:"chain,omitempty"`
+	RequestId  string   `json:"requestId,omitempty"`
+}
+
+// TlsProbeResponse TLS 握手检查结果
+//
+// 握手不校验证书，校验结果单独在 Verified 和 VerifyError 中给出。
+type TlsProbeResponse struct {
+	IP            string           `json:"ip"`
+	Port          int              `json:"port"`
+	Success       bool             `json:"success"`
+	Version       string           `json:"version,omitempty"`
+	CipherSuite   string           `json:"cipherSuite,omitempty"`
+	ALPN          string           `json:"alpn,omitempty"`
+	ConnectTime   float64          `json:"connectTime"`   // 建立连接耗时(ms)
+	HandshakeTime float64          `json:"handshakeTime"` // TLS 握手耗时(ms)
+	Verified      bool             `json:"verified"`
+	VerifyError   string           `json:"verifyError,omitempty"`
+	Certificates  []TlsCertificate `json:"certificates,omitempty"`
+	ErrorMessage  string           `json:"errorMessage,omitempty"`
+	RequestId     string           `json:"requestId,omitempty"`
+}
+
+// TlsCertificate 证书链中的一张证书
+type TlsCertificate struct {
+	Subject      string   `json:"subject"`
+	Issuer       string   `json:"issuer"`
+	SerialNumber string   `json:"serialNumber"`
+	NotBefore    int64    `json:"notBefore"`
+	NotAfter     int64    `json:"notAfter"`
+	DNSNames     []string `json:"dnsNames,omitempty"`
+	IPAddresses  []string `json:"ipAddresses,omitempty"`
+	SHA256       string   `json:"sha256"`
+}
+
+// HttpProbeRequest HTTP 探测请求
+type HttpProbeRequest struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"` // 默认 GET
+	Headers map[string]string `json:"headers,omitempty"`
+	// ExpectStatus 期望的状态码，为 0 时 2xx 和 3xx 视为成功
+	ExpectStatus int    `json:"expectStatus,omitempty"`
+	Insecure     bool   `json:"insecure,omitempty"` // 不校验服务器证书
+	Timeout      int    `json:"timeout"`
+	Chain        string `json:"chain,omitempty"`
+	RequestId    string `json:"requestId,omitempty"`
+}
+
+// HttpProbeResponse HTTP 探测结果
+type HttpProbeResponse struct {
+	URL           string  `json:"url"`
+	Success       bool    `json:"success"`
+	StatusCode    int     `json:"statusCode,omitempty"`
+	Proto         string  `json:"proto,omitempty"`
+	ConnectTime   float64 `json:"connectTime"`   // 建立连接耗时(ms)
+	TLSTime       float64 `json:"tlsTime"`       // TLS 握手耗时(ms)
+	FirstByteTime float64 `json:"firstByteTime"` // 发出请求到收到首字节的耗时(ms)
+	TotalTime     float64 `json:"totalTime"`     // 总耗时(ms)
+	BodySize      int64   `json:"bodySize"`      // 读取的响应体长度，最多 1MB
+	ErrorMessage  string  `json:"errorMessage,omitempty"`
+	RequestId     string  `json:"requestId,omitempty"`
+}
+
+// ChainProbeRequest 经转发链探测目标，验证完整的多跳路径
+type ChainProbeRequest struct {
+	Chain   string `json:"chain"`
+	IP      string `json:"ip"`
+	Port    int    `json:"port"`
+	Count   int    `json:"count"`
+	Timeout int    `json:"timeout"`
+	// Payload 不为空时建立连接后发送，Expect 不为空时响应必须包含该内容
+	Payload   string `json:"payload,omitempty"`
+	Expect    string `json:"expect,omitempty"`
+	RequestId string `json:"requestId,omitempty"`
+}
+
+// ChainProbeResponse 转发链探测结果
+type ChainProbeResponse struct {
+	Chain        string              `json:"chain"`
+	IP           string              `json:"ip"`
+	Port         int                 `json:"port"`
+	Success      bool                `json:"success"`
+	AverageTime  float64             `json:"averageTime"` // 成功时建立连接的平均耗时(ms)
+	PacketLoss   float64             `json:"packetLoss"`  // 失败率(%)
+	Attempts     []ChainProbeAttempt `json:"attempts"`
+	ErrorMessage string              `json:"errorMessage,omitempty"`
+	RequestId    string              `json:"requestId,omitempty"`
+}
+
+// ChainProbeAttempt 一次转发链探测
+type ChainProbeAttempt struct {
+	Route    []string `json:"route"` // 选中的节点，name@addr
+	Success  bool     `json:"success"`
+	Time     float64  `json:"time"` // 建立连接耗时(ms)
+	Response string   `json:"response,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// decodeDiagnoseRequest 解析诊断命令的请求数据
+func decodeDiagnoseRequest(data interface{}, v any) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化诊断数据失败: %v", err)
+	}
+	if err := json.Unmarshal(jsonData, v); err != nil {
+		return fmt.Errorf("解析诊断请求失败: %v", err)
+	}
+	return nil
+}
+
+// checkTarget 检查目标地址和端口，返回错误信息
+func checkTarget(ip string, port int) string {
+	if net.ParseIP(ip) == nil && !isValidHostname(ip) {
+		return "无效的IP地址或主机名"
+	}
+	if port <= 0 || port > 65535 {
+		return "无效的端口号，范围应为1-65535"
+	}
+	return ""
+}
+
+func diagnoseTimeout(ms int) time.Duration {
+	if ms <= 0 {
+		ms = defaultDiagnoseTimeout
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// diagnoseDialer 返回诊断使用的拨号函数，chainName 不为空时经该转发链拨号
+func diagnoseDialer(chainName string) (dialFunc, error) {
+	if chainName == "" {
+		var d net.Dialer
+		return d.DialContext, nil
+	}
+
+	if !registry.ChainRegistry().IsRegistered(chainName) {
+		return nil, fmt.Errorf("转发链 %s 不存在", chainName)
+	}
+	router := xchain.NewRouter(
+		chain.ChainRouterOption(registry.ChainRegistry().Get(chainName)),
+		chain.LoggerRouterOption(logger.Default().WithFields(map[string]any{
+			"kind":  "diagnose",
+			"chain": chainName,
+		})),
+	)
+	return router.Dial, nil
+}
+
+// probeText 按需编码并截断探测到的响应内容
+func probeText(b []byte, hexEncoded bool) string {
+	if len(b) > maxProbeResponse {
+		b = b[:maxProbeResponse]
+	}
+	if hexEncoded {
+		return hex.EncodeToString(b)
+	}
+	return strings.ToValidUTF8(string(b), "")
+}
+
+// handleUdpProbe 处理UDP探测命令
+func (w *WebSocketReporter) handleUdpProbe(ctx context.Context, data interface{}) (UdpProbeResponse, error) {
+	var req UdpProbeRequest
+	if err := decodeDiagnoseRequest(data, &req); err != nil {
+		return UdpProbeResponse{}, err
+	}
+
+	response := UdpProbeResponse{
+		IP:        req.IP,
+		Port:      req.Port,
+		RequestId: req.RequestId,
+	}
+	if msg := checkTarget(req.IP, req.Port); msg != "" {
+		response.ErrorMessage = msg
+		return response, nil
+	}
+	if req.Count <= 0 {
+		req.Count = 4
+	}
+
+	payload, expect := []byte(req.Payload), []byte(req.Expect)
+	if req.Hex {
+		var err error
+		if payload, err = hex.DecodeString(req.Payload); err != nil {
+			response.ErrorMessage = "无效的十六进制 payload"
+			return response, nil
+		}
+		if expect, err = hex.DecodeString(req.Expect); err != nil {
+			response.ErrorMessage = "无效的十六进制 expect"
+			return response, nil
+		}
+	}
+
+	dial, err := diagnoseDialer(req.Chain)
+	if err != nil {
+		response.ErrorMessage = err.Error()
+		return response, nil
+	}
+
+	timeout := diagnoseTimeout(req.Timeout)
+	target := net.JoinHostPort(req.IP, strconv.Itoa(req.Port))
+	xlogger.Printf("🔍 开始UDP探测: %s，次数: %d，超时: %v\n", target, req.Count, timeout)
+
+	dctx, cancel := context.WithTimeout(ctx, timeout)
+	conn, err := dial(dctx, "udp", target)
+	cancel()
+	if err != nil {
+		response.ErrorMessage = err.Error()
+		return response, nil
+	}
+	defer conn.Close()
+
+	// 取消时中断等待中的读取
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	var totalTime time.Duration
+	var lastErr error
+	buf := make([]byte, 64*1024)
+	for i := 0; i < req.Count && ctx.Err() == nil; i++ {
+		start := time.Now()
+		conn.SetDeadline(start.Add(timeout))
+		if _, err := conn.Write(payload); err != nil {
+			lastErr = err
+			break
+		}
+		response.Sent++
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				lastErr = err
+				break
+			}
+			if len(expect) > 0 && !bytes.Contains(buf[:n], expect) {
+				continue
+			}
+			totalTime += time.Since(start)
+			response.Received++
+			if response.Response == "" {
+				response.Response = probeText(buf[:n], req.Hex)
+			}
+			break
+		}
+	}
+
+	if response.Sent > 0 {
+		response.PacketLoss = float64(response.Sent-response.Received) / float64(response.Sent) * 100
+	}
+	if response.Received == 0 {
+		if lastErr == nil {
+			lastErr = ctx.Err()
+		}
+		response.ErrorMessage = fmt.Sprintf("未收到有效响应: %v", lastErr)
+		return response, nil
+	}
+
+	response.Success = true
+	response.AverageTime = milliseconds(totalTime / time.Duration(response.Received))
+	xlogger.Printf("✅ UDP探测完成: 平均往返时间 %.2fms，失败率 %.1f%%\n", response.AverageTime, response.PacketLoss)
+	return response, nil
+}
+
+// handleTcpTraceroute 处理TCP traceroute命令
+func (w *WebSocketReporter) handleTcpTraceroute(ctx context.Context, data interface{}) (TcpTracerouteResponse, error) {
+	var req TcpTracerouteRequest
+	if err := decodeDiagnoseRequest(data, &req); err != nil {
+		return TcpTracerouteResponse{}, err
+	}
+
+	response := TcpTracerouteResponse{
+		IP:        req.IP,
+		Port:      req.Port,
+		RequestId: req.RequestId,
+	}
+	if msg := checkTarget(req.IP, req.Port); msg != "" {
+		response.ErrorMessage = msg
+		return response, nil
+	}
+	if req.MaxHops <= 0 || req.MaxHops > 64 {
+		req.MaxHops = defaultTracerouteHops
+	}
+	timeout := diagnoseTimeout(req.Timeout)
+
+	ip := net.ParseIP(req.IP)
+	if ip == nil {
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip", req.IP)
+		if err != nil || len(ips) == 0 {
+			response.ErrorMessage = fmt.Sprintf("解析目标地址失败: %v", err)
+			return response, nil
+		}
+		ip = ips[0]
+		for _, v := range ips {
+			if v.To4() != nil {
+				ip = v
+				break
+			}
+		}
+	}
+	response.Target = ip.String()
+
+	xlogger.Printf("🔍 开始TCP traceroute: %s，最大跳数: %d\n", net.JoinHostPort(response.Target, strconv.Itoa(req.Port)), req.MaxHops)
+
+	for ttl := 1; ttl <= req.MaxHops; ttl++ {
+		if err := ctx.Err(); err != nil {
+			response.ErrorMessage = err.Error()
+			return response, nil
+		}
+
+		hop, err := tcpProbeTTL(ip, req.Port, ttl, timeout)
+		if err != nil {
+			response.ErrorMessage = err.Error()
+			return response, nil
+		}
+		response.Hops = append(response.Hops, hop)
+		if hop.Reached {
+			response.Success = true
+			break
+		}
+	}
+
+	if !response.Success && response.ErrorMessage == "" {
+		response.ErrorMessage = fmt.Sprintf("%d 跳内未到达目标", req.MaxHops)
+	}
+	xlogger.Printf("✅ TCP traceroute完成: %d 跳，到达目标: %v\n", len(response.Hops), response.Success)
+	return response, nil
+}
+
+// handleDnsResolve 处理DNS解析命令
+func (w *WebSocketReporter) handleDnsResolve(ctx context.Context, data interface{}) (DnsResolveResponse, error) {
+	var req DnsResolveRequest
+	if err := decodeDiagnoseRequest(data, &req); err != nil {
+		return DnsResolveResponse{}, err
+	}
+
+	response := DnsResolveResponse{
+		Host:      req.Host,
+		Resolver:  req.Resolver,
+		RequestId: req.RequestId,
+	}
+	if !isValidHostname(req.Host) {
+		response.ErrorMessage = "无效的主机名"
+		return response, nil
+	}
+	switch req.Network {
+	case "":
+		req.Network = "ip"
+	case "ip", "ip4", "ip6":
+	default:
+		response.ErrorMessage = "无效的网络类型，应为 ip、ip4 或 ip6"
+		return response, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diagnoseTimeout(req.Timeout))
+	defer cancel()
+
+	var ips []net.IP
+	var err error
+	start := time.Now()
+	if req.Resolver == "" {
+		ips, err = net.DefaultResolver.LookupIP(ctx, req.Network, req.Host)
+	} else {
+		if !registry.ResolverRegistry().IsRegistered(req.Resolver) {
+			response.ErrorMessage = fmt.Sprintf("解析器 %s 不存在", req.Resolver)
+			return response, nil
+		}
+		ips, err = registry.ResolverRegistry().Get(req.Resolver).Resolve(ctx, req.Network, req.Host)
+	}
+	response.Time = milliseconds(time.Since(start))
+
+	if err == nil && len(ips) == 0 {
+		err = errors.New("没有解析结果")
+	}
+	if err != nil {
+		response.ErrorMessage = err.Error()
+		return response, nil
+	}
+
+	for _, ip := range ips {
+		response.IPs = append(response.IPs, ip.String())
+	}
+	response.Success = true
+	return response, nil
+}
+
+// handleTlsProbe 处理TLS握手检查命令
+func (w *WebSocketReporter) handleTlsProbe(ctx context.Context, data interface{}) (TlsProbeResponse, error) {
+	var req TlsProbeRequest
+	if err := decodeDiagnoseRequest(data, &req); err != nil {
+		return TlsProbeResponse{}, err
+	}
+
+	response := TlsProbeResponse{
+		IP:        req.IP,
+		Port:      req.Port,
+		RequestId: req.RequestId,
+	}
+	if msg := checkTarget(req.IP, req.Port); msg != "" {
+		response.ErrorMessage = msg
+		return response, nil
+	}
+	serverName := req.ServerName
+	if serverName == "" {
+		serverName = req.IP
+	}
+
+	dial, err := diagnoseDialer(req.Chain)
+	if err != nil {
+		response.ErrorMessage = err.Error()
+		return response, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diagnoseTimeout(req.Timeout))
+	defer cancel()
+
+	start := time.Now()
+	conn, err := dial(ctx, "tcp", net.JoinHostPort(req.IP, strconv.Itoa(req.Port)))
+	if err != nil {
+		response.ErrorMessage = err.Error()
+		return response, nil
+	}
+	defer conn.Close()
+	response.ConnectTime = milliseconds(time.Since(start))
+
+	tc := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		NextProtos:         req.ALPN,
+		InsecureSkipVerify: true,
+	})
+	start = time.Now()
+	if err := tc.HandshakeContext(ctx); err != nil {
+		response.ErrorMessage = fmt.Sprintf("TLS握手失败: %v", err)
+		return response, nil
+	}
+	response.HandshakeTime = milliseconds(time.Since(start))
+
+	state := tc.ConnectionState()
+	response.Success = true
+	response.Version = tls.VersionName(state.Version)
+	response.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	response.ALPN = state.NegotiatedProtocol
+
+	for _, cert := range state.PeerCertificates {
+		response.Certificates = append(response.Certificates, tlsCertificate(cert))
+	}
+	if err := verifyCertificates(state.PeerCertificates, serverName); err != nil {
+		response.VerifyError = err.Error()
+	} else {
+		response.Verified = true
+	}
+	return response, nil
+}
+
+func tlsCertificate(cert *x509.Certificate) TlsCertificate {
+	sum := sha256.Sum256(cert.Raw)
+	c := TlsCertificate{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: cert.SerialNumber.String(),
+		NotBefore:    cert.NotBefore.Unix(),
+		NotAfter:     cert.NotAfter.Unix(),
+		DNSNames:     cert.DNSNames,
+		SHA256:       hex.EncodeToString(sum[:]),
+	}
+	for _, ip := range cert.IPAddresses {
+		c.IPAddresses = append(c.IPAddresses, ip.String())
+	}
+	return c
+}
+
+// verifyCertificates 按系统根证书校验证书链和主机名
+func verifyCertificates(certs []*x509.Certificate, serverName string) error {
+	if len(certs) == 0 {
+		return errors.New("服务器未提供证书")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+// handleHttpProbe 处理HTTP探测命令
+func (w *WebSocketReporter) handleHttpProbe(ctx context.Context, data interface{}) (HttpProbeResponse, error) {
+	var req HttpProbeRequest
+	if err := decodeDiagnoseRequest(data, &req); err != nil {
+		return HttpProbeResponse{}, err
+	}
+
+	response := HttpProbeResponse{
+		URL:       req.URL,
+		RequestId: req.RequestId,
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		response.ErrorMessage = "无效的URL，仅支持 http 和 https"
+		return response, nil
+	}
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+
+	dial, err := diagnoseDialer(req.Chain)
+	if err != nil {
+		response.ErrorMessage = err.Error()
+		return response, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diagnoseTimeout(req.Timeout))
+	defer cancel()
+
+	var connectTime time.Duration
+	tr := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			start := time.Now()
+			conn, err := dial(ctx, network, addr)
+			connectTime = time.Since(start)
+			return conn, err
+		},
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: req.Insecure},
+		ForceAttemptHTTP2: true,
+		DisableKeepAlives: true,
+	}
+	defer tr.CloseIdleConnections()
+
+	var tlsStart, wroteRequest time.Time
+	var tlsTime, firstByteTime time.Duration
+	trace := &httptrace.ClientTrace{
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tlsTime = time.Since(tlsStart)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { wroteRequest = time.Now() },
+		GotFirstResponseByte: func() {
+			firstByteTime = time.Since(wroteRequest)
+		},
+	}
+
+	hreq, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), req.Method, u.String(), nil)
+	if err != nil {
+		response.ErrorMessage = err.Error()
+		return response, nil
+	}
+	for k, v := range req.Headers {
+		hreq.Header.Set(k, v)
+	}
+	if host := hreq.Header.Get("Host"); host != "" {
+		hreq.Host = host
+	}
+	if hreq.Header.Get("User-Agent") == "" {
+		hreq.Header.Set("User-Agent", "gost-diagnose")
+	}
+
+	xlogger.Printf("🔍 开始HTTP探测: %s %s\n", req.Method, u.Redacted())
+
+	start := time.Now()
+	resp, err := tr.RoundTrip(hreq)
+	if err != nil {
+		response.ErrorMessage = err.Error()
+		return response, nil
+	}
+	defer resp.Body.Close()
+
+	response.BodySize, err = io.Copy(io.Discard, io.LimitReader(resp.Body, maxHTTPProbeBody))
+	response.TotalTime = milliseconds(time.Since(start))
+	response.ConnectTime = milliseconds(connectTime)
+	response.TLSTime = milliseconds(tlsTime)
+	response.FirstByteTime = milliseconds(firstByteTime)
+	response.StatusCode = resp.StatusCode
+	response.Proto = resp.Proto
+	if err != nil {
+		response.ErrorMessage = fmt.Sprintf("读取响应失败: %v", err)
+		return response, nil
+	}
+
+	if req.ExpectStatus > 0 {
+		response.Success = resp.StatusCode == req.ExpectStatus
+	} else {
+		response.Success = resp.StatusCode >= 200 && resp.StatusCode < 400
+	}
+	if !response.Success {
+		response.ErrorMessage = fmt.Sprintf("非预期的状态码 %d", resp.StatusCode)
+	}
+	return response, nil
+}
+
+// handleChainProbe 处理转发链探测命令
+//
+// 每次探测经转发链选择路由并连接目标。连接器可能延迟发送连接请求（如 relay），
+// 因此建立连接后会先发送 Payload（可以为空）并等待出口的确认，
+// 在 1 秒内未收到错误即视为目标可达；指定了 Expect 时必须在超时前收到期望的响应。
+func (w *WebSocketReporter) handleChainProbe(ctx context.Context, data interface{}) (ChainProbeResponse, error) {
+	var req ChainProbeRequest
+	if err := decodeDiagnoseRequest(data, &req); err != nil {
+		return ChainProbeResponse{}, err
+	}
+
+	response := ChainProbeResponse{
+		Chain:     req.Chain,
+		IP:        req.IP,
+		Port:      req.Port,
+		RequestId: req.RequestId,
+	}
+	if msg := checkTarget(req.IP, req.Port); msg != "" {
+		response.ErrorMessage = msg
+		return response, nil
+	}
+	if !registry.ChainRegistry().IsRegistered(req.Chain) {
+		response.ErrorMessage = fmt.Sprintf("转发链 %s 不存在", req.Chain)
+		return response, nil
+	}
+	c := registry.ChainRegistry().Get(req.Chain)
+	if req.Count <= 0 {
+		req.Count = 4
+	}
+
+	timeout := diagnoseTimeout(req.Timeout)
+	target := net.JoinHostPort(req.IP, strconv.Itoa(req.Port))
+	log := logger.Default().WithFields(map[string]any{
+		"kind":  "diagnose",
+		"chain": req.Chain,
+	})
+	xlogger.Printf("🔍 开始转发链探测: %s -> %s，次数: %d\n", req.Chain, target, req.Count)
+
+	var totalTime time.Duration
+	var successCount int
+	for i := 0; i < req.Count && ctx.Err() == nil; i++ {
+		attempt, d := chainProbe(ctx, c, target, []byte(req.Payload), []byte(req.Expect), timeout, log)
+		if attempt.Success {
+			successCount++
+			totalTime += d
+		}
+		response.Attempts = append(response.Attempts, attempt)
+	}
+
+	if n := len(response.Attempts); n > 0 {
+		response.PacketLoss = float64(n-successCount) / float64(n) * 100
+	}
+	if successCount == 0 {
+		response.ErrorMessage = "所有探测都失败"
+		if n := len(response.Attempts); n > 0 {
+			response.ErrorMessage = response.Attempts[n-1].Error
+		} else if err := ctx.Err(); err != nil {
+			response.ErrorMessage = err.Error()
+		}
+		return response, nil
+	}
+
+	response.Success = true
+	response.AverageTime = milliseconds(totalTime / time.Duration(successCount))
+	xlogger.Printf("✅ 转发链探测完成: 平均连接时间 %.2fms，失败率 %.1f%%\n", response.AverageTime, response.PacketLoss)
+	return response, nil
+}
+
+func chainProbe(ctx context.Context, c chain.Chainer, target string, payload, expect []byte, timeout time.Duration, log logger.Logger) (attempt ChainProbeAttempt, d time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	route := c.Route(ctx, "tcp", target)
+	if route == nil {
+		attempt.Error = "没有可用的路由"
+		return
+	}
+	for _, node := range route.Nodes() {
+		attempt.Route = append(attempt.Route, node.Name+"@"+node.Addr)
+	}
+
+	start := time.Now()
+	conn, err := route.Dial(ctx, "tcp", target, chain.LoggerDialOption(log))
+	if err != nil {
+		attempt.Error = err.Error()
+		return
+	}
+	defer conn.Close()
+	d = time.Since(start)
+	attempt.Time = milliseconds(d)
+
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.Write(payload); err != nil {
+		attempt.Error = err.Error()
+		return
+	}
+
+	deadline, _ := ctx.Deadline()
+	if settle := time.Now().Add(chainProbeSettle); len(expect) == 0 && settle.Before(deadline) {
+		deadline = settle
+	}
+	conn.SetReadDeadline(deadline)
+
+	var buf bytes.Buffer
+	b := make([]byte, 4096)
+	for {
+		n, err := conn.Read(b)
+		buf.Write(b[:n])
+		if len(expect) == 0 && (n > 0 || err == io.EOF || isTimeout(err)) {
+			// 收到数据、目标主动关闭或等待期间没有错误，说明出口已连接目标
+			attempt.Success = true
+			break
+		}
+		if len(expect) > 0 && bytes.Contains(buf.Bytes(), expect) {
+			attempt.Success = true
+			break
+		}
+		if err != nil {
+			attempt.Error = err.Error()
+			if len(expect) > 0 && isTimeout(err) {
+				attempt.Error = "未收到期望的响应"
+			}
+			break
+		}
+	}
+	attempt.Response = probeText(buf.Bytes(), false)
+	return
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
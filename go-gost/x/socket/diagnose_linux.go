@@ -0,0 +1,111 @@
+//go:build linux
+
+package socket
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"time"
+)
+
+// tcpProbeTTL 发送一个 TTL 为 ttl 的 SYN
+//
+// 目标响应 SYN-ACK 或 RST 时到达目标；中间路由器返回 ICMP 超时时，
+// 通过 IP_RECVERR 从套接字的错误队列中读取路由器地址，无需特权。
+func tcpProbeTTL(ip net.IP, port int, ttl int, timeout time.Duration) (TracerouteHop, error) {
+	hop := TracerouteHop{TTL: ttl}
+
+	family, level, ttlOpt, recvErrOpt := syscall.AF_INET, syscall.IPPROTO_IP, syscall.IP_TTL, syscall.IP_RECVERR
+	var sa syscall.Sockaddr
+	if ip4 := ip.To4(); ip4 != nil {
+		addr := &syscall.SockaddrInet4{Port: port}
+		copy(addr.Addr[:], ip4)
+		sa = addr
+	} else {
+		family, level, ttlOpt, recvErrOpt = syscall.AF_INET6, syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, syscall.IPV6_RECVERR
+		addr := &syscall.SockaddrInet6{Port: port}
+		copy(addr.Addr[:], ip.To16())
+		sa = addr
+	}
+
+	fd, err := syscall.Socket(family, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return hop, err
+	}
+	defer syscall.Close(fd)
+
+	if err := syscall.SetsockoptInt(fd, level, ttlOpt, ttl); err != nil {
+		return hop, err
+	}
+	if err := syscall.SetsockoptInt(fd, level, recvErrOpt, 1); err != nil {
+		return hop, err
+	}
+	// 阻塞的 connect 在发送超时后返回 EINPROGRESS
+	tv := syscall.NsecToTimeval(timeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_SNDTIMEO, &tv); err != nil {
+		return hop, err
+	}
+
+	start := time.Now()
+	err = syscall.Connect(fd, sa)
+	elapsed := time.Since(start)
+
+	switch err {
+	case nil, syscall.ECONNREFUSED:
+		hop.Addr = ip.String()
+		hop.Time = milliseconds(elapsed)
+		hop.Reached = true
+		return hop, nil
+	case syscall.EINPROGRESS, syscall.EAGAIN, syscall.ETIMEDOUT:
+		hop.Timeout = true
+		return hop, nil
+	}
+
+	addr := icmpOffender(fd)
+	if addr == nil {
+		return hop, err
+	}
+	hop.Addr = addr.String()
+	hop.Time = milliseconds(elapsed)
+	hop.Reached = addr.Equal(ip)
+	return hop, nil
+}
+
+// icmpOffender 从错误队列中读取发送 ICMP 错误的地址
+func icmpOffender(fd int) net.IP {
+	b := make([]byte, 1)
+	oob := make([]byte, 512)
+	_, oobn, _, _, err := syscall.Recvmsg(fd, b, oob, syscall.MSG_ERRQUEUE|syscall.MSG_DONTWAIT)
+	if err != nil {
+		return nil
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return nil
+	}
+
+	for _, msg := range msgs {
+		if !(msg.Header.Level == syscall.IPPROTO_IP && msg.Header.Type == syscall.IP_RECVERR) &&
+			!(msg.Header.Level == syscall.IPPROTO_IPV6 && msg.Header.Type == syscall.IPV6_RECVERR) {
+			continue
+		}
+		// struct sock_extended_err 之后为发送方的地址
+		const extendedErrLen = 16
+		if len(msg.Data) < extendedErrLen+2 {
+			continue
+		}
+		sa := msg.Data[extendedErrLen:]
+		switch binary.NativeEndian.Uint16(sa) {
+		case syscall.AF_INET:
+			if len(sa) >= 8 {
+				return net.IP(append([]byte(nil), sa[4:8]...))
+			}
+		case syscall.AF_INET6:
+			if len(sa) >= 24 {
+				return net.IP(append([]byte(nil), sa[8:24]...))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package socket
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// tcpProbeTTL 仅在 Linux 上支持
+func tcpProbeTTL(ip net.IP, port int, ttl int, timeout time.Duration) (TracerouteHop, error) {
+	return TracerouteHop{TTL: ttl}, errors.New("TCP traceroute 仅支持 Linux")
+}
@@ -0,0 +1,375 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// commandWorkers 同时执行的命令数
+	commandWorkers = 8
+	// commandQueueSize 等待执行的命令上限，超出时直接拒绝
+	commandQueueSize = 256
+	// defaultCommandTimeout 命令未指定 timeout 时的默认期限（含排队时间）
+	defaultCommandTimeout = 2 * time.Minute
+	// globalCommandKey 涉及全部资源的命令（如 SyncState）使用的资源键
+	globalCommandKey = "*"
+)
+
+// 命令状态
+const (
+	commandQueued  = "queued"
+	commandRunning = "running"
+)
+
+// commandTask 一条待执行或正在执行的命令
+type commandTask struct {
+	cmd      CommandMessage
+	keys     []string
+	deps     []chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	state    string
+	received time.Time
+	started  time.Time
+	done     chan struct{}
+}
+
+// interruptible 不修改资源的命令（诊断类）可以在执行中被取消，
+// 修改资源的命令一旦开始执行就会运行到结束，取消只在排队期间生效
+func (t *commandTask) interruptible() bool {
+	return len(t.keys) == 0
+}
+
+// commandDispatcher 命令调度器
+//
+// 命令进入有界队列后由固定数量的 worker 并发执行，读循环不再被长耗时命令阻塞。
+// 只有操作同一资源的命令按到达顺序执行；涉及全部资源的命令与其他所有命令串行。
+type commandDispatcher struct {
+	w        *WebSocketReporter
+	queue    chan *commandTask
+	inflight map[string]*commandTask
+	lastKey  map[string]*commandTask
+	mu       sync.Mutex
+}
+
+func newCommandDispatcher(w *WebSocketReporter) *commandDispatcher {
+	return &commandDispatcher{
+		w:        w,
+		queue:    make(chan *commandTask, commandQueueSize),
+		inflight: make(map[string]*commandTask),
+		lastKey:  make(map[string]*commandTask),
+	}
+}
+
+// start 启动 worker，ctx 结束时退出
+func (d *commandDispatcher) start(ctx context.Context) {
+	for i := 0; i < commandWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case t := <-d.queue:
+					d.run(t)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+// submit 将命令放入队列，队列已满时立即回复失败
+func (d *commandDispatcher) submit(cmd CommandMessage) {
+	timeout := defaultCommandTimeout
+	if cmd.Timeout > 0 {
+		timeout = time.Duration(cmd.Timeout) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(d.w.ctx, timeout)
+
+	t := &commandTask{
+		cmd:      cmd,
+		keys:     commandKeys(cmd),
+		ctx:      ctx,
+		cancel:   cancel,
+		state:    commandQueued,
+		received: time.Now(),
+		done:     make(chan struct{}),
+	}
+
+	d.mu.Lock()
+	if cmd.RequestId != "" {
+		if _, ok := d.inflight[cmd.RequestId]; ok {
+			d.mu.Unlock()
+			cancel()
+			d.w.sendResponse(failedResponse(cmd, fmt.Sprintf("请求 %s 正在处理中", cmd.RequestId)))
+			return
+		}
+	}
+
+	// 只有 submit 会写入队列且都在锁内，检查通过后写入不会阻塞
+	if len(d.queue) >= cap(d.queue) {
+		d.mu.Unlock()
+		cancel()
+		d.w.sendResponse(failedResponse(cmd, "节点繁忙，命令队列已满"))
+		return
+	}
+
+	d.orderLocked(t)
+	if cmd.RequestId != "" {
+		d.inflight[cmd.RequestId] = t
+	}
+	d.queue <- t
+	d.mu.Unlock()
+}
+
+// orderLocked 记录该命令需要等待的前序命令
+//
+// 入队和登记依赖在同一把锁内完成，队列先进先出，
+// 因此依赖总是排在前面，不会出现 worker 互相等待。
+func (d *commandDispatcher) orderLocked(t *commandTask) {
+	seen := make(map[*commandTask]bool)
+	addDep := func(p *commandTask) {
+		if p != nil && p != t && !seen[p] {
+			seen[p] = true
+			t.deps = append(t.deps, p.done)
+		}
+	}
+
+	for _, key := range t.keys {
+		if key == globalCommandKey {
+			for _, p := range d.lastKey {
+				addDep(p)
+			}
+		} else {
+			addDep(d.lastKey[key])
+			addDep(d.lastKey[globalCommandKey])
+		}
+	}
+	for _, key := range t.keys {
+		d.lastKey[key] = t
+	}
+}
+
+// run 等待前序命令完成后执行命令并回复
+func (d *commandDispatcher) run(t *commandTask) {
+	defer d.finish(t)
+
+	for _, dep := range t.deps {
+		select {
+		case <-dep:
+		case <-t.ctx.Done():
+			d.w.sendResponse(failedResponse(t.cmd, contextMessage(t.ctx, "排队期间")))
+			return
+		}
+	}
+	if t.ctx.Err() != nil {
+		d.w.sendResponse(failedResponse(t.cmd, contextMessage(t.ctx, "排队期间")))
+		return
+	}
+
+	d.mu.Lock()
+	t.state = commandRunning
+	t.started = time.Now()
+	d.mu.Unlock()
+
+	ctx := t.ctx
+	if !t.interruptible() {
+		// 修改资源的命令不响应取消，避免半途而废
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	response := d.w.executeCommand(ctx, t.cmd)
+	if t.interruptible() && t.ctx.Err() != nil {
+		response = failedResponse(t.cmd, contextMessage(t.ctx, "执行期间"))
+	}
+	d.w.sendResponse(response)
+}
+
+func (d *commandDispatcher) finish(t *commandTask) {
+	t.cancel()
+	close(t.done)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.inflight[t.cmd.RequestId] == t {
+		delete(d.inflight, t.cmd.RequestId)
+	}
+	for _, key := range t.keys {
+		if d.lastKey[key] == t {
+			delete(d.lastKey, key)
+		}
+	}
+}
+
+// cancel 取消指定 requestId 的命令
+func (d *commandDispatcher) cancel(requestId string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, ok := d.inflight[requestId]
+	if !ok {
+		return fmt.Errorf("没有正在处理的请求 %s", requestId)
+	}
+	if t.state == commandRunning && !t.interruptible() {
+		return fmt.Errorf("请求 %s 正在修改资源，无法取消", requestId)
+	}
+	t.cancel()
+	return nil
+}
+
+// CommandStatus 正在处理的命令
+type CommandStatus struct {
+	RequestId string   `json:"requestId"`
+	Type      string   `json:"type"`
+	State     string   `json:"state"`
+	Resources []string `json:"resources,omitempty"`
+	// Elapsed 自收到命令起经过的毫秒数
+	Elapsed int64 `json:"elapsed"`
+	// Running 开始执行后经过的毫秒数，排队中为 0
+	Running int64 `json:"running,omitempty"`
+}
+
+// status 返回所有正在排队或执行的命令
+func (d *commandDispatcher) status() []CommandStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	list := make([]CommandStatus, 0, len(d.inflight))
+	for id, t := range d.inflight {
+		st := CommandStatus{
+			RequestId: id,
+			Type:      t.cmd.Type,
+			State:     t.state,
+			Resources: t.keys,
+			Elapsed:   now.Sub(t.received).Milliseconds(),
+		}
+		if t.state == commandRunning {
+			st.Running = now.Sub(t.started).Milliseconds()
+		}
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Elapsed > list[j].Elapsed })
+	return list
+}
+
+func failedResponse(cmd CommandMessage, message string) CommandResponse {
+	return CommandResponse{
+		Type:      cmd.Type + "Response",
+		Success:   false,
+		Message:   message,
+		RequestId: cmd.RequestId,
+	}
+}
+
+func contextMessage(ctx context.Context, phase string) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "命令在" + phase + "超时"
+	}
+	return "命令在" + phase + "被取消"
+}
+
+// commandKeys 提取命令涉及的资源，用于同一资源上的命令排序
+func commandKeys(cmd CommandMessage) []string {
+	switch cmd.Type {
+	case "SyncState", "Transaction", "RotateKey":
+		return []string{globalCommandKey}
+	}
+
+	raw, err := json.Marshal(cmd.Data)
+	if err != nil {
+		return []string{globalCommandKey}
+	}
+
+	if cmd.Type == "Apply" || cmd.Type == "Delete" {
+		if keys := resourceKeys(raw); len(keys) > 0 {
+			return keys
+		}
+		return []string{globalCommandKey}
+	}
+
+	var names []string
+	var kind string
+	switch cmd.Type {
+	case "AddService", "UpdateService":
+		kind = "service"
+		var list []struct {
+			Name string `json:"name"`
+		}
+		json.Unmarshal(raw, &list)
+		for _, v := range list {
+			names = append(names, v.Name)
+		}
+	case "DeleteService", "PauseService", "ResumeService", "KillConnections":
+		kind = "service"
+		var req struct {
+			Services []string `json:"services"`
+		}
+		json.Unmarshal(raw, &req)
+		names = req.Services
+	case "AddChains", "UpdateChains", "DeleteChains":
+		kind = "chain"
+		names = resourceName(raw, "chain")
+	case "AddLimiters", "UpdateLimiters", "DeleteLimiters":
+		kind = "limiter"
+		names = resourceName(raw, "limiter")
+	case "SetLogLevel":
+		kind = "service"
+		var req struct {
+			Service string `json:"service"`
+		}
+		json.Unmarshal(raw, &req)
+		names = []string{req.Service}
+	case "SpeedTest":
+		var req struct {
+			Role string `json:"role"`
+		}
+		json.Unmarshal(raw, &req)
+		if req.Role != speedTestRoleServer {
+			return nil
+		}
+		// 测速服务占用监听端口，可能与服务争用同一端口，与所有资源变更串行
+		return []string{globalCommandKey}
+	default:
+		return nil
+	}
+
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			keys = append(keys, kind+":"+name)
+		}
+	}
+	if len(keys) == 0 {
+		// 无法确定资源时按全局命令处理，保证不会乱序
+		return []string{globalCommandKey}
+	}
+	return keys
+}
+
+// resourceName 兼容 {"name":..}、{"<field>":..,"data":{..}} 和纯字符串三种格式
+func resourceName(raw []byte, field string) []string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return []string{s}
+	}
+
+	var m map[string]any
+	if json.Unmarshal(raw, &m) != nil {
+		return nil
+	}
+	if v, ok := m[field].(string); ok && v != "" {
+		return []string{v}
+	}
+	if v, ok := m["name"].(string); ok {
+		return []string{v}
+	}
+	return nil
+}
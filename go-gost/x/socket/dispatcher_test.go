@@ -0,0 +1,201 @@
+package socket
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandKeys(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		cmd    CommandMessage
+		expect []string
+	}{
+		{
+			desc:   "add services",
+			cmd:    CommandMessage{Type: "AddService", Data: []map[string]any{{"name": "a"}, {"name": "b"}}},
+			expect: []string{"service:a", "service:b"},
+		},
+		{
+			desc:   "delete services",
+			cmd:    CommandMessage{Type: "DeleteService", Data: map[string]any{"services": []string{"a"}}},
+			expect: []string{"service:a"},
+		},
+		{
+			desc:   "chain by name",
+			cmd:    CommandMessage{Type: "UpdateChains", Data: map[string]any{"chain": "c", "data": map[string]any{}}},
+			expect: []string{"chain:c"},
+		},
+		{
+			desc:   "limiter as string",
+			cmd:    CommandMessage{Type: "DeleteLimiters", Data: "l"},
+			expect: []string{"limiter:l"},
+		},
+		{
+			desc:   "service without names",
+			cmd:    CommandMessage{Type: "PauseService", Data: map[string]any{}},
+			expect: []string{globalCommandKey},
+		},
+		{
+			desc:   "sync state",
+			cmd:    CommandMessage{Type: "SyncState"},
+			expect: []string{globalCommandKey},
+		},
+		{
+			desc:   "log level of a service",
+			cmd:    CommandMessage{Type: "SetLogLevel", Data: map[string]any{"service": "a", "level": "debug"}},
+			expect: []string{"service:a"},
+		},
+		{
+			desc:   "speed test server",
+			cmd:    CommandMessage{Type: "SpeedTest", Data: map[string]any{"role": speedTestRoleServer}},
+			expect: []string{globalCommandKey},
+		},
+		{
+			desc: "speed test client",
+			cmd:  CommandMessage{Type: "SpeedTest", Data: map[string]any{"role": speedTestRoleClient}},
+		},
+		{
+			desc: "diagnostic",
+			cmd:  CommandMessage{Type: "TcpPing", Data: map[string]any{"ip": "127.0.0.1"}},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expect, commandKeys(test.cmd))
+		})
+	}
+}
+
+func TestDispatcherOrder(t *testing.T) {
+	testCases := []struct {
+		desc string
+		keys [][]string
+		// expect lists the pairs of commands (by index) which must run in order
+		expect [][2]int
+		// parallel lists the pairs of commands which must not wait for each other
+		parallel [][2]int
+	}{
+		{
+			desc:   "same resource",
+			keys:   [][]string{{"service:a"}, {"service:a"}, {"service:a"}},
+			expect: [][2]int{{0, 1}, {1, 2}},
+		},
+		{
+			desc:     "different resources",
+			keys:     [][]string{{"service:a"}, {"service:b"}, {"chain:a"}},
+			parallel: [][2]int{{0, 1}, {0, 2}, {1, 2}},
+		},
+		{
+			desc:     "multiple resources",
+			keys:     [][]string{{"service:a"}, {"service:b"}, {"service:a", "service:b"}, {"service:c"}},
+			expect:   [][2]int{{0, 2}, {1, 2}},
+			parallel: [][2]int{{0, 3}, {2, 3}},
+		},
+		{
+			desc:   "global command",
+			keys:   [][]string{{"service:a"}, {"chain:b"}, {globalCommandKey}, {"service:c"}},
+			expect: [][2]int{{0, 2}, {1, 2}, {2, 3}},
+		},
+		{
+			desc:     "keyless commands",
+			keys:     [][]string{{"service:a"}, nil, {globalCommandKey}, nil},
+			expect:   [][2]int{{0, 2}},
+			parallel: [][2]int{{0, 1}, {1, 2}, {2, 3}},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			d := newCommandDispatcher(&WebSocketReporter{})
+			tasks := make([]*commandTask, len(test.keys))
+			for i, keys := range test.keys {
+				tasks[i] = &commandTask{keys: keys, done: make(chan struct{})}
+				d.mu.Lock()
+				d.orderLocked(tasks[i])
+				d.mu.Unlock()
+			}
+
+			dependsOn := func(later, earlier int) bool {
+				for _, dep := range tasks[later].deps {
+					if dep == tasks[earlier].done {
+						return true
+					}
+				}
+				return false
+			}
+			for _, pair := range test.expect {
+				assert.True(t, dependsOn(pair[1], pair[0]), "command %d waits for %d", pair[1], pair[0])
+			}
+			for _, pair := range test.parallel {
+				assert.False(t, dependsOn(pair[1], pair[0]), "command %d does not wait for %d", pair[1], pair[0])
+			}
+		})
+	}
+}
+
+// TestDispatcherPerKeyOrder runs the commands the way the workers do, with random delays,
+// and checks that the commands of each resource finish in the order they were submitted.
+func TestDispatcherPerKeyOrder(t *testing.T) {
+	keys := []string{"service:a", "service:b", "chain:a"}
+
+	d := newCommandDispatcher(&WebSocketReporter{})
+	var (
+		mu    sync.Mutex
+		order = make(map[string][]int)
+		wg    sync.WaitGroup
+	)
+
+	for i := 0; i < 60; i++ {
+		taskKeys := []string{keys[rand.Intn(len(keys))]}
+		if i%20 == 19 {
+			taskKeys = []string{globalCommandKey}
+		}
+		task := &commandTask{keys: taskKeys, done: make(chan struct{})}
+		d.mu.Lock()
+		d.orderLocked(task)
+		d.mu.Unlock()
+
+		wg.Add(1)
+		go func(i int, task *commandTask) {
+			defer wg.Done()
+			for _, dep := range task.deps {
+				<-dep
+			}
+			time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+
+			mu.Lock()
+			for _, key := range task.keys {
+				order[key] = append(order[key], i)
+			}
+			if task.keys[0] == globalCommandKey {
+				for _, key := range keys {
+					order[key] = append(order[key], i)
+				}
+			}
+			mu.Unlock()
+
+			close(task.done)
+			d.mu.Lock()
+			for _, key := range task.keys {
+				if d.lastKey[key] == task {
+					delete(d.lastKey, key)
+				}
+			}
+			d.mu.Unlock()
+		}(i, task)
+	}
+	wg.Wait()
+
+	for key, seq := range order {
+		assert.IsIncreasing(t, seq, "commands of %s", key)
+	}
+}
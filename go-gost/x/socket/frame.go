@@ -0,0 +1,270 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/x/internal/util/crypto"
+	xlogger "github.com/go-gost/x/logger"
+	"github.com/patrickmn/go-cache"
+)
+
+// frameFreshnessWindow 加密帧时间戳允许的最大偏差
+const frameFreshnessWindow = 5 * time.Minute
+
+// 加密帧被拒绝的原因，作为响应类型回报给面板
+const (
+	FrameErrNoDecryptor = "NoDecryptor"
+	FrameErrDecrypt     = "DecryptError"
+	FrameErrMalformed   = "MalformedFrameError"
+	FrameErrStale       = "StaleFrameError"
+	FrameErrReplay      = "ReplayFrameError"
+	FrameErrUnknownKey  = "UnknownKeyError"
+)
+
+// encryptedFrame 加密消息帧
+//
+// kid、type、requestId 和 timestamp 明文放在帧头，并作为 AES-GCM 的附加认证数据，
+// 因此无法被篡改，也无法把截获的密文挪用到其他命令上。
+// kid 指明加密所用的密钥，密钥轮换期间新旧密钥的帧可以共存。
+type encryptedFrame struct {
+	Encrypted bool   `json:"encrypted"`
+	Data      string `json:"data"`
+	Timestamp int64  `json:"timestamp"`
+	Kid       string `json:"kid,omitempty"`
+	Type      string `json:"type,omitempty"`
+	RequestId string `json:"requestId,omitempty"`
+}
+
+// FrameError 加密帧校验失败
+type FrameError struct {
+	Code      string
+	RequestId string
+	Err       error
+}
+
+func (e *FrameError) Error() string {
+	return e.Code + ": " + e.Err.Error()
+}
+
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+// legacyFramesDisabled 为 true 时不兼容旧版面板，只接受认证帧
+var legacyFramesDisabled atomic.Bool
+
+// DisableLegacyFrames 关闭对旧版面板加密格式的兼容，需在创建 WebSocket 报告器之前调用
+func DisableLegacyFrames() {
+	legacyFramesDisabled.Store(true)
+}
+
+// replayGuard 在时间窗口内记录已处理的 requestId，拒绝重复的帧
+type replayGuard struct {
+	window time.Duration
+	seen   *cache.Cache
+}
+
+func newReplayGuard(window time.Duration) *replayGuard {
+	// 超出窗口的帧会因时间戳被拒绝，记录保留两倍窗口即可覆盖所有可能的重放
+	return &replayGuard{
+		window: window,
+		seen:   cache.New(2*window, window),
+	}
+}
+
+// checkFresh 校验时间戳是否在窗口内，兼容秒和毫秒两种单位
+func (g *replayGuard) checkFresh(timestamp int64) error {
+	ts := time.Unix(timestamp, 0)
+	if timestamp > 1e12 {
+		ts = time.UnixMilli(timestamp)
+	}
+
+	d := time.Since(ts)
+	if d < 0 {
+		d = -d
+	}
+	if d > g.window {
+		return fmt.Errorf("时间戳偏差 %v 超出允许范围 %v", d.Round(time.Second), g.window)
+	}
+	return nil
+}
+
+// remember 记录 requestId，已存在时返回错误
+func (g *replayGuard) remember(requestId string) error {
+	if err := g.seen.Add(requestId, struct{}{}, cache.DefaultExpiration); err != nil {
+		return fmt.Errorf("重复的请求 %s", requestId)
+	}
+	return nil
+}
+
+// openFrame 校验并解密收到的消息
+//
+// 配置了密钥环时只接受加密帧。认证帧必须携带 kid、type 和 requestId，
+// 时间戳在窗口内，密文能用帧头作为附加数据解开，内层消息与帧头一致，
+// 并且 requestId 没有处理过。面板第一次使用待切换的密钥时完成轮换。
+// 面板发来第一个合法的认证帧之前，同时接受旧版面板不带 kid 的帧；
+// 升级状态写入密钥状态文件，重启后仍然拒绝旧格式。
+func (w *WebSocketReporter) openFrame(message []byte) ([]byte, error) {
+	var frame encryptedFrame
+	if err := json.Unmarshal(message, &frame); err != nil || !frame.Encrypted {
+		if w.keyring != nil {
+			return nil, &FrameError{Code: FrameErrMalformed, Err: errors.New("拒绝未加密的消息")}
+		}
+		return message, nil
+	}
+
+	reject := func(code string, err error) error {
+		return &FrameError{Code: code, RequestId: frame.RequestId, Err: err}
+	}
+
+	if w.keyring == nil {
+		return nil, reject(FrameErrNoDecryptor, errors.New("没有可用的解密器"))
+	}
+	if frame.Kid == "" && !w.keyring.Upgraded() {
+		return w.openLegacyFrame(frame)
+	}
+	if frame.Kid == "" || frame.Type == "" || frame.RequestId == "" {
+		return nil, reject(FrameErrMalformed, errors.New("加密帧缺少 kid、type 或 requestId"))
+	}
+	if err := w.replayGuard.checkFresh(frame.Timestamp); err != nil {
+		return nil, reject(FrameErrStale, err)
+	}
+
+	aesCrypto, err := w.keyring.Lookup(frame.Kid, crypto.PurposeCommand)
+	if err != nil {
+		return nil, reject(FrameErrUnknownKey, err)
+	}
+
+	ad := crypto.AdditionalData(frame.Kid, frame.Type, frame.RequestId, frame.Timestamp)
+	plain, err := aesCrypto.DecryptWithAD(frame.Data, ad)
+	if err != nil {
+		return nil, reject(FrameErrDecrypt, err)
+	}
+
+	var inner struct {
+		Type      string `json:"type"`
+		RequestId string `json:"requestId"`
+	}
+	if err := json.Unmarshal(plain, &inner); err != nil {
+		return nil, reject(FrameErrMalformed, fmt.Errorf("解析内层消息失败: %v", err))
+	}
+	if inner.Type != frame.Type || inner.RequestId != frame.RequestId {
+		return nil, reject(FrameErrMalformed, errors.New("内层消息与帧头不一致"))
+	}
+
+	// 认证通过后才记录，避免伪造的帧占用 requestId
+	if err := w.replayGuard.remember(frame.RequestId); err != nil {
+		return nil, reject(FrameErrReplay, err)
+	}
+
+	if ok, err := w.keyring.MarkUpgraded(); err != nil {
+		xlogger.Printf("⚠️ 保存认证帧升级状态失败，重启后将重新接受旧版加密格式: %v\n", err)
+	} else if ok {
+		xlogger.Printf("🔐 面板支持认证帧，停止使用旧版加密格式\n")
+	}
+	if w.keyring.IsNext(frame.Kid) {
+		if ok, err := w.keyring.Promote(frame.Kid); err != nil {
+			xlogger.Printf("⚠️ 切换密钥 %s 失败: %v\n", frame.Kid, err)
+		} else if ok {
+			xlogger.Printf("🔑 面板已启用密钥 %s，完成密钥轮换\n", frame.Kid)
+		}
+	}
+
+	return plain, nil
+}
+
+// openLegacyFrame 解密旧版面板的帧：密钥为 SHA-256(secret)，没有附加认证数据
+//
+// 仍然校验时间戳，并按内层消息的 requestId 拒绝重放；
+// 除面板对心跳的应答 call 外，不带 requestId 的消息无法防重放，一律拒绝。
+func (w *WebSocketReporter) openLegacyFrame(frame encryptedFrame) ([]byte, error) {
+	if err := w.replayGuard.checkFresh(frame.Timestamp); err != nil {
+		return nil, &FrameError{Code: FrameErrStale, Err: err}
+	}
+
+	plain, err := w.keyring.Legacy().Decrypt(frame.Data)
+	if err != nil {
+		return nil, &FrameError{Code: FrameErrDecrypt, Err: err}
+	}
+
+	var inner struct {
+		Type      string `json:"type"`
+		RequestId string `json:"requestId"`
+	}
+	if err := json.Unmarshal(plain, &inner); err != nil {
+		return nil, &FrameError{Code: FrameErrMalformed, Err: fmt.Errorf("解析内层消息失败: %v", err)}
+	}
+	if inner.Type == "call" && inner.RequestId == "" {
+		return plain, nil
+	}
+	if inner.RequestId == "" {
+		return nil, &FrameError{Code: FrameErrMalformed, Err: errors.New("消息缺少 requestId")}
+	}
+	if err := w.replayGuard.remember(inner.RequestId); err != nil {
+		return nil, &FrameError{Code: FrameErrReplay, RequestId: inner.RequestId, Err: err}
+	}
+	return plain, nil
+}
+
+// sealFrame 加密待发送的消息，帧头使用内层消息的 type 和 requestId
+//
+// 面板表明支持认证帧之前使用旧版格式，保证旧版面板能够解密。
+func (w *WebSocketReporter) sealFrame(jsonData []byte) ([]byte, error) {
+	if !w.keyring.Upgraded() {
+		data, err := w.keyring.Legacy().Encrypt(jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(encryptedFrame{
+			Encrypted: true,
+			Data:      data,
+			Timestamp: time.Now().Unix(),
+		})
+	}
+
+	var header struct {
+		Type      string `json:"type"`
+		RequestId string `json:"requestId"`
+	}
+	json.Unmarshal(jsonData, &header)
+
+	kid, aesCrypto := w.keyring.Current(crypto.PurposeCommand)
+	frame := encryptedFrame{
+		Encrypted: true,
+		Timestamp: time.Now().Unix(),
+		Kid:       kid,
+		Type:      header.Type,
+		RequestId: header.RequestId,
+	}
+
+	ad := crypto.AdditionalData(frame.Kid, frame.Type, frame.RequestId, frame.Timestamp)
+	data, err := aesCrypto.EncryptWithAD(jsonData, ad)
+	if err != nil {
+		return nil, err
+	}
+	frame.Data = data
+
+	return json.Marshal(frame)
+}
+
+// sendFrameRejection 把加密帧被拒绝的原因回报给面板
+func (w *WebSocketReporter) sendFrameRejection(err error) {
+	response := CommandResponse{
+		Type:    FrameErrMalformed,
+		Success: false,
+		Message: err.Error(),
+	}
+
+	var fe *FrameError
+	if errors.As(err, &fe) {
+		response.Type = fe.Code
+		response.Message = fe.Err.Error()
+		response.RequestId = fe.RequestId
+	}
+
+	w.sendResponse(response)
+}
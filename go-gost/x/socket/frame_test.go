@@ -0,0 +1,286 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-gost/x/internal/util/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFrameSecret = "node-secret"
+
+func newTestFrameReporter(t *testing.T, upgraded bool) *WebSocketReporter {
+	keyring, err := crypto.NewKeyring(testFrameSecret, "")
+	require.NoError(t, err)
+	if upgraded {
+		_, err := keyring.MarkUpgraded()
+		require.NoError(t, err)
+	}
+	return &WebSocketReporter{
+		keyring:     keyring,
+		replayGuard: newReplayGuard(frameFreshnessWindow),
+	}
+}
+
+// sealTestFrame builds an authenticated frame the way the panel does.
+func sealTestFrame(t *testing.T, secret string, frame encryptedFrame, inner any) []byte {
+	plain, err := json.Marshal(inner)
+	require.NoError(t, err)
+
+	key, err := crypto.DeriveKey(secret, frame.Kid, crypto.PurposeCommand)
+	require.NoError(t, err)
+	aesCrypto, err := crypto.NewAESCryptoWithKey(key)
+	require.NoError(t, err)
+
+	ad := crypto.AdditionalData(frame.Kid, frame.Type, frame.RequestId, frame.Timestamp)
+	frame.Encrypted = true
+	frame.Data, err = aesCrypto.EncryptWithAD(plain, ad)
+	require.NoError(t, err)
+
+	b, err := json.Marshal(frame)
+	require.NoError(t, err)
+	return b
+}
+
+// sealLegacyTestFrame builds a frame the way panels without key derivation do.
+func sealLegacyTestFrame(t *testing.T, timestamp int64, inner any) []byte {
+	plain, err := json.Marshal(inner)
+	require.NoError(t, err)
+
+	aesCrypto, err := crypto.NewAESCrypto(testFrameSecret)
+	require.NoError(t, err)
+	data, err := aesCrypto.Encrypt(plain)
+	require.NoError(t, err)
+
+	b, err := json.Marshal(encryptedFrame{Encrypted: true, Data: data, Timestamp: timestamp})
+	require.NoError(t, err)
+	return b
+}
+
+func frameCommand(msgType, requestId string) map[string]any {
+	return map[string]any{"type": msgType, "requestId": requestId}
+}
+
+func TestOpenFrame(t *testing.T) {
+	now := time.Now().Unix()
+	stale := time.Now().Add(-2 * frameFreshnessWindow).Unix()
+
+	testCases := []struct {
+		desc     string
+		upgraded bool
+		message  func(t *testing.T) []byte
+		expect   string // expected FrameError code, empty for success
+	}{
+		{
+			desc: "authenticated frame",
+			message: func(t *testing.T) []byte {
+				return sealTestFrame(t, testFrameSecret,
+					encryptedFrame{Kid: "0", Type: "AddService", RequestId: "r1", Timestamp: now},
+					frameCommand("AddService", "r1"))
+			},
+		},
+		{
+			desc: "authenticated frame with millisecond timestamp",
+			message: func(t *testing.T) []byte {
+				ts := time.Now().UnixMilli()
+				return sealTestFrame(t, testFrameSecret,
+					encryptedFrame{Kid: "0", Type: "AddService", RequestId: "r1", Timestamp: ts},
+					frameCommand("AddService", "r1"))
+			},
+		},
+		{
+			desc: "stale authenticated frame",
+			message: func(t *testing.T) []byte {
+				return sealTestFrame(t, testFrameSecret,
+					encryptedFrame{Kid: "0", Type: "AddService", RequestId: "r1", Timestamp: stale},
+					frameCommand("AddService", "r1"))
+			},
+			expect: FrameErrStale,
+		},
+		{
+			desc: "unknown key id",
+			message: func(t *testing.T) []byte {
+				return sealTestFrame(t, testFrameSecret,
+					encryptedFrame{Kid: "7", Type: "AddService", RequestId: "r1", Timestamp: now},
+					frameCommand("AddService", "r1"))
+			},
+			expect: FrameErrUnknownKey,
+		},
+		{
+			desc: "wrong secret",
+			message: func(t *testing.T) []byte {
+				return sealTestFrame(t, "other-secret",
+					encryptedFrame{Kid: "0", Type: "AddService", RequestId: "r1", Timestamp: now},
+					frameCommand("AddService", "r1"))
+			},
+			expect: FrameErrDecrypt,
+		},
+		{
+			desc: "header type changed after sealing",
+			message: func(t *testing.T) []byte {
+				b := sealTestFrame(t, testFrameSecret,
+					encryptedFrame{Kid: "0", Type: "AddService", RequestId: "r1", Timestamp: now},
+					frameCommand("AddService", "r1"))
+				var frame encryptedFrame
+				require.NoError(t, json.Unmarshal(b, &frame))
+				frame.Type = "DeleteService"
+				b, err := json.Marshal(frame)
+				require.NoError(t, err)
+				return b
+			},
+			expect: FrameErrDecrypt,
+		},
+		{
+			desc: "inner message differs from header",
+			message: func(t *testing.T) []byte {
+				return sealTestFrame(t, testFrameSecret,
+					encryptedFrame{Kid: "0", Type: "AddService", RequestId: "r1", Timestamp: now},
+					frameCommand("DeleteService", "r1"))
+			},
+			expect: FrameErrMalformed,
+		},
+		{
+			desc: "authenticated frame without request id",
+			message: func(t *testing.T) []byte {
+				return sealTestFrame(t, testFrameSecret,
+					encryptedFrame{Kid: "0", Type: "AddService", Timestamp: now},
+					frameCommand("AddService", ""))
+			},
+			expect: FrameErrMalformed,
+		},
+		{
+			desc: "plain message",
+			message: func(t *testing.T) []byte {
+				b, _ := json.Marshal(frameCommand("AddService", "r1"))
+				return b
+			},
+			expect: FrameErrMalformed,
+		},
+		{
+			desc: "legacy frame before upgrade",
+			message: func(t *testing.T) []byte {
+				return sealLegacyTestFrame(t, time.Now().UnixMilli(), frameCommand("AddService", "r1"))
+			},
+		},
+		{
+			desc: "legacy heartbeat reply without request id",
+			message: func(t *testing.T) []byte {
+				return sealLegacyTestFrame(t, now, map[string]any{"type": "call"})
+			},
+		},
+		{
+			desc: "legacy command without request id",
+			message: func(t *testing.T) []byte {
+				return sealLegacyTestFrame(t, now, frameCommand("AddService", ""))
+			},
+			expect: FrameErrMalformed,
+		},
+		{
+			desc: "stale legacy frame",
+			message: func(t *testing.T) []byte {
+				return sealLegacyTestFrame(t, stale, frameCommand("AddService", "r1"))
+			},
+			expect: FrameErrStale,
+		},
+		{
+			desc:     "legacy frame after upgrade",
+			upgraded: true,
+			message: func(t *testing.T) []byte {
+				return sealLegacyTestFrame(t, now, frameCommand("AddService", "r1"))
+			},
+			expect: FrameErrMalformed,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			w := newTestFrameReporter(t, test.upgraded)
+			plain, err := w.openFrame(test.message(t))
+			if test.expect == "" {
+				require.NoError(t, err)
+				assert.NotEmpty(t, plain)
+				return
+			}
+
+			var fe *FrameError
+			require.True(t, errors.As(err, &fe), "unexpected error: %v", err)
+			assert.Equal(t, test.expect, fe.Code)
+		})
+	}
+}
+
+func TestOpenFrameReplay(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		message func(t *testing.T, requestId string) []byte
+	}{
+		{
+			desc: "authenticated frame",
+			message: func(t *testing.T, requestId string) []byte {
+				return sealTestFrame(t, testFrameSecret,
+					encryptedFrame{Kid: "0", Type: "AddService", RequestId: requestId, Timestamp: time.Now().Unix()},
+					frameCommand("AddService", requestId))
+			},
+		},
+		{
+			desc: "legacy frame",
+			message: func(t *testing.T, requestId string) []byte {
+				return sealLegacyTestFrame(t, time.Now().Unix(), frameCommand("AddService", requestId))
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			w := newTestFrameReporter(t, false)
+			message := test.message(t, "r1")
+
+			_, err := w.openFrame(message)
+			require.NoError(t, err)
+
+			_, err = w.openFrame(message)
+			var fe *FrameError
+			require.True(t, errors.As(err, &fe), "unexpected error: %v", err)
+			assert.Equal(t, FrameErrReplay, fe.Code)
+
+			// a fresh request id is still accepted
+			_, err = w.openFrame(test.message(t, "r2"))
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestOpenFrameUpgrade(t *testing.T) {
+	w := newTestFrameReporter(t, false)
+
+	// replies are sealed in the legacy format until the panel sends an authenticated frame
+	sealed, err := w.sealFrame([]byte(`{"type":"AddServiceResponse","requestId":"r1"}`))
+	require.NoError(t, err)
+	var frame encryptedFrame
+	require.NoError(t, json.Unmarshal(sealed, &frame))
+	assert.Empty(t, frame.Kid)
+
+	_, err = w.openFrame(sealTestFrame(t, testFrameSecret,
+		encryptedFrame{Kid: "0", Type: "AddService", RequestId: "r1", Timestamp: time.Now().Unix()},
+		frameCommand("AddService", "r1")))
+	require.NoError(t, err)
+	assert.True(t, w.keyring.Upgraded())
+
+	sealed, err = w.sealFrame([]byte(`{"type":"AddServiceResponse","requestId":"r1"}`))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(sealed, &frame))
+	assert.Equal(t, "0", frame.Kid)
+	assert.Equal(t, "AddServiceResponse", frame.Type)
+	assert.Equal(t, "r1", frame.RequestId)
+
+	_, err = w.openFrame(sealLegacyTestFrame(t, time.Now().Unix(), frameCommand("AddService", "r2")))
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+package socket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	xhop "github.com/go-gost/x/hop"
+	xlogger "github.com/go-gost/x/logger"
+)
+
+// HealthStatusRequest 查询节点健康状态，Names 为 hop 名称（内联转发的 hop 与服务同名），为空时返回全部
+type HealthStatusRequest struct {
+	Names []string `json:"names"`
+}
+
+// NodeHealthReport 节点健康状态变化上报
+type NodeHealthReport struct {
+	Hop string `json:"hop"`
+	xhop.NodeHealth
+}
+
+func (w *WebSocketReporter) handleHealthStatus(data interface{}) (map[string][]xhop.NodeHealth, error) {
+	var req HealthStatusRequest
+	if data != nil {
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("序列化数据失败: %v", err)
+		}
+		if err := json.Unmarshal(jsonData, &req); err != nil {
+			return nil, fmt.Errorf("解析健康状态请求失败: %v", err)
+		}
+	}
+
+	if len(req.Names) == 0 {
+		return xhop.AllHealth(), nil
+	}
+
+	result := make(map[string][]xhop.NodeHealth, len(req.Names))
+	for _, name := range req.Names {
+		name = strings.TrimSpace(name)
+		if nodes, ok := xhop.Health(name); ok {
+			result[name] = nodes
+		}
+	}
+	return result, nil
+}
+
+// reportHealth 上报节点健康状态变化，面板未确认时不重发，当前状态可通过 HealthStatus 查询
+func (w *WebSocketReporter) reportHealth(hop string, health xhop.NodeHealth) {
+	data, err := json.Marshal(NodeHealthReport{Hop: hop, NodeHealth: health})
+	if err != nil {
+		return
+	}
+
+	state := "恢复"
+	if !health.Healthy {
+		state = "不可用"
+	}
+	xlogger.Printf("🩺 %s 节点 %s(%s) %s\n", hop, health.Node, health.Addr, state)
+
+	go w.Report(w.ctx, "NodeHealth", data)
+}
@@ -0,0 +1,61 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-gost/x/internal/util/crypto"
+	xlogger "github.com/go-gost/x/logger"
+)
+
+// RotateKeyRequest 密钥轮换请求
+type RotateKeyRequest struct {
+	// KeyId 新密钥的 ID，面板之后用它标记使用新密钥加密的帧
+	KeyId string `json:"keyId"`
+	// Secret 新的密钥材料，为空时由节点密钥以 KeyId 为盐派生
+	Secret string `json:"secret,omitempty"`
+}
+
+// RotateKeyResponse 密钥轮换结果
+type RotateKeyResponse struct {
+	CurrentKeyId string `json:"currentKeyId"`
+	NextKeyId    string `json:"nextKeyId"`
+}
+
+// Keyring 返回节点的密钥环，供 HTTP 上报共用以保证轮换同时生效
+func (w *WebSocketReporter) Keyring() *crypto.Keyring {
+	return w.keyring
+}
+
+// handleRotateKey 登记下一个密钥
+//
+// 响应仍使用当前密钥加密；面板收到确认后改用新密钥发送，
+// 节点收到第一个使用新密钥的合法帧时切换，整个过程不需要重连。
+func (w *WebSocketReporter) handleRotateKey(data interface{}) (RotateKeyResponse, error) {
+	if w.keyring == nil {
+		return RotateKeyResponse{}, errors.New("未配置节点密钥，无法轮换")
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return RotateKeyResponse{}, fmt.Errorf("序列化密钥轮换数据失败: %v", err)
+	}
+
+	var req RotateKeyRequest
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return RotateKeyResponse{}, fmt.Errorf("解析密钥轮换请求失败: %v", err)
+	}
+
+	if err := w.keyring.Stage(req.KeyId, req.Secret); err != nil {
+		return RotateKeyResponse{}, fmt.Errorf("登记新密钥失败: %v", err)
+	}
+
+	current, _ := w.keyring.Current(crypto.PurposeCommand)
+	xlogger.Printf("🔑 已登记新密钥 %s，等待面板启用\n", req.KeyId)
+
+	return RotateKeyResponse{
+		CurrentKeyId: current,
+		NextKeyId:    req.KeyId,
+	}, nil
+}
@@ -0,0 +1,322 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/logger"
+	xlogger "github.com/go-gost/x/logger"
+	"github.com/go-gost/x/registry"
+	"github.com/go-gost/x/service"
+	"github.com/rs/xid"
+)
+
+const (
+	// logStreamDefaultDuration 日志订阅的默认时长
+	logStreamDefaultDuration = 5 * time.Minute
+	// logStreamMaxDuration 日志订阅的最长时长，到期后面板需要重新订阅
+	logStreamMaxDuration = time.Hour
+	// logStreamMaxCount 同时存在的日志订阅上限
+	logStreamMaxCount = 8
+	// logStreamBufferSize 每个订阅缓冲的最大记录数，超出后丢弃新记录
+	logStreamBufferSize = 2000
+	// logStreamBatchSize 单次上报的最大记录数
+	logStreamBatchSize = 200
+	// logStreamFlushInterval 定时上报间隔
+	logStreamFlushInterval = time.Second
+)
+
+// SubscribeLogsRequest 日志订阅请求，过滤条件为空时不过滤
+type SubscribeLogsRequest struct {
+	Service string `json:"service,omitempty"`
+	Sid     string `json:"sid,omitempty"`
+	// Level 最低日志级别（trace/debug/info/warn/error/fatal），默认全部
+	Level string `json:"level,omitempty"`
+	// Duration 订阅时长(秒)，默认 300，最长 3600
+	Duration int `json:"duration,omitempty"`
+}
+
+// SubscribeLogsResponse 日志订阅结果
+type SubscribeLogsResponse struct {
+	Id        string `json:"id"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// UnsubscribeLogsRequest 取消日志订阅请求
+type UnsubscribeLogsRequest struct {
+	Id string `json:"id"`
+}
+
+// SetLogLevelRequest 服务日志级别请求，Level 为空时恢复为配置的级别
+type SetLogLevelRequest struct {
+	Service string `json:"service"`
+	Level   string `json:"level"`
+}
+
+// SetLogLevelResponse 服务日志级别设置结果，Previous 为空表示此前未单独设置
+type SetLogLevelResponse struct {
+	Service  string `json:"service"`
+	Level    string `json:"level"`
+	Previous string `json:"previous,omitempty"`
+}
+
+// LogRecord 一条日志记录，Fields 包含 service、sid、kind 等结构化字段
+type LogRecord struct {
+	Time   int64          `json:"time"` // 毫秒时间戳
+	Level  string         `json:"level"`
+	Msg    string         `json:"msg"`
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// LogRecordBatch 一批日志记录，以 LogRecords 上报
+type LogRecordBatch struct {
+	Id      string      `json:"id"`
+	Records []LogRecord `json:"records"`
+	// Dropped 订阅开始以来因缓冲已满被丢弃的记录数
+	Dropped uint64 `json:"dropped,omitempty"`
+	// Done 为 true 表示订阅已结束，Reason 为结束原因
+	Done   bool   `json:"done,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// logStream 一个日志订阅，将匹配的日志记录批量上报到面板
+type logStream struct {
+	id     string
+	sub    *xlogger.Subscription
+	cancel context.CancelFunc
+}
+
+// logStreams 管理当前连接上的日志订阅
+type logStreams struct {
+	streams map[string]*logStream
+	mu      sync.Mutex
+}
+
+func newLogStreams() *logStreams {
+	return &logStreams{
+		streams: make(map[string]*logStream),
+	}
+}
+
+// handleSubscribeLogs 创建日志订阅
+//
+// 订阅到期、被取消或面板连接断开时结束；断线重连后面板需要重新订阅。
+func (w *WebSocketReporter) handleSubscribeLogs(data interface{}) (SubscribeLogsResponse, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return SubscribeLogsResponse{}, fmt.Errorf("序列化日志订阅数据失败: %v", err)
+	}
+
+	var req SubscribeLogsRequest
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return SubscribeLogsResponse{}, fmt.Errorf("解析日志订阅请求失败: %v", err)
+	}
+
+	duration := logStreamDefaultDuration
+	if req.Duration > 0 {
+		duration = min(time.Duration(req.Duration)*time.Second, logStreamMaxDuration)
+	}
+
+	w.logs.mu.Lock()
+	defer w.logs.mu.Unlock()
+
+	if len(w.logs.streams) >= logStreamMaxCount {
+		return SubscribeLogsResponse{}, fmt.Errorf("日志订阅数量已达上限 %d", logStreamMaxCount)
+	}
+
+	sub, err := xlogger.Subscribe(xlogger.Filter{
+		Service: req.Service,
+		SID:     req.Sid,
+		Level:   logger.LogLevel(req.Level),
+	}, logStreamBufferSize)
+	if err != nil {
+		return SubscribeLogsResponse{}, fmt.Errorf("无效的日志级别: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(w.ctx, duration)
+	ls := &logStream{
+		id:     xid.New().String(),
+		sub:    sub,
+		cancel: cancel,
+	}
+	w.logs.streams[ls.id] = ls
+	go w.runLogStream(ctx, ls)
+
+	xlogger.Printf("📜 日志订阅 %s 已创建，服务: %q，级别: %q，时长: %v\n", ls.id, req.Service, req.Level, duration)
+
+	return SubscribeLogsResponse{
+		Id:        ls.id,
+		ExpiresAt: time.Now().Add(duration).Unix(),
+	}, nil
+}
+
+// handleUnsubscribeLogs 取消日志订阅，缓冲中剩余的记录随结束消息一并上报
+func (w *WebSocketReporter) handleUnsubscribeLogs(data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化取消订阅数据失败: %v", err)
+	}
+
+	var req UnsubscribeLogsRequest
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return fmt.Errorf("解析取消订阅请求失败: %v", err)
+	}
+
+	w.logs.mu.Lock()
+	ls, ok := w.logs.streams[req.Id]
+	w.logs.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("日志订阅 %s 不存在", req.Id)
+	}
+
+	ls.cancel()
+	return nil
+}
+
+// handleSetLogLevel 在运行时修改单个服务的日志级别，不需要重启服务
+//
+// 级别按服务名称生效，服务更新重建后仍然保留，节点重启后失效。
+func (w *WebSocketReporter) handleSetLogLevel(data interface{}) (SetLogLevelResponse, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return SetLogLevelResponse{}, fmt.Errorf("序列化日志级别数据失败: %v", err)
+	}
+
+	var req SetLogLevelRequest
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return SetLogLevelResponse{}, fmt.Errorf("解析日志级别请求失败: %v", err)
+	}
+
+	if req.Service == "" {
+		return SetLogLevelResponse{}, errors.New("服务名称不能为空")
+	}
+	if !registry.ServiceRegistry().IsRegistered(req.Service) {
+		return SetLogLevelResponse{}, fmt.Errorf("服务 %s 不存在", req.Service)
+	}
+
+	previous, err := xlogger.SetServiceLevel(req.Service, logger.LogLevel(req.Level))
+	if err != nil {
+		return SetLogLevelResponse{}, fmt.Errorf("设置日志级别失败: %v", err)
+	}
+
+	if req.Level == "" {
+		xlogger.Printf("📜 服务 %s 的日志级别已恢复为配置值\n", req.Service)
+	} else {
+		xlogger.Printf("📜 服务 %s 的日志级别已设置为 %s\n", req.Service, req.Level)
+	}
+
+	return SetLogLevelResponse{
+		Service:  req.Service,
+		Level:    req.Level,
+		Previous: string(previous),
+	}, nil
+}
+
+// runLogStream 定时或攒够一批后上报订阅的日志记录，订阅结束时上报剩余记录和结束原因
+func (w *WebSocketReporter) runLogStream(ctx context.Context, ls *logStream) {
+	defer func() {
+		ls.cancel()
+		ls.sub.Close()
+
+		w.logs.mu.Lock()
+		delete(w.logs.streams, ls.id)
+		w.logs.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(logStreamFlushInterval)
+	defer ticker.Stop()
+
+	var records []LogRecord
+	for {
+		select {
+		case r := <-ls.sub.Records():
+			records = append(records, newLogRecord(r))
+			if len(records) < logStreamBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(records) == 0 {
+				continue
+			}
+		case <-ctx.Done():
+			reason := "expired"
+			if errors.Is(ctx.Err(), context.Canceled) {
+				reason = "canceled"
+			}
+			w.finishLogStream(ls, w.drainLogStream(ls, records), reason)
+			return
+		}
+
+		if err := w.reportLogs(ctx, LogRecordBatch{
+			Id:      ls.id,
+			Records: records,
+			Dropped: ls.sub.Dropped(),
+		}); err != nil {
+			// 上报期间订阅被取消或到期时保留记录，随结束消息上报
+			if ctx.Err() != nil {
+				continue
+			}
+			if !errors.Is(err, service.ErrReportTransportUnavailable) {
+				xlogger.Printf("⚠️ 上报日志订阅 %s 失败: %v，订阅已结束\n", ls.id, err)
+			}
+			return
+		}
+		records = nil
+	}
+}
+
+// drainLogStream 取出缓冲中剩余的记录，最多一批
+func (w *WebSocketReporter) drainLogStream(ls *logStream, records []LogRecord) []LogRecord {
+	for len(records) < logStreamBatchSize {
+		select {
+		case r := <-ls.sub.Records():
+			records = append(records, newLogRecord(r))
+		default:
+			return records
+		}
+	}
+	return records
+}
+
+// finishLogStream 上报订阅结束消息，节点停止时不再上报
+func (w *WebSocketReporter) finishLogStream(ls *logStream, records []LogRecord, reason string) {
+	if w.ctx.Err() != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(w.ctx, 10*time.Second)
+	defer cancel()
+
+	w.reportLogs(ctx, LogRecordBatch{
+		Id:      ls.id,
+		Records: records,
+		Dropped: ls.sub.Dropped(),
+		Done:    true,
+		Reason:  reason,
+	})
+}
+
+func newLogRecord(r xlogger.Record) LogRecord {
+	return LogRecord{
+		Time:   r.Time.UnixMilli(),
+		Level:  string(r.Level),
+		Msg:    r.Message,
+		Fields: r.Fields,
+	}
+}
+
+func (w *WebSocketReporter) reportLogs(ctx context.Context, batch LogRecordBatch) error {
+	if batch.Records == nil {
+		batch.Records = []LogRecord{}
+	}
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	_, err = w.Report(ctx, "LogRecords", data)
+	return err
+}
@@ -0,0 +1,214 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-gost/x/config"
+	xlogger "github.com/go-gost/x/logger"
+	"github.com/go-gost/x/service"
+	"github.com/gorilla/websocket"
+	"github.com/rs/xid"
+)
+
+const (
+	// reportAckTimeout 等待面板确认上报消息的默认超时
+	reportAckTimeout = 15 * time.Second
+	// reportPauseDuration 面板未确认上报后改用 HTTP 的时长，旧版面板不确认上报消息
+	reportPauseDuration = 5 * time.Minute
+	// reportCompressThreshold 超过该大小的上报消息使用 gzip 压缩
+	reportCompressThreshold = 4 * 1024
+)
+
+// ReportMessage 节点主动上报的消息，面板以相同 requestId 的 CommandResponse 确认
+type ReportMessage struct {
+	Type       string      `json:"type"`
+	Compressed bool        `json:"compressed,omitempty"`
+	Data       interface{} `json:"data"`
+	RequestId  string      `json:"requestId"`
+}
+
+// Report 通过 WebSocket 发送一条类型化的上报消息并等待面板确认
+//
+// 连接未建立或面板未在超时内确认时返回 service.ErrReportTransportUnavailable，
+// 调用方据此回退到 HTTP。确认超时后 reportPauseDuration 内或重连前不再尝试 WebSocket。
+func (w *WebSocketReporter) Report(ctx context.Context, msgType string, data []byte) (json.RawMessage, error) {
+	if time.Now().Unix() < w.reportPaused.Load() {
+		return nil, service.ErrReportTransportUnavailable
+	}
+
+	msg := ReportMessage{
+		Type:      msgType,
+		Data:      json.RawMessage(data),
+		RequestId: xid.New().String(),
+	}
+	if len(data) > reportCompressThreshold {
+		if compressed, err := gzipData(data); err == nil {
+			msg.Compressed = true
+			msg.Data = compressed
+		}
+	}
+
+	jsonData, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("序列化上报消息失败: %v", err)
+	}
+
+	ch := make(chan CommandResponse, 1)
+	w.pendingMutex.Lock()
+	w.pending[msg.RequestId] = ch
+	w.pendingMutex.Unlock()
+
+	defer func() {
+		w.pendingMutex.Lock()
+		delete(w.pending, msg.RequestId)
+		w.pendingMutex.Unlock()
+	}()
+
+	if err := w.writeReport(jsonData); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(reportAckTimeout)
+	defer timer.Stop()
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			// 等待期间连接断开，面板是否收到未知；流量记录按序号去重，可以安全地回退到 HTTP 重发
+			return nil, fmt.Errorf("%w: 等待 %s 确认时连接断开", service.ErrReportTransportUnavailable, msgType)
+		}
+		if !resp.Success {
+			return nil, fmt.Errorf("面板拒绝 %s: %s", msgType, resp.Message)
+		}
+		data, err := json.Marshal(resp.Data)
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 确认失败: %v", msgType, err)
+		}
+		return data, nil
+	case <-timer.C:
+		now := time.Now()
+		if w.reportPaused.Swap(now.Add(reportPauseDuration).Unix()) <= now.Unix() {
+			xlogger.Printf("⚠️ 面板未确认 %s 上报，%v 内改用 HTTP 上报\n", msgType, reportPauseDuration)
+		}
+		return nil, fmt.Errorf("%w: 等待 %s 确认超时", service.ErrReportTransportUnavailable, msgType)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// writeReport 写入一条上报消息
+func (w *WebSocketReporter) writeReport(jsonData []byte) error {
+	w.connMutex.Lock()
+	defer w.connMutex.Unlock()
+
+	if w.conn == nil || !w.connected {
+		return service.ErrReportTransportUnavailable
+	}
+
+	messageData := w.encodeMessage(jsonData)
+
+	timeout := 5 * time.Second
+	if len(messageData) > 1024*1024 {
+		timeout = 30 * time.Second
+	}
+
+	w.conn.SetWriteDeadline(time.Now().Add(timeout))
+	if err := w.conn.WriteMessage(websocket.TextMessage, messageData); err != nil {
+		w.connected = false
+		return fmt.Errorf("%w: 写入消息失败: %v", service.ErrReportTransportUnavailable, err)
+	}
+	return nil
+}
+
+// deliverReportAck 如果消息是对本节点上报的确认，交给等待方并返回 true
+func (w *WebSocketReporter) deliverReportAck(message []byte) bool {
+	var resp CommandResponse
+	if err := json.Unmarshal(message, &resp); err != nil || resp.RequestId == "" {
+		return false
+	}
+
+	w.pendingMutex.Lock()
+	ch, ok := w.pending[resp.RequestId]
+	if ok {
+		delete(w.pending, resp.RequestId)
+	}
+	w.pendingMutex.Unlock()
+
+	if !ok {
+		return false
+	}
+	ch <- resp
+	close(ch)
+	return true
+}
+
+// failPendingReports 连接断开时唤醒所有等待确认的上报
+func (w *WebSocketReporter) failPendingReports() {
+	w.pendingMutex.Lock()
+	defer w.pendingMutex.Unlock()
+
+	for id, ch := range w.pending {
+		close(ch)
+		delete(w.pending, id)
+	}
+}
+
+// ConfigState 节点当前配置的摘要，连接建立后上报给面板用于对账
+type ConfigState struct {
+	Hash     string `json:"hash"`
+	Services int    `json:"services"`
+	Chains   int    `json:"chains"`
+	Limiters int    `json:"limiters"`
+}
+
+// reportConfigState 上报当前配置的校验和
+//
+// 面板据此判断节点恢复的配置是否与期望一致，不一致时重新下发；
+// 确认中携带 {"match":false} 时仅记录日志，由面板负责后续同步。
+func (w *WebSocketReporter) reportConfigState() {
+	hash, err := StateHash()
+	if err != nil {
+		xlogger.Printf("⚠️ 计算配置校验和失败: %v\n", err)
+		return
+	}
+
+	cfg := config.Global()
+	data, err := json.Marshal(ConfigState{
+		Hash:     hash,
+		Services: len(cfg.Services),
+		Chains:   len(cfg.Chains),
+		Limiters: len(cfg.Limiters),
+	})
+	if err != nil {
+		return
+	}
+
+	ack, err := w.Report(w.ctx, "ConfigState", data)
+	if err != nil {
+		xlogger.Printf("⚠️ 上报配置校验和失败: %v\n", err)
+		return
+	}
+
+	var result struct {
+		Match *bool `json:"match"`
+	}
+	if json.Unmarshal(ack, &result) == nil && result.Match != nil && !*result.Match {
+		xlogger.Printf("⚠️ 节点配置与面板不一致 (hash: %s)，等待面板重新下发\n", hash)
+	}
+}
+
+// reportDrain 上报服务排空进度，面板未确认时不重发
+func (w *WebSocketReporter) reportDrain(st service.DrainStatus) {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return
+	}
+	go func() {
+		if _, err := w.Report(w.ctx, "ServiceDrain", data); err != nil && st.Done {
+			xlogger.Printf("⚠️ 上报服务 %s 排空结果失败: %v\n", st.Service, err)
+		}
+	}()
+}
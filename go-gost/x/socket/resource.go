@@ -0,0 +1,463 @@
+package socket
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-gost/core/admission"
+	"github.com/go-gost/core/auth"
+	"github.com/go-gost/core/bypass"
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/hop"
+	"github.com/go-gost/core/hosts"
+	"github.com/go-gost/core/ingress"
+	"github.com/go-gost/core/limiter/conn"
+	"github.com/go-gost/core/limiter/rate"
+	"github.com/go-gost/core/limiter/traffic"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/observer"
+	"github.com/go-gost/core/recorder"
+	reg "github.com/go-gost/core/registry"
+	"github.com/go-gost/core/resolver"
+	"github.com/go-gost/core/router"
+	"github.com/go-gost/core/sd"
+	"github.com/go-gost/x/config"
+	admission_parser "github.com/go-gost/x/config/parsing/admission"
+	auth_parser "github.com/go-gost/x/config/parsing/auth"
+	bypass_parser "github.com/go-gost/x/config/parsing/bypass"
+	chain_parser "github.com/go-gost/x/config/parsing/chain"
+	hop_parser "github.com/go-gost/x/config/parsing/hop"
+	hosts_parser "github.com/go-gost/x/config/parsing/hosts"
+	ingress_parser "github.com/go-gost/x/config/parsing/ingress"
+	limiter_parser "github.com/go-gost/x/config/parsing/limiter"
+	observer_parser "github.com/go-gost/x/config/parsing/observer"
+	recorder_parser "github.com/go-gost/x/config/parsing/recorder"
+	resolver_parser "github.com/go-gost/x/config/parsing/resolver"
+	router_parser "github.com/go-gost/x/config/parsing/router"
+	sd_parser "github.com/go-gost/x/config/parsing/sd"
+	"github.com/go-gost/x/registry"
+)
+
+// resourceKind 可以通过 Apply/Delete/Get 和事务远程管理的一类资源
+type resourceKind interface {
+	// get 返回指定名称的资源配置，name 为空时返回全部
+	get(name string) (any, error)
+	// stage 在事务中执行 add/update/put 操作，返回资源名称
+	stage(t *txn, op transactionOp) (string, error)
+	// remove 在事务中删除资源
+	remove(t *txn, name string) error
+}
+
+// registryResource 由注册表和全局配置中的一个列表共同描述的资源，
+// 与 x/api 中对应接口使用相同的解析器和配置更新逻辑
+type registryResource[C any, V any] struct {
+	kind     string
+	registry func() reg.Registry[V]
+	parse    func(*C) (V, error)
+	name     func(*C) *string
+	list     func(*config.Config) *[]*C
+}
+
+func (r *registryResource[C, V]) stage(t *txn, op transactionOp) (string, error) {
+	name := strings.TrimSpace(op.Name)
+	cfg := new(C)
+	if err := decodeOpData(op, cfg, r.name(cfg), &name); err != nil {
+		return name, err
+	}
+	if err := checkExists(op.Op, name, r.find(t.cfg, name) != nil || r.registry().IsRegistered(name)); err != nil {
+		return name, err
+	}
+	return name, r.put(t, cfg)
+}
+
+// put 在事务中创建或替换资源，撤销时按变更前的配置重新创建
+func (r *registryResource[C, V]) put(t *txn, cfg *C) error {
+	name := *r.name(cfg)
+	v, err := r.parse(cfg)
+	if err != nil {
+		return fmt.Errorf("create %s %s failed: %v", r.kind, name, err)
+	}
+
+	// 注销时关闭实现了 io.Closer 的旧对象（跳跃点的重载和健康检查、链内联的跳跃点等）
+	old := r.find(t.cfg, name)
+	r.registry().Unregister(name)
+	if err := r.registry().Register(name, v); err != nil {
+		return fmt.Errorf("%s %s already exists", r.kind, name)
+	}
+
+	r.setList(t, replaceByName(*r.list(t.cfg), cfg, func(v *C) string { return *r.name(v) }))
+	t.undo = append(t.undo, func() error {
+		r.registry().Unregister(name)
+		if old != nil {
+			return r.restore(old)
+		}
+		return nil
+	})
+	return nil
+}
+
+// remove 在事务中删除资源，只存在于注册表中的资源同样会被删除
+func (r *registryResource[C, V]) remove(t *txn, name string) error {
+	name = strings.TrimSpace(name)
+	old := r.find(t.cfg, name)
+	if old == nil && !r.registry().IsRegistered(name) {
+		return fmt.Errorf("%s %s not found", r.kind, name)
+	}
+
+	// 注销时关闭实现了 io.Closer 的旧对象
+	r.registry().Unregister(name)
+	r.setList(t, removeByName(*r.list(t.cfg), name, func(v *C) string { return *r.name(v) }))
+	t.undo = append(t.undo, func() error {
+		if old != nil {
+			return r.restore(old)
+		}
+		return nil
+	})
+	return nil
+}
+
+func (r *registryResource[C, V]) restore(cfg *C) error {
+	v, err := r.parse(cfg)
+	if err != nil {
+		return fmt.Errorf("恢复 %s %s 失败: %v", r.kind, *r.name(cfg), err)
+	}
+	return r.registry().Register(*r.name(cfg), v)
+}
+
+func (r *registryResource[C, V]) find(cfg *config.Config, name string) *C {
+	for _, v := range *r.list(cfg) {
+		if *r.name(v) == name {
+			return v
+		}
+	}
+	return nil
+}
+
+func (r *registryResource[C, V]) setList(t *txn, list []*C) {
+	*r.list(t.cfg) = list
+	t.touch(r.kind, func(dst, src *config.Config) { *r.list(dst) = *r.list(src) })
+}
+
+func (r *registryResource[C, V]) get(name string) (any, error) {
+	name = strings.TrimSpace(name)
+
+	var out []*C
+	for _, v := range *r.list(config.Global()) {
+		if name == "" || *r.name(v) == name {
+			out = append(out, v)
+		}
+	}
+	if name != "" && len(out) == 0 {
+		return nil, fmt.Errorf("%s %s not found", r.kind, name)
+	}
+	if name != "" {
+		return out[0], nil
+	}
+	if out == nil {
+		out = []*C{}
+	}
+	return out, nil
+}
+
+// noError 包装不返回错误的解析函数
+func noError[C any, V any](f func(*C) V) func(*C) (V, error) {
+	return func(c *C) (V, error) { return f(c), nil }
+}
+
+var (
+	chainResource = &registryResource[config.ChainConfig, chain.Chainer]{
+		kind:     "chain",
+		registry: registry.ChainRegistry,
+		parse: func(c *config.ChainConfig) (chain.Chainer, error) {
+			return chain_parser.ParseChain(c, logger.Default())
+		},
+		name: func(c *config.ChainConfig) *string { return &c.Name },
+		list: func(c *config.Config) *[]*config.ChainConfig { return &c.Chains },
+	}
+	limiterResource = &registryResource[config.LimiterConfig, traffic.TrafficLimiter]{
+		kind:     "limiter",
+		registry: registry.TrafficLimiterRegistry,
+		parse:    noError(limiter_parser.ParseTrafficLimiter),
+		name:     func(c *config.LimiterConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.LimiterConfig { return &c.Limiters },
+	}
+)
+
+// resourceKinds 支持的资源类型，键与配置文件中的字段含义一致
+var resourceKinds = map[string]resourceKind{
+	"chain": chainResource,
+	"hop": &registryResource[config.HopConfig, hop.Hop]{
+		kind:     "hop",
+		registry: registry.HopRegistry,
+		parse: func(c *config.HopConfig) (hop.Hop, error) {
+			return hop_parser.ParseHop(c, logger.Default())
+		},
+		name: func(c *config.HopConfig) *string { return &c.Name },
+		list: func(c *config.Config) *[]*config.HopConfig { return &c.Hops },
+	},
+	"auther": &registryResource[config.AutherConfig, auth.Authenticator]{
+		kind:     "auther",
+		registry: registry.AutherRegistry,
+		parse:    noError(auth_parser.ParseAuther),
+		name:     func(c *config.AutherConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.AutherConfig { return &c.Authers },
+	},
+	"admission": &registryResource[config.AdmissionConfig, admission.Admission]{
+		kind:     "admission",
+		registry: registry.AdmissionRegistry,
+		parse:    noError(admission_parser.ParseAdmission),
+		name:     func(c *config.AdmissionConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.AdmissionConfig { return &c.Admissions },
+	},
+	"bypass": &registryResource[config.BypassConfig, bypass.Bypass]{
+		kind:     "bypass",
+		registry: registry.BypassRegistry,
+		parse:    noError(bypass_parser.ParseBypass),
+		name:     func(c *config.BypassConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.BypassConfig { return &c.Bypasses },
+	},
+	"resolver": &registryResource[config.ResolverConfig, resolver.Resolver]{
+		kind:     "resolver",
+		registry: registry.ResolverRegistry,
+		parse:    resolver_parser.ParseResolver,
+		name:     func(c *config.ResolverConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.ResolverConfig { return &c.Resolvers },
+	},
+	"hosts": &registryResource[config.HostsConfig, hosts.HostMapper]{
+		kind:     "hosts",
+		registry: registry.HostsRegistry,
+		parse:    noError(hosts_parser.ParseHostMapper),
+		name:     func(c *config.HostsConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.HostsConfig { return &c.Hosts },
+	},
+	"ingress": &registryResource[config.IngressConfig, ingress.Ingress]{
+		kind:     "ingress",
+		registry: registry.IngressRegistry,
+		parse:    noError(ingress_parser.ParseIngress),
+		name:     func(c *config.IngressConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.IngressConfig { return &c.Ingresses },
+	},
+	"router": &registryResource[config.RouterConfig, router.Router]{
+		kind:     "router",
+		registry: registry.RouterRegistry,
+		parse:    noError(router_parser.ParseRouter),
+		name:     func(c *config.RouterConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.RouterConfig { return &c.Routers },
+	},
+	"sd": &registryResource[config.SDConfig, sd.SD]{
+		kind:     "sd",
+		registry: registry.SDRegistry,
+		parse:    noError(sd_parser.ParseSD),
+		name:     func(c *config.SDConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.SDConfig { return &c.SDs },
+	},
+	"recorder": &registryResource[config.RecorderConfig, recorder.Recorder]{
+		kind:     "recorder",
+		registry: registry.RecorderRegistry,
+		parse:    noError(recorder_parser.ParseRecorder),
+		name:     func(c *config.RecorderConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.RecorderConfig { return &c.Recorders },
+	},
+	"observer": &registryResource[config.ObserverConfig, observer.Observer]{
+		kind:     "observer",
+		registry: registry.ObserverRegistry,
+		parse:    noError(observer_parser.ParseObserver),
+		name:     func(c *config.ObserverConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.ObserverConfig { return &c.Observers },
+	},
+	"limiter": limiterResource,
+	"climiter": &registryResource[config.LimiterConfig, conn.ConnLimiter]{
+		kind:     "climiter",
+		registry: registry.ConnLimiterRegistry,
+		parse:    noError(limiter_parser.ParseConnLimiter),
+		name:     func(c *config.LimiterConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.LimiterConfig { return &c.CLimiters },
+	},
+	"rlimiter": &registryResource[config.LimiterConfig, rate.RateLimiter]{
+		kind:     "rlimiter",
+		registry: registry.RateLimiterRegistry,
+		parse:    noError(limiter_parser.ParseRateLimiter),
+		name:     func(c *config.LimiterConfig) *string { return &c.Name },
+		list:     func(c *config.Config) *[]*config.LimiterConfig { return &c.RLimiters },
+	},
+}
+
+// resourceRequest Apply/Delete/Get 命令的请求
+type resourceRequest struct {
+	// Kind 资源类型，见 resourceKinds
+	Kind string `json:"kind"`
+	// Name 单个资源名称（Delete/Get）
+	Name string `json:"name,omitempty"`
+	// Names 多个资源名称（Delete）
+	Names []string `json:"names,omitempty"`
+	// Data 单个资源配置或配置数组（Apply）
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// ResourceResponse Apply/Delete 的执行结果
+type ResourceResponse struct {
+	Kind  string   `json:"kind"`
+	Names []string `json:"names"`
+}
+
+func (w *WebSocketReporter) decodeResourceRequest(data interface{}) (resourceRequest, resourceKind, error) {
+	var req resourceRequest
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return req, nil, fmt.Errorf("序列化数据失败: %v", err)
+	}
+
+	// 预处理：将字符串格式的 duration 转换为纳秒数
+	processedData, err := w.preprocessDurationFields(jsonData)
+	if err != nil {
+		return req, nil, fmt.Errorf("预处理duration字段失败: %v", err)
+	}
+
+	if err := json.Unmarshal(processedData, &req); err != nil {
+		return req, nil, fmt.Errorf("解析资源请求失败: %v", err)
+	}
+
+	req.Kind = strings.ToLower(strings.TrimSpace(req.Kind))
+	kind, ok := resourceKinds[req.Kind]
+	if !ok {
+		return req, nil, fmt.Errorf("未知资源类型: %s，支持: %s", req.Kind, strings.Join(resourceKindNames(), ", "))
+	}
+	return req, kind, nil
+}
+
+// handleApply 创建或替换一个或多个资源
+//
+// 多个资源在一个事务中生效，任一资源失败时全部撤销。
+func (w *WebSocketReporter) handleApply(data interface{}) (ResourceResponse, error) {
+	req, kind, err := w.decodeResourceRequest(data)
+	if err != nil {
+		return ResourceResponse{}, err
+	}
+
+	items, err := splitResourceData(req.Data)
+	if err != nil {
+		return ResourceResponse{}, err
+	}
+
+	resp := ResourceResponse{Kind: req.Kind, Names: []string{}}
+	t := beginTxn()
+	for _, item := range items {
+		name, err := kind.stage(t, transactionOp{Op: txnOpPut, Kind: req.Kind, Data: item})
+		if err != nil {
+			return ResourceResponse{Kind: req.Kind, Names: []string{}}, rollbackResource(t, err)
+		}
+		resp.Names = append(resp.Names, name)
+	}
+	if err := t.commit(); err != nil {
+		return ResourceResponse{Kind: req.Kind, Names: []string{}}, err
+	}
+	return resp, nil
+}
+
+// handleDelete 删除一个或多个资源，多个资源在一个事务中删除
+func (w *WebSocketReporter) handleDelete(data interface{}) (ResourceResponse, error) {
+	req, kind, err := w.decodeResourceRequest(data)
+	if err != nil {
+		return ResourceResponse{}, err
+	}
+
+	list := req.Names
+	if req.Name != "" {
+		list = append([]string{req.Name}, list...)
+	}
+	if len(list) == 0 {
+		return ResourceResponse{}, errors.New("name or names is required")
+	}
+
+	resp := ResourceResponse{Kind: req.Kind, Names: []string{}}
+	t := beginTxn()
+	for _, name := range list {
+		if err := kind.remove(t, name); err != nil {
+			return ResourceResponse{Kind: req.Kind, Names: []string{}}, rollbackResource(t, err)
+		}
+		resp.Names = append(resp.Names, strings.TrimSpace(name))
+	}
+	if err := t.commit(); err != nil {
+		return ResourceResponse{Kind: req.Kind, Names: []string{}}, err
+	}
+	return resp, nil
+}
+
+// rollbackResource 撤销 Apply/Delete 已生效的资源，返回原始错误
+func rollbackResource(t *txn, err error) error {
+	if rerr := t.rollback(); rerr != nil {
+		return fmt.Errorf("%v，回滚未完全成功: %v", err, rerr)
+	}
+	return err
+}
+
+// handleGet 查询资源配置
+func (w *WebSocketReporter) handleGet(data interface{}) (any, error) {
+	req, kind, err := w.decodeResourceRequest(data)
+	if err != nil {
+		return nil, err
+	}
+	return kind.get(req.Name)
+}
+
+// splitResourceData 将单个配置或配置数组拆分为单个配置
+func splitResourceData(data json.RawMessage) ([]json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, errors.New("缺少资源配置 data")
+	}
+	if data[0] != '[' {
+		return []json.RawMessage{data}, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("解析资源配置失败: %v", err)
+	}
+	if len(items) == 0 {
+		return nil, errors.New("资源配置列表不能为空")
+	}
+	return items, nil
+}
+
+// resourceKeys 提取 Apply/Delete 涉及的资源，供命令调度排序使用
+func resourceKeys(raw []byte) []string {
+	var req resourceRequest
+	if json.Unmarshal(raw, &req) != nil {
+		return nil
+	}
+	kind := strings.ToLower(strings.TrimSpace(req.Kind))
+
+	list := append([]string{req.Name}, req.Names...)
+	if items, err := splitResourceData(req.Data); err == nil {
+		for _, item := range items {
+			var v struct {
+				Name string `json:"name"`
+			}
+			json.Unmarshal(item, &v)
+			list = append(list, v.Name)
+		}
+	}
+
+	var keys []string
+	for _, name := range list {
+		if name = strings.TrimSpace(name); name != "" {
+			keys = append(keys, kind+":"+name)
+		}
+	}
+	return keys
+}
+
+func resourceKindNames() []string {
+	list := make([]string, 0, len(resourceKinds))
+	for k := range resourceKinds {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
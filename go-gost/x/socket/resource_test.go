@@ -0,0 +1,95 @@
+package socket
+
+import (
+	"testing"
+
+	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleApply(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		data   any
+		expect []string
+	}{
+		{
+			desc:   "single item",
+			data:   map[string]any{"name": "res-a"},
+			expect: []string{"res-a"},
+		},
+		{
+			desc:   "multiple items",
+			data:   []map[string]any{{"name": "res-a"}, {"name": "res-b"}},
+			expect: []string{"res-a", "res-b"},
+		},
+		{
+			desc: "failed item rolls back the earlier ones",
+			data: []map[string]any{{"name": "res-a"}, {"name": " "}},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			useStateDir(t)
+			t.Cleanup(func() {
+				registry.BypassRegistry().Unregister("res-a")
+				registry.BypassRegistry().Unregister("res-b")
+			})
+
+			w := &WebSocketReporter{}
+			resp, err := w.handleApply(map[string]any{"kind": "bypass", "data": test.data})
+			if test.expect == nil {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expect, append([]string(nil), resp.Names...))
+
+			configured := names(config.Global().Bypasses, func(c *config.BypassConfig) string { return c.Name })
+			assert.ElementsMatch(t, test.expect, configured)
+			for _, name := range []string{"res-a", "res-b"} {
+				assert.Equal(t, contains(test.expect, name), registry.BypassRegistry().IsRegistered(name), name)
+			}
+		})
+	}
+}
+
+func TestHandleDelete(t *testing.T) {
+	useStateDir(t)
+	t.Cleanup(func() {
+		registry.BypassRegistry().Unregister("res-a")
+		registry.BypassRegistry().Unregister("res-b")
+	})
+
+	w := &WebSocketReporter{}
+	_, err := w.handleApply(map[string]any{
+		"kind": "bypass",
+		"data": []map[string]any{{"name": "res-a"}, {"name": "res-b"}},
+	})
+	require.NoError(t, err)
+
+	// a missing name fails the whole deletion
+	_, err = w.handleDelete(map[string]any{"kind": "bypass", "names": []string{"res-a", "res-missing"}})
+	assert.Error(t, err)
+	assert.True(t, registry.BypassRegistry().IsRegistered("res-a"))
+	assert.Len(t, config.Global().Bypasses, 2)
+
+	resp, err := w.handleDelete(map[string]any{"kind": "bypass", "name": "res-a", "names": []string{"res-b"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"res-a", "res-b"}, resp.Names)
+	assert.False(t, registry.BypassRegistry().IsRegistered("res-a"))
+	assert.False(t, registry.BypassRegistry().IsRegistered("res-b"))
+	assert.Empty(t, config.Global().Bypasses)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -9,8 +9,9 @@ import (
 	"github.com/go-gost/core/service"
 	"github.com/go-gost/x/config"
 	parser "github.com/go-gost/x/config/parsing/service"
-	kill "github.com/go-gost/x/internal/util/port"
+	xlogger "github.com/go-gost/x/logger"
 	"github.com/go-gost/x/registry"
+	xservice "github.com/go-gost/x/service"
 )
 
 func createServices(req createServicesRequest) error {
@@ -101,34 +102,14 @@ func updateServices(req updateServicesRequest) error {
 		}
 	}
 
-	// 第二阶段：按照原来的updateService逻辑，逐个更新服务
+	// 第二阶段：逐个更新服务，只有监听相关的配置变化时才重新监听
 	for _, serviceConfig := range req.Data {
 		name := strings.TrimSpace(serviceConfig.Name)
 		serviceConfig.Name = name
 
-		// 1. 获取旧服务
-		old := registry.ServiceRegistry().Get(name)
-
-		// 2. 关闭旧服务
-		old.Close()
-
-		// 3. 从注册表移除旧服务
-		registry.ServiceRegistry().Unregister(name)
-
-		// 4. 解析新服务配置
-		svc, err := parser.ParseService(&serviceConfig)
-		if err != nil {
-			return errors.New("create service " + name + " failed: " + err.Error())
+		if err := updateService(serviceConfigOf(name), &serviceConfig); err != nil {
+			return errors.New("update service " + name + " failed: " + err.Error())
 		}
-
-		// 5. 注册新服务
-		if err := registry.ServiceRegistry().Register(name, svc); err != nil {
-			svc.Close()
-			return errors.New("service " + name + " already exists")
-		}
-
-		// 6. 启动新服务
-		go svc.Serve()
 	}
 
 	// 第三阶段：更新配置
@@ -147,10 +128,10 @@ func updateServices(req updateServicesRequest) error {
 	return nil
 }
 
-func deleteServices(req deleteServicesRequest) error {
+func deleteServices(req deleteServicesRequest, progress func(xservice.DrainStatus)) (*ServiceDrainResponse, error) {
 
 	if len(req.Services) == 0 {
-		return errors.New("services list cannot be empty")
+		return nil, errors.New("services list cannot be empty")
 	}
 
 	// 第一阶段：验证所有服务是否存在
@@ -162,12 +143,12 @@ func deleteServices(req deleteServicesRequest) error {
 	for _, serviceName := range req.Services {
 		name := strings.TrimSpace(serviceName)
 		if name == "" {
-			return errors.New("service name is required")
+			return nil, errors.New("service name is required")
 		}
 
 		svc := registry.ServiceRegistry().Get(name)
 		if svc == nil {
-			return errors.New("service " + name + " not found")
+			return nil, errors.New("service " + name + " not found")
 		}
 
 		servicesToDelete = append(servicesToDelete, struct {
@@ -177,9 +158,11 @@ func deleteServices(req deleteServicesRequest) error {
 	}
 
 	// 第二阶段：删除所有服务
+	resp := req.newResponse()
 	for _, std := range servicesToDelete {
 		registry.ServiceRegistry().Unregister(std.name)
 		std.service.Close()
+		req.disconnect(std.name, std.service, progress, resp)
 	}
 
 	// 第三阶段：更新配置
@@ -201,13 +184,13 @@ func deleteServices(req deleteServicesRequest) error {
 		return nil
 	})
 
-	return nil
+	return resp, nil
 }
 
-func pauseServices(req pauseServicesRequest) error {
+func pauseServices(req pauseServicesRequest, progress func(xservice.DrainStatus)) (*ServiceDrainResponse, error) {
 
 	if len(req.Services) == 0 {
-		return errors.New("services list cannot be empty")
+		return nil, errors.New("services list cannot be empty")
 	}
 
 	// 第一阶段：验证所有服务是否存在，并筛选需要暂停的服务
@@ -221,12 +204,12 @@ func pauseServices(req pauseServicesRequest) error {
 	for _, serviceName := range req.Services {
 		name := strings.TrimSpace(serviceName)
 		if name == "" {
-			return errors.New("service name is required")
+			return nil, errors.New("service name is required")
 		}
 
 		svc := registry.ServiceRegistry().Get(name)
 		if svc == nil {
-			return errors.New(fmt.Sprintf("service %s not found", name))
+			return nil, errors.New(fmt.Sprintf("service %s not found", name))
 		}
 
 		//// 检查服务是否已经暂停
@@ -266,21 +249,20 @@ func pauseServices(req pauseServicesRequest) error {
 	}
 
 	// 逐个暂停服务，如果失败则回滚
+	resp := req.newResponse()
 	for _, stp := range servicesToPause {
 		serviceConfig := serviceConfigs[stp.name]
 		if serviceConfig == nil {
 			// 找不到配置，回滚已暂停的服务
 			rollbackPausedServices(pausedServices)
-			return errors.New(fmt.Sprintf("service %s configuration not found", stp.name))
+			return nil, errors.New(fmt.Sprintf("service %s configuration not found", stp.name))
 		}
 
 		// 暂停服务
 		stp.service.Close()
 
-		// 强制断开端口的所有连接
-		if serviceConfig.Addr != "" {
-			_ = kill.ForceClosePortConnections(serviceConfig.Addr)
-		}
+		// 断开该服务的所有连接，排空模式下等待连接自然结束
+		req.disconnect(stp.name, stp.service, progress, resp)
 
 		// 记录已暂停的服务
 		pausedServices = append(pausedServices, struct {
@@ -309,10 +291,10 @@ func pauseServices(req pauseServicesRequest) error {
 	if err != nil {
 		// 配置更新失败，需要回滚所有暂停的服务
 		rollbackPausedServices(pausedServices)
-		return errors.New(fmt.Sprintf("Failed to update config, rolling back paused services: %v", err))
+		return nil, errors.New(fmt.Sprintf("Failed to update config, rolling back paused services: %v", err))
 	}
 
-	return nil
+	return resp, nil
 }
 
 func resumeServices(req resumeServicesRequest) error {
@@ -504,16 +486,94 @@ func rollbackResumedServices(resumedServices []struct {
 	}
 }
 
+// KillConnectionsResponse 断开连接的结果
+type KillConnectionsResponse struct {
+	// Closed 每个服务断开的连接数
+	Closed map[string]int `json:"closed"`
+	Total  int            `json:"total"`
+}
+
+// killConnections 断开指定服务当前的所有连接，服务本身继续运行
+func killConnections(req killConnectionsRequest) (KillConnectionsResponse, error) {
+	if len(req.Services) == 0 {
+		return KillConnectionsResponse{}, errors.New("services list cannot be empty")
+	}
+
+	var svcs []service.Service
+	for _, serviceName := range req.Services {
+		name := strings.TrimSpace(serviceName)
+		if name == "" {
+			return KillConnectionsResponse{}, errors.New("service name is required")
+		}
+		svc := registry.ServiceRegistry().Get(name)
+		if svc == nil {
+			return KillConnectionsResponse{}, errors.New(fmt.Sprintf("service %s not found", name))
+		}
+		svcs = append(svcs, svc)
+	}
+
+	resp := KillConnectionsResponse{Closed: make(map[string]int)}
+	for i, svc := range svcs {
+		n := xservice.CloseConnections(svc)
+		resp.Closed[strings.TrimSpace(req.Services[i])] = n
+		resp.Total += n
+	}
+	return resp, nil
+}
+
+type killConnectionsRequest struct {
+	Services []string `json:"services"`
+}
+
 type resumeServicesRequest struct {
 	Services []string `json:"services"`
 }
 
 type pauseServicesRequest struct {
 	Services []string `json:"services"`
+	drainOptions
 }
 
 type deleteServicesRequest struct {
 	Services []string `json:"services"`
+	drainOptions
+}
+
+// drainOptions 暂停、删除服务时的排空选项
+type drainOptions struct {
+	// Drain 为 true 时停止接受新连接后不立即断开已有连接，等待其自然结束
+	Drain bool `json:"drain,omitempty"`
+	// DrainTimeout 排空期限（秒），超过后强制断开剩余连接，默认 5 分钟
+	DrainTimeout int `json:"drainTimeout,omitempty"`
+}
+
+// ServiceDrainResponse 开始排空时各服务的剩余连接数
+type ServiceDrainResponse struct {
+	Draining map[string]int `json:"draining"`
+}
+
+func (o drainOptions) newResponse() *ServiceDrainResponse {
+	if !o.Drain {
+		return nil
+	}
+	return &ServiceDrainResponse{Draining: make(map[string]int)}
+}
+
+// disconnect 断开已停止服务的连接，排空模式下在后台等待连接结束
+func (o drainOptions) disconnect(name string, svc service.Service, progress func(xservice.DrainStatus), resp *ServiceDrainResponse) {
+	if !o.Drain {
+		if n := xservice.CloseConnections(svc); n > 0 {
+			xlogger.Printf("✂️ 服务 %s 已断开 %d 个连接\n", name, n)
+		}
+		return
+	}
+
+	timeout := time.Duration(o.DrainTimeout) * time.Second
+	n := xservice.Drain(name, svc, timeout, progress)
+	if resp != nil {
+		resp.Draining[name] = n
+	}
+	xlogger.Printf("⏳ 服务 %s 开始排空，剩余 %d 个连接\n", name, n)
 }
 
 type updateServicesRequest struct {
@@ -0,0 +1,149 @@
+package socket
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/go-gost/core/observer/stats"
+	"github.com/go-gost/x/registry"
+	"github.com/go-gost/x/service"
+)
+
+// ServiceStatus 服务的实时状态，随心跳上报
+//
+// 心跳中的 services 只包含与上次发送相比发生变化的服务，removed_services 为已删除的服务；
+// full 为 true 时 services 为全部服务。
+type ServiceStatus struct {
+	Name         string        `json:"name"`
+	State        string        `json:"state"`
+	CurrentConns uint64        `json:"current_conns"`
+	TotalConns   uint64        `json:"total_conns"`
+	TotalErrs    uint64        `json:"total_errs"`
+	InputRate    uint64        `json:"input_rate"`  // 输入速率（字节/秒）
+	OutputRate   uint64        `json:"output_rate"` // 输出速率（字节/秒）
+	LastEvent    *ServiceEvent `json:"last_event,omitempty"`
+}
+
+// ServiceEvent 服务的最近事件
+type ServiceEvent struct {
+	Time int64  `json:"time"`
+	Msg  string `json:"msg"`
+}
+
+type serviceStatusGetter interface {
+	Status() *service.Status
+}
+
+type trafficTotals interface {
+	TrafficTotals() (inputBytes, outputBytes uint64)
+}
+
+type trafficSample struct {
+	input  uint64
+	output uint64
+	time   time.Time
+	stats  stats.Stats
+}
+
+// serviceStatsCollector 采集服务状态并计算与上次发送的差异，只在心跳循环中使用
+type serviceStatsCollector struct {
+	samples map[string]trafficSample
+	sent    map[string]json.RawMessage // 面板已知的各服务状态
+}
+
+func newServiceStatsCollector() *serviceStatsCollector {
+	return &serviceStatsCollector{
+		samples: make(map[string]trafficSample),
+	}
+}
+
+// reset 清空已发送的状态，下次返回全部服务
+func (c *serviceStatsCollector) reset() {
+	c.sent = nil
+}
+
+// next 返回需要发送的服务状态和已删除的服务，full 为 true 时返回全部服务
+func (c *serviceStatsCollector) next(full bool) (list []ServiceStatus, removed []string) {
+	current := make(map[string]json.RawMessage)
+	for _, st := range c.collect() {
+		data, err := json.Marshal(st)
+		if err != nil {
+			continue
+		}
+		current[st.Name] = data
+		if full || c.sent == nil || !bytes.Equal(c.sent[st.Name], data) {
+			list = append(list, st)
+		}
+	}
+
+	if !full && c.sent != nil {
+		for name := range c.sent {
+			if _, ok := current[name]; !ok {
+				removed = append(removed, name)
+			}
+		}
+		sort.Strings(removed)
+	}
+	c.sent = current
+	return
+}
+
+func (c *serviceStatsCollector) collect() []ServiceStatus {
+	now := time.Now()
+	samples := make(map[string]trafficSample)
+
+	var list []ServiceStatus
+	for name, svc := range registry.ServiceRegistry().GetAll() {
+		ss, ok := svc.(serviceStatusGetter)
+		if !ok {
+			continue
+		}
+		status := ss.Status()
+		if status == nil {
+			continue
+		}
+
+		st := ServiceStatus{
+			Name:  name,
+			State: string(status.State()),
+		}
+
+		if sts := status.Stats(); sts != nil {
+			st.CurrentConns = sts.Get(stats.KindCurrentConns)
+			st.TotalConns = sts.Get(stats.KindTotalConns)
+			st.TotalErrs = sts.Get(stats.KindTotalErrs)
+
+			if t, ok := sts.(trafficTotals); ok {
+				sample := trafficSample{time: now, stats: sts}
+				sample.input, sample.output = t.TrafficTotals()
+				samples[name] = sample
+
+				// 服务重建后统计对象可能不同，跳过本次速率
+				last, ok := c.samples[name]
+				if elapsed := now.Sub(last.time).Seconds(); ok && elapsed > 0 && last.stats == sts &&
+					sample.input >= last.input && sample.output >= last.output {
+					st.InputRate = uint64(float64(sample.input-last.input) / elapsed)
+					st.OutputRate = uint64(float64(sample.output-last.output) / elapsed)
+				}
+			}
+		}
+
+		for _, ev := range status.Events() {
+			if ev.Time.IsZero() {
+				break
+			}
+			st.LastEvent = &ServiceEvent{
+				Time: ev.Time.Unix(),
+				Msg:  ev.Message,
+			}
+		}
+
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	c.samples = samples
+	return list
+}
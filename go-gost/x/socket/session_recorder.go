@@ -0,0 +1,145 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/recorder"
+	xlogger "github.com/go-gost/x/logger"
+	xrecorder "github.com/go-gost/x/recorder"
+	"github.com/go-gost/x/registry"
+	"github.com/go-gost/x/service"
+)
+
+const (
+	// PanelRecorderName 会话记录上报到面板的记录器名称，
+	// 服务配置中引用该记录器（record: recorder.service.handler）即可开启
+	PanelRecorderName = "panel"
+	// sessionBatchSize 单次上报的最大记录数，缓冲达到该数量时立即上报
+	sessionBatchSize = 500
+	// sessionBufferSize 本地最多缓冲的记录数，超出后丢弃最早的记录
+	sessionBufferSize = 20000
+	// sessionFlushInterval 定时上报间隔
+	sessionFlushInterval = 10 * time.Second
+)
+
+// SessionRecordBatch 一批会话记录
+type SessionRecordBatch struct {
+	Records []json.RawMessage `json:"records"`
+	// Dropped 自上次成功上报以来因缓冲已满被丢弃的记录数
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// sessionRecorder 将处理器的会话记录（客户端、目标、路由、流量、耗时、错误）批量上报到面板
+//
+// 记录先进入内存缓冲，定时或攒够一批后通过 WebSocket 以 SessionRecords 上报；
+// 面板不可达时保留在缓冲中等待下次上报，缓冲满后丢弃最早的记录并在下一批中告知丢弃数量。
+type sessionRecorder struct {
+	w       *WebSocketReporter
+	records []json.RawMessage
+	dropped uint64
+	notify  chan struct{}
+	mu      sync.Mutex
+}
+
+func newSessionRecorder(w *WebSocketReporter) *sessionRecorder {
+	return &sessionRecorder{
+		w:      w,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Record 实现 recorder.Recorder，只写入缓冲，不会阻塞连接处理
+func (r *sessionRecorder) Record(ctx context.Context, b []byte, opts ...recorder.RecordOption) error {
+	if !json.Valid(b) {
+		return errors.New("invalid session record")
+	}
+
+	r.mu.Lock()
+	if len(r.records) >= sessionBufferSize {
+		r.records = r.records[1:]
+		r.dropped++
+	}
+	r.records = append(r.records, append(json.RawMessage(nil), b...))
+	full := len(r.records) >= sessionBatchSize
+	r.mu.Unlock()
+
+	if full {
+		select {
+		case r.notify <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// run 定时上报缓冲中的记录，ctx 结束时退出
+func (r *sessionRecorder) run(ctx context.Context) {
+	ticker := time.NewTicker(sessionFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.notify:
+		case <-ctx.Done():
+			return
+		}
+		r.flush(ctx)
+	}
+}
+
+// flush 按批上报直到缓冲为空或上报失败
+func (r *sessionRecorder) flush(ctx context.Context) {
+	for {
+		r.mu.Lock()
+		n := min(len(r.records), sessionBatchSize)
+		batch := SessionRecordBatch{
+			Records: r.records[:n:n],
+			Dropped: r.dropped,
+		}
+		r.mu.Unlock()
+
+		if n == 0 {
+			return
+		}
+
+		data, err := json.Marshal(batch)
+		if err != nil {
+			return
+		}
+		if _, err := r.w.Report(ctx, "SessionRecords", data); err != nil {
+			if !errors.Is(err, service.ErrReportTransportUnavailable) {
+				xlogger.Printf("⚠️ 上报会话记录失败: %v，%d 条记录等待重试\n", err, r.pending())
+			}
+			return
+		}
+
+		// 上报期间缓冲满时最早的记录可能已被丢弃，其中已上报的不算丢失，
+		// 只从缓冲中移除仍然存在的已上报记录
+		r.mu.Lock()
+		evicted := int(r.dropped - batch.Dropped)
+		sentEvicted := min(evicted, n)
+		r.records = r.records[n-sentEvicted:]
+		r.dropped -= batch.Dropped + uint64(sentEvicted)
+		r.mu.Unlock()
+	}
+}
+
+func (r *sessionRecorder) pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.records)
+}
+
+// registerSessionRecorder 将会话记录器注册为内置记录器 PanelRecorderName，重新加载配置后仍然可用
+func (w *WebSocketReporter) registerSessionRecorder() {
+	xrecorder.RegisterBuiltin(PanelRecorderName, w.sessions)
+	if !registry.RecorderRegistry().IsRegistered(PanelRecorderName) {
+		registry.RecorderRegistry().Register(PanelRecorderName, w.sessions)
+	}
+}
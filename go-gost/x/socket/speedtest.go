@@ -0,0 +1,534 @@
+package socket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/dialer"
+	"github.com/go-gost/core/listener"
+	"github.com/go-gost/core/logger"
+	xchain "github.com/go-gost/x/chain"
+	"github.com/go-gost/x/config/parsing"
+	xlogger "github.com/go-gost/x/logger"
+	mdx "github.com/go-gost/x/metadata"
+	"github.com/go-gost/x/registry"
+)
+
+const (
+	// defaultSpeedTestDuration 测速默认时长(秒)
+	defaultSpeedTestDuration = 10
+	// maxSpeedTestDuration 测速最长时长(秒)
+	maxSpeedTestDuration = 60
+	// speedTestSetupTimeout 测速服务等待客户端连接的额外时间
+	speedTestSetupTimeout = 30 * time.Second
+	// speedTestFrameSize 每帧的数据长度
+	speedTestFrameSize = 32 * 1024
+	// speedTestFrameHeader 帧头: 4 字节数据长度 + 8 字节发送时间(纳秒)，数据长度为 0 表示结束
+	speedTestFrameHeader = 12
+	// speedTestStall 两帧之间的间隔超过该值视为一次停顿，通常由丢包重传引起
+	speedTestStall = 200 * time.Millisecond
+)
+
+// 测速角色和方向
+const (
+	speedTestRoleServer = "server"
+	speedTestRoleClient = "client"
+	speedTestModePush   = "push" // 客户端发送，服务端接收
+	speedTestModePull   = "pull" // 服务端发送，客户端接收
+)
+
+// SpeedTestRequest 节点间测速请求
+//
+// 面板先向节点 A 发送 role 为 server 的请求，启动临时的接收服务并取得端口和令牌，
+// 再向节点 B 发送 role 为 client 的请求连接 A 进行测速。临时服务不写入配置，
+// 测速结束或超时后自动关闭。
+type SpeedTestRequest struct {
+	Role     string `json:"role"`
+	Duration int    `json:"duration"` // 测速时长(秒)，默认 10，最长 60
+
+	// 服务端参数
+	Port     int            `json:"port,omitempty"`     // 监听端口，为 0 时随机
+	Listener string         `json:"listener,omitempty"` // 监听器类型，默认 tcp，需与客户端的拨号器类型对应
+	Metadata map[string]any `json:"metadata,omitempty"` // 监听器或拨号器的参数
+
+	// 客户端参数
+	Addr   string `json:"addr,omitempty"`   // 测速服务地址
+	Token  string `json:"token,omitempty"`  // 测速服务返回的令牌
+	Mode   string `json:"mode,omitempty"`   // push 或 pull，默认 push
+	Dialer string `json:"dialer,omitempty"` // 拨号器类型，默认 tcp
+	Chain  string `json:"chain,omitempty"`  // 经指定转发链连接测速服务(此时服务端应使用 tcp 监听)，与 dialer 互斥
+
+	RequestId string `json:"requestId,omitempty"`
+}
+
+// SpeedTestServerResponse 测速服务信息
+type SpeedTestServerResponse struct {
+	Port      int    `json:"port"`
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expiresAt"` // 未使用时自动关闭的时间
+}
+
+// SpeedTestResult 测速结果，由接收方统计
+type SpeedTestResult struct {
+	Mode       string    `json:"mode"`
+	Bytes      int64     `json:"bytes"`
+	Duration   float64   `json:"duration"`   // 接收耗时(ms)
+	Throughput float64   `json:"throughput"` // 平均吞吐量(Mbps)
+	Intervals  []float64 `json:"intervals"`  // 每秒吞吐量(Mbps)
+	Jitter     float64   `json:"jitter"`     // 帧传输时延抖动(ms)，按 RFC 3550 计算
+	Stalls     int       `json:"stalls"`     // 停顿次数，两帧间隔超过 200ms
+	MaxGap     float64   `json:"maxGap"`     // 两帧之间的最大间隔(ms)
+	// Retransmits 发送方 TCP 套接字的重传次数，仅在发送方直接使用 TCP 连接时可用
+	Retransmits *uint32 `json:"retransmits,omitempty"`
+}
+
+// speedTestHello 客户端连接后发送的请求
+type speedTestHello struct {
+	Token    string `json:"token"`
+	Mode     string `json:"mode"`
+	Duration int    `json:"duration"`
+}
+
+// speedTestReply 服务端对请求的答复
+type speedTestReply struct {
+	Error string `json:"error,omitempty"`
+}
+
+// handleSpeedTest 处理测速命令
+func (w *WebSocketReporter) handleSpeedTest(ctx context.Context, data interface{}) (any, error) {
+	var req SpeedTestRequest
+	if err := decodeDiagnoseRequest(data, &req); err != nil {
+		return nil, err
+	}
+
+	if req.Duration <= 0 {
+		req.Duration = defaultSpeedTestDuration
+	}
+	if req.Duration > maxSpeedTestDuration {
+		return nil, fmt.Errorf("测速时长不能超过 %d 秒", maxSpeedTestDuration)
+	}
+
+	switch req.Role {
+	case speedTestRoleServer:
+		return startSpeedTestServer(req)
+	case speedTestRoleClient:
+		return runSpeedTestClient(ctx, req)
+	default:
+		return nil, fmt.Errorf("无效的测速角色 %q，应为 server 或 client", req.Role)
+	}
+}
+
+// startSpeedTestServer 启动临时的测速服务
+func startSpeedTestServer(req SpeedTestRequest) (*SpeedTestServerResponse, error) {
+	if req.Port < 0 || req.Port > 65535 {
+		return nil, errors.New("无效的端口号，范围应为0-65535")
+	}
+	if req.Listener == "" {
+		req.Listener = "tcp"
+	}
+	newListener := registry.ListenerRegistry().Get(req.Listener)
+	if newListener == nil {
+		return nil, fmt.Errorf("不支持的监听器类型: %s", req.Listener)
+	}
+
+	tlsConfig := parsing.DefaultTLSConfig()
+	if tlsConfig == nil {
+		var err error
+		if tlsConfig, err = parsing.BuildDefaultTLSConfig(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	log := logger.Default().WithFields(map[string]any{
+		"kind":     "speedtest",
+		"listener": req.Listener,
+	})
+	ln := newListener(
+		listener.AddrOption(fmt.Sprintf(":%d", req.Port)),
+		listener.TLSConfigOption(tlsConfig),
+		listener.LoggerOption(log),
+	)
+	if err := ln.Init(mdx.NewMetadata(req.Metadata)); err != nil {
+		return nil, fmt.Errorf("启动测速服务失败: %v", err)
+	}
+
+	token := make([]byte, 16)
+	rand.Read(token)
+	s := &speedTestServer{
+		ln:       ln,
+		token:    hex.EncodeToString(token),
+		duration: time.Duration(req.Duration) * time.Second,
+		log:      log,
+	}
+
+	ttl := s.duration + speedTestSetupTimeout
+	s.timer = time.AfterFunc(ttl, func() {
+		xlogger.Printf("⏹️ 测速服务 %s 超时未使用，已关闭\n", ln.Addr())
+		s.close()
+	})
+	go s.serve()
+
+	_, p, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(p)
+
+	xlogger.Printf("🚀 测速服务已启动: %s (%s)，%v 后自动关闭\n", ln.Addr(), req.Listener, ttl)
+	return &SpeedTestServerResponse{
+		Port:      port,
+		Token:     s.token,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}, nil
+}
+
+// speedTestServer 临时测速服务，完成一次测速后关闭
+type speedTestServer struct {
+	ln       listener.Listener
+	token    string
+	duration time.Duration
+	timer    *time.Timer
+	log      logger.Logger
+	once     sync.Once
+}
+
+func (s *speedTestServer) close() {
+	s.once.Do(func() {
+		s.timer.Stop()
+		s.ln.Close()
+	})
+}
+
+func (s *speedTestServer) serve() {
+	defer s.close()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+
+			done, err := s.handle(conn)
+			if err != nil {
+				s.log.Warnf("speed test from %s: %v", conn.RemoteAddr(), err)
+			}
+			if done {
+				xlogger.Printf("⏹️ 测速完成，测速服务 %s 已关闭\n", s.ln.Addr())
+				s.close()
+			}
+		}()
+	}
+}
+
+// handle 处理一个测速连接，令牌正确时返回 done 为 true
+func (s *speedTestServer) handle(conn net.Conn) (done bool, err error) {
+	conn.SetDeadline(time.Now().Add(speedTestSetupTimeout))
+
+	br := bufio.NewReader(conn)
+	var hello speedTestHello
+	if err := readSpeedTestLine(br, &hello); err != nil {
+		return false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hello.Token), []byte(s.token)) != 1 {
+		writeSpeedTestLine(conn, speedTestReply{Error: "invalid token"})
+		return false, errors.New("invalid token")
+	}
+
+	// 以服务端的时长为准，防止客户端占用过久
+	d := time.Duration(hello.Duration) * time.Second
+	if d <= 0 || d > s.duration {
+		d = s.duration
+	}
+	conn.SetDeadline(time.Now().Add(d + speedTestSetupTimeout))
+
+	switch hello.Mode {
+	case speedTestModePush:
+		if err := writeSpeedTestLine(conn, speedTestReply{}); err != nil {
+			return true, err
+		}
+		result, err := receiveSpeedTest(br)
+		if err != nil {
+			return true, err
+		}
+		result.Mode = hello.Mode
+		return true, writeSpeedTestLine(conn, result)
+
+	case speedTestModePull:
+		if err := writeSpeedTestLine(conn, speedTestReply{}); err != nil {
+			return true, err
+		}
+		retransmits, err := sendSpeedTest(conn, d)
+		if err != nil {
+			return true, err
+		}
+		// 结束帧之后附带发送方的重传次数
+		return true, writeSpeedTestLine(conn, SpeedTestResult{Retransmits: retransmits})
+
+	default:
+		writeSpeedTestLine(conn, speedTestReply{Error: "invalid mode"})
+		return false, fmt.Errorf("invalid mode %q", hello.Mode)
+	}
+}
+
+// runSpeedTestClient 连接测速服务并测速
+func runSpeedTestClient(ctx context.Context, req SpeedTestRequest) (*SpeedTestResult, error) {
+	if req.Addr == "" || req.Token == "" {
+		return nil, errors.New("缺少测速服务地址或令牌")
+	}
+	if req.Mode == "" {
+		req.Mode = speedTestModePush
+	}
+	if req.Mode != speedTestModePush && req.Mode != speedTestModePull {
+		return nil, fmt.Errorf("无效的测速方向 %q，应为 push 或 pull", req.Mode)
+	}
+	if req.Chain != "" && req.Dialer != "" {
+		return nil, errors.New("chain 和 dialer 不能同时指定")
+	}
+
+	d := time.Duration(req.Duration) * time.Second
+	xlogger.Printf("🔍 开始测速: %s，方向: %s，时长: %v\n", req.Addr, req.Mode, d)
+
+	dctx, cancel := context.WithTimeout(ctx, speedTestSetupTimeout)
+	conn, err := dialSpeedTest(dctx, req)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("连接测速服务失败: %v", err)
+	}
+	defer conn.Close()
+
+	// 取消时中断测速
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	conn.SetDeadline(time.Now().Add(d + speedTestSetupTimeout))
+
+	if err := writeSpeedTestLine(conn, speedTestHello{
+		Token:    req.Token,
+		Mode:     req.Mode,
+		Duration: req.Duration,
+	}); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	var reply speedTestReply
+	if err := readSpeedTestLine(br, &reply); err != nil {
+		return nil, fmt.Errorf("测速服务未响应: %v", err)
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("测速服务拒绝: %s", reply.Error)
+	}
+
+	var result *SpeedTestResult
+	switch req.Mode {
+	case speedTestModePush:
+		retransmits, err := sendSpeedTest(conn, d)
+		if err != nil {
+			return nil, err
+		}
+		result = &SpeedTestResult{}
+		if err := readSpeedTestLine(br, result); err != nil {
+			return nil, fmt.Errorf("读取测速结果失败: %v", err)
+		}
+		result.Retransmits = retransmits
+
+	case speedTestModePull:
+		if result, err = receiveSpeedTest(br); err != nil {
+			return nil, err
+		}
+		var sender SpeedTestResult
+		if err := readSpeedTestLine(br, &sender); err == nil {
+			result.Retransmits = sender.Retransmits
+		}
+	}
+	result.Mode = req.Mode
+
+	xlogger.Printf("✅ 测速完成: %.2f Mbps，抖动 %.2fms，停顿 %d 次\n", result.Throughput, result.Jitter, result.Stalls)
+	return result, nil
+}
+
+// dialSpeedTest 按指定的转发链或拨号器连接测速服务
+func dialSpeedTest(ctx context.Context, req SpeedTestRequest) (net.Conn, error) {
+	if req.Chain != "" {
+		dial, err := diagnoseDialer(req.Chain)
+		if err != nil {
+			return nil, err
+		}
+		return dial(ctx, "tcp", req.Addr)
+	}
+
+	if req.Dialer == "" {
+		req.Dialer = "tcp"
+	}
+	newDialer := registry.DialerRegistry().Get(req.Dialer)
+	if newDialer == nil {
+		return nil, fmt.Errorf("不支持的拨号器类型: %s", req.Dialer)
+	}
+
+	host, _, _ := net.SplitHostPort(req.Addr)
+	d := newDialer(
+		// 测速服务使用自签名证书
+		dialer.TLSConfigOption(&tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: true,
+		}),
+		dialer.LoggerOption(logger.Default().WithFields(map[string]any{
+			"kind":   "speedtest",
+			"dialer": req.Dialer,
+		})),
+	)
+	if err := d.Init(mdx.NewMetadata(req.Metadata)); err != nil {
+		return nil, err
+	}
+
+	tr := xchain.NewTransport(d, nil, chain.AddrTransportOption(req.Addr))
+	conn, err := tr.Dial(ctx, req.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := tr.Handshake(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return cc, nil
+}
+
+// sendSpeedTest 在 d 时间内持续发送数据帧，最后发送结束帧，返回期间的 TCP 重传次数
+func sendSpeedTest(conn net.Conn, d time.Duration) (*uint32, error) {
+	retransBefore, ok := tcpRetransmits(conn)
+
+	frame := make([]byte, speedTestFrameHeader+speedTestFrameSize)
+	// 随机数据，避免被传输层压缩
+	rand.Read(frame[speedTestFrameHeader:])
+	binary.BigEndian.PutUint32(frame, speedTestFrameSize)
+
+	deadline := time.Now().Add(d)
+	for now := time.Now(); now.Before(deadline); now = time.Now() {
+		binary.BigEndian.PutUint64(frame[4:], uint64(now.UnixNano()))
+		if _, err := conn.Write(frame); err != nil {
+			return nil, err
+		}
+	}
+
+	end := make([]byte, speedTestFrameHeader)
+	if _, err := conn.Write(end); err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, nil
+	}
+	retransAfter, ok := tcpRetransmits(conn)
+	if !ok {
+		return nil, nil
+	}
+	n := retransAfter - retransBefore
+	return &n, nil
+}
+
+// receiveSpeedTest 接收数据帧直到结束帧，统计吞吐量、抖动和停顿
+func receiveSpeedTest(r io.Reader) (*SpeedTestResult, error) {
+	result := &SpeedTestResult{}
+	header := make([]byte, speedTestFrameHeader)
+	buf := make([]byte, speedTestFrameSize)
+
+	var start, last time.Time
+	var lastTransit, jitter float64
+	var intervalBytes int64
+	var intervalStart time.Time
+	var maxGap time.Duration
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		n := int(binary.BigEndian.Uint32(header))
+		if n == 0 {
+			break
+		}
+		if n > len(buf) {
+			buf = make([]byte, n)
+		}
+		if _, err := io.ReadFull(r, buf[:n]); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		sent := time.Unix(0, int64(binary.BigEndian.Uint64(header[4:])))
+		// 两端时钟的偏差在差值中抵消
+		transit := float64(now.Sub(sent)) / float64(time.Millisecond)
+
+		if start.IsZero() {
+			start, intervalStart = now, now
+		} else {
+			if gap := now.Sub(last); gap > maxGap {
+				maxGap = gap
+			}
+			if now.Sub(last) > speedTestStall {
+				result.Stalls++
+			}
+			jitter += (math.Abs(transit-lastTransit) - jitter) / 16
+		}
+		last, lastTransit = now, transit
+
+		size := int64(speedTestFrameHeader + n)
+		result.Bytes += size
+		for now.Sub(intervalStart) >= time.Second {
+			result.Intervals = append(result.Intervals, mbps(intervalBytes, time.Second))
+			intervalBytes = 0
+			intervalStart = intervalStart.Add(time.Second)
+		}
+		intervalBytes += size
+	}
+
+	if !start.IsZero() {
+		elapsed := last.Sub(start)
+		// 不足半秒的尾部样本偏差较大，只计入总量
+		if rest := last.Sub(intervalStart); rest >= time.Second/2 && intervalBytes > 0 {
+			result.Intervals = append(result.Intervals, mbps(intervalBytes, rest))
+		}
+		result.Duration = milliseconds(elapsed)
+		result.Throughput = mbps(result.Bytes, elapsed)
+	}
+	result.Jitter = math.Round(jitter*1000) / 1000
+	result.MaxGap = milliseconds(maxGap)
+	return result, nil
+}
+
+func mbps(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return math.Round(float64(n)*8/d.Seconds()/1e6*100) / 100
+}
+
+func writeSpeedTestLine(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func readSpeedTestLine(br *bufio.Reader, v any) error {
+	line, err := br.ReadSlice('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, v)
+}
@@ -0,0 +1,45 @@
+//go:build linux
+
+package socket
+
+import (
+	"net"
+	"syscall"
+
+	xnet "github.com/go-gost/x/internal/net"
+	"golang.org/x/sys/unix"
+)
+
+// tcpRetransmits 返回连接底层 TCP 套接字累计的重传次数
+func tcpRetransmits(conn net.Conn) (uint32, bool) {
+	var v any = conn
+	for {
+		switch c := v.(type) {
+		case interface{ NetConn() net.Conn }:
+			v = c.NetConn()
+			continue
+		case xnet.Splicer:
+			v = c.Unwrap()
+			continue
+		}
+		break
+	}
+
+	sc, ok := v.(syscall.Conn)
+	if !ok {
+		return 0, false
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+
+	var info *unix.TCPInfo
+	rc.Control(func(fd uintptr) {
+		info, err = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	})
+	if err != nil || info == nil {
+		return 0, false
+	}
+	return info.Total_retrans, true
+}
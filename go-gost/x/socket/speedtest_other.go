@@ -0,0 +1,10 @@
+//go:build !linux
+
+package socket
+
+import "net"
+
+// tcpRetransmits 仅在 Linux 上支持
+func tcpRetransmits(conn net.Conn) (uint32, bool) {
+	return 0, false
+}
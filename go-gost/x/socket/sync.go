@@ -0,0 +1,246 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-gost/x/config"
+	xlogger "github.com/go-gost/x/logger"
+	"github.com/go-gost/x/registry"
+)
+
+// syncStateRequest 面板期望的完整节点状态
+type syncStateRequest struct {
+	Services []config.ServiceConfig `json:"services"`
+	Chains   []config.ChainConfig   `json:"chains"`
+	Limiters []config.LimiterConfig `json:"limiters"`
+	// DryRun 只计算差异，不做任何变更
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// ResourceDiff 单类资源的差异
+type ResourceDiff struct {
+	Created   []string `json:"created,omitempty"`
+	Updated   []string `json:"updated,omitempty"`
+	Deleted   []string `json:"deleted,omitempty"`
+	Unchanged int      `json:"unchanged"`
+}
+
+func (d *ResourceDiff) empty() bool {
+	return len(d.Created) == 0 && len(d.Updated) == 0 && len(d.Deleted) == 0
+}
+
+// SyncStateResponse 对账结果
+type SyncStateResponse struct {
+	Services ResourceDiff `json:"services"`
+	Chains   ResourceDiff `json:"chains"`
+	Limiters ResourceDiff `json:"limiters"`
+	Applied  bool         `json:"applied"`
+	Hash     string       `json:"hash,omitempty"`
+}
+
+func (w *WebSocketReporter) handleSyncState(data interface{}) (SyncStateResponse, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return SyncStateResponse{}, fmt.Errorf("序列化数据失败: %v", err)
+	}
+
+	// 预处理：将字符串格式的 duration 转换为纳秒数
+	processedData, err := w.preprocessDurationFields(jsonData)
+	if err != nil {
+		return SyncStateResponse{}, fmt.Errorf("预处理duration字段失败: %v", err)
+	}
+
+	var req syncStateRequest
+	if err := json.Unmarshal(processedData, &req); err != nil {
+		return SyncStateResponse{}, fmt.Errorf("解析同步请求失败: %v", err)
+	}
+
+	return syncState(req)
+}
+
+// syncState 将节点状态对齐到面板期望的状态
+//
+// 差异同时考虑全局配置和注册表：配置中存在但未注册的资源会被重新创建，
+// 已注册但不在期望状态中的资源会被删除。变更在一个事务中完成，
+// 任一步失败时全部回滚，节点保持原状。
+func syncState(req syncStateRequest) (SyncStateResponse, error) {
+	services := pointers(req.Services)
+	chains := pointers(req.Chains)
+	limiters := pointers(req.Limiters)
+
+	for _, list := range [][]string{
+		names(services, func(s *config.ServiceConfig) string { return s.Name }),
+		names(chains, func(c *config.ChainConfig) string { return c.Name }),
+		names(limiters, func(l *config.LimiterConfig) string { return l.Name }),
+	} {
+		if err := checkNames(list); err != nil {
+			return SyncStateResponse{}, err
+		}
+	}
+
+	t := beginTxn()
+
+	var resp SyncStateResponse
+	svcCreate, svcUpdate, svcDelete := diffResources(services, t.cfg.Services,
+		registry.ServiceRegistry().GetAll(), func(s *config.ServiceConfig) string { return s.Name }, &resp.Services)
+	chainCreate, chainUpdate, chainDelete := diffResources(chains, t.cfg.Chains,
+		registry.ChainRegistry().GetAll(), func(c *config.ChainConfig) string { return c.Name }, &resp.Chains)
+	limCreate, limUpdate, limDelete := diffResources(limiters, t.cfg.Limiters,
+		registry.TrafficLimiterRegistry().GetAll(), func(l *config.LimiterConfig) string { return l.Name }, &resp.Limiters)
+
+	if req.DryRun || (resp.Services.empty() && resp.Chains.empty() && resp.Limiters.empty()) {
+		t.rollback()
+		resp.Hash, _ = StateHash()
+		return resp, nil
+	}
+
+	// 先删除服务，再处理被服务引用的限速器和链，最后创建和更新服务
+	err := func() error {
+		for _, name := range svcDelete {
+			if err := t.deleteService(name); err != nil {
+				return err
+			}
+		}
+		for _, name := range chainDelete {
+			if err := t.deleteChain(name); err != nil {
+				return err
+			}
+		}
+		for _, name := range limDelete {
+			if err := t.deleteLimiter(name); err != nil {
+				return err
+			}
+		}
+		for _, lc := range append(limCreate, limUpdate...) {
+			if err := t.putLimiter(lc); err != nil {
+				return err
+			}
+		}
+		for _, cc := range append(chainCreate, chainUpdate...) {
+			if err := t.putChain(cc); err != nil {
+				return err
+			}
+		}
+		for _, sc := range append(svcCreate, svcUpdate...) {
+			if err := t.putService(sc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		if rerr := t.rollback(); rerr != nil {
+			return resp, fmt.Errorf("同步失败: %v，回滚未完全成功: %v", err, rerr)
+		}
+		return resp, fmt.Errorf("同步失败，已回滚: %v", err)
+	}
+
+	if err := t.commit(); err != nil {
+		return resp, fmt.Errorf("更新配置失败: %v", err)
+	}
+
+	resp.Applied = true
+	resp.Hash, _ = StateHash()
+	xlogger.Printf("🔄 状态同步完成: 服务 +%d ~%d -%d, 链 +%d ~%d -%d, 限速器 +%d ~%d -%d\n",
+		len(svcCreate), len(svcUpdate), len(svcDelete),
+		len(chainCreate), len(chainUpdate), len(chainDelete),
+		len(limCreate), len(limUpdate), len(limDelete))
+	return resp, nil
+}
+
+// diffResources 计算期望状态与当前配置、注册表之间的差异
+func diffResources[T any, R any](desired, current []T, registered map[string]R, name func(T) string, diff *ResourceDiff) (create, update []T, del []string) {
+	cur := make(map[string]T, len(current))
+	for _, v := range current {
+		cur[name(v)] = v
+	}
+
+	want := make(map[string]bool, len(desired))
+	for _, v := range desired {
+		n := name(v)
+		want[n] = true
+
+		old, inConfig := cur[n]
+		_, inRegistry := registered[n]
+		switch {
+		case !inConfig && !inRegistry:
+			create = append(create, v)
+			diff.Created = append(diff.Created, n)
+		case !inConfig || !inRegistry || !sameConfig(old, v):
+			update = append(update, v)
+			diff.Updated = append(diff.Updated, n)
+		default:
+			diff.Unchanged++
+		}
+	}
+
+	seen := make(map[string]bool)
+	for n := range cur {
+		if !want[n] {
+			seen[n] = true
+		}
+	}
+	for n := range registered {
+		if !want[n] {
+			seen[n] = true
+		}
+	}
+	for n := range seen {
+		del = append(del, n)
+	}
+	sort.Strings(del)
+	diff.Deleted = del
+
+	return
+}
+
+// sameConfig 通过 JSON 比较两份配置，忽略指针和字段顺序带来的差异
+func sameConfig(a, b any) bool {
+	ja, err1 := json.Marshal(a)
+	jb, err2 := json.Marshal(b)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	var va, vb any
+	if json.Unmarshal(ja, &va) != nil || json.Unmarshal(jb, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func pointers[T any](list []T) []*T {
+	out := make([]*T, 0, len(list))
+	for i := range list {
+		out = append(out, &list[i])
+	}
+	return out
+}
+
+func names[T any](list []T, name func(T) string) []string {
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		out = append(out, name(v))
+	}
+	return out
+}
+
+// checkNames 校验资源名非空且不重复
+func checkNames(list []string) error {
+	seen := make(map[string]bool, len(list))
+	for _, n := range list {
+		if strings.TrimSpace(n) == "" || n != strings.TrimSpace(n) {
+			return errors.New("资源名称不能为空或包含首尾空白")
+		}
+		if seen[n] {
+			return fmt.Errorf("资源 %s 重复", n)
+		}
+		seen[n] = true
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package socket
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/load"
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+const (
+	// telemetryFullInterval 完整遥测的发送间隔，其余心跳只携带变化的字段
+	telemetryFullInterval = 5 * time.Minute
+	// telemetryTCPStatesInterval TCP 状态的采样间隔，需要遍历全部套接字，不随每次心跳采集
+	telemetryTCPStatesInterval = 30 * time.Second
+)
+
+// NodeTelemetry 节点遥测信息，随心跳上报
+//
+// 心跳中的 telemetry 只包含与上次发送相比发生变化的字段，
+// full 为 true 时为完整快照，面板应以其替换而不是合并已有的数据。
+// 无法获取的字段(如未加载 conntrack 模块)不上报；此前上报过、现在无法获取的字段
+// 在差异中为 null，面板应删除该字段。
+type NodeTelemetry struct {
+	Interfaces []InterfaceStats `json:"interfaces,omitempty"`  // 各网卡流量及速率
+	Load       *LoadAverage     `json:"load,omitempty"`        // 系统负载
+	CPUCores   []float64        `json:"cpu_cores,omitempty"`   // 各核心使用率（百分比）
+	FDs        *FDUsage         `json:"fds,omitempty"`         // 本进程文件描述符
+	TCPStates  map[string]int   `json:"tcp_states,omitempty"`  // 各状态的TCP套接字数量
+	Conntrack  *ConntrackUsage  `json:"conntrack,omitempty"`   // 连接跟踪表使用情况
+	Goroutines int              `json:"goroutines"`            // 协程数量
+	Version    string           `json:"version,omitempty"`     // 节点版本
+	ConfigHash string           `json:"config_hash,omitempty"` // 当前配置校验和
+}
+
+// InterfaceStats 网卡流量统计
+type InterfaceStats struct {
+	Name             string `json:"name"`
+	BytesReceived    uint64 `json:"bytes_received"`    // 接收字节数
+	BytesTransmitted uint64 `json:"bytes_transmitted"` // 发送字节数
+	RxRate           uint64 `json:"rx_rate"`           // 接收速率（字节/秒）
+	TxRate           uint64 `json:"tx_rate"`           // 发送速率（字节/秒）
+}
+
+// LoadAverage 系统负载
+type LoadAverage struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
+// FDUsage 文件描述符使用情况
+type FDUsage struct {
+	Open  uint64 `json:"open"`  // 已打开数量
+	Limit uint64 `json:"limit"` // 软限制
+}
+
+// ConntrackUsage 连接跟踪表使用情况
+type ConntrackUsage struct {
+	Count int64 `json:"count"`
+	Max   int64 `json:"max"`
+}
+
+// telemetryCollector 采集节点遥测并计算与上次发送的差异，只在心跳循环中使用
+type telemetryCollector struct {
+	version  string
+	lastIO   map[string]psnet.IOCountersStat
+	lastTime time.Time
+	sent     map[string]json.RawMessage // 面板已知的各字段取值
+	fullAt   time.Time                  // 上次发送完整快照的时间
+	tcp      map[string]int             // 最近一次采样的 TCP 状态
+	tcpAt    time.Time                  // 上次采样 TCP 状态的时间
+}
+
+func newTelemetryCollector(version string) *telemetryCollector {
+	return &telemetryCollector{
+		version: version,
+	}
+}
+
+// reset 清空已发送的状态，下次心跳发送完整快照，用于重新连接后
+func (c *telemetryCollector) reset() {
+	c.sent = nil
+}
+
+// next 采集遥测并返回需要发送的字段，full 表示返回的是完整快照
+func (c *telemetryCollector) next() (fields map[string]json.RawMessage, full bool) {
+	data, err := json.Marshal(c.collect())
+	if err != nil {
+		return nil, false
+	}
+	var current map[string]json.RawMessage
+	if err := json.Unmarshal(data, &current); err != nil {
+		return nil, false
+	}
+
+	now := time.Now()
+	if c.sent == nil || now.Sub(c.fullAt) >= telemetryFullInterval {
+		c.sent = current
+		c.fullAt = now
+		return current, true
+	}
+
+	fields = make(map[string]json.RawMessage)
+	for k, v := range current {
+		if !bytes.Equal(c.sent[k], v) {
+			fields[k] = v
+		}
+	}
+	for k := range c.sent {
+		if _, ok := current[k]; !ok {
+			fields[k] = json.RawMessage("null")
+		}
+	}
+	c.sent = current
+	return fields, false
+}
+
+func (c *telemetryCollector) collect() NodeTelemetry {
+	t := NodeTelemetry{
+		Interfaces: c.interfaces(),
+		CPUCores:   cpuCores(),
+		FDs:        fdUsage(),
+		TCPStates:  c.tcpStates(),
+		Goroutines: runtime.NumGoroutine(),
+		Version:    c.version,
+	}
+
+	if avg, err := load.Avg(); err == nil {
+		t.Load = &LoadAverage{
+			Load1:  round(avg.Load1, 2),
+			Load5:  round(avg.Load5, 2),
+			Load15: round(avg.Load15, 2),
+		}
+	}
+
+	if stats, err := psnet.FilterCounters(); err == nil && len(stats) > 0 && stats[0].ConnTrackMax > 0 {
+		t.Conntrack = &ConntrackUsage{
+			Count: stats[0].ConnTrackCount,
+			Max:   stats[0].ConnTrackMax,
+		}
+	}
+
+	if hash, err := cachedStateHash(); err == nil {
+		t.ConfigHash = hash
+	}
+
+	return t
+}
+
+// tcpStates 按 telemetryTCPStatesInterval 采样 TCP 状态，间隔内返回上次的结果
+func (c *telemetryCollector) tcpStates() map[string]int {
+	if now := time.Now(); c.tcpAt.IsZero() || now.Sub(c.tcpAt) >= telemetryTCPStatesInterval {
+		c.tcp = tcpStates()
+		c.tcpAt = now
+	}
+	return c.tcp
+}
+
+// interfaces 返回非回环网卡的流量，速率按与上次采集的差值计算
+func (c *telemetryCollector) interfaces() []InterfaceStats {
+	counters, err := psnet.IOCounters(true)
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(c.lastTime).Seconds()
+	current := make(map[string]psnet.IOCountersStat, len(counters))
+
+	var list []InterfaceStats
+	for _, io := range counters {
+		if strings.HasPrefix(io.Name, "lo") {
+			continue
+		}
+		current[io.Name] = io
+
+		stats := InterfaceStats{
+			Name:             io.Name,
+			BytesReceived:    io.BytesRecv,
+			BytesTransmitted: io.BytesSent,
+		}
+		// 计数器回绕或网卡重建时跳过本次速率
+		if last, ok := c.lastIO[io.Name]; ok && elapsed > 0 &&
+			io.BytesRecv >= last.BytesRecv && io.BytesSent >= last.BytesSent {
+			stats.RxRate = uint64(float64(io.BytesRecv-last.BytesRecv) / elapsed)
+			stats.TxRate = uint64(float64(io.BytesSent-last.BytesSent) / elapsed)
+		}
+		list = append(list, stats)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	c.lastIO = current
+	c.lastTime = now
+	return list
+}
+
+// cpuCores 返回自上次调用以来各核心的使用率，首次调用返回空
+func cpuCores() []float64 {
+	percentages, err := cpu.Percent(0, true)
+	if err != nil {
+		return nil
+	}
+	for i, v := range percentages {
+		percentages[i] = round(v, 1)
+	}
+	return percentages
+}
+
+// round 按精度取整，避免微小波动导致字段被视为变化
+func round(v float64, precision int) float64 {
+	p := math.Pow10(precision)
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package socket
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// tcpStateNames /proc/net/tcp 中的状态编码
+var tcpStateNames = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// tcpStates 统计本机 IPv4 和 IPv6 TCP 套接字的状态
+func tcpStates() map[string]int {
+	var states map[string]int
+	for _, name := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(f)
+		scanner.Scan() // 跳过表头
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 4 {
+				continue
+			}
+			state, ok := tcpStateNames[fields[3]]
+			if !ok {
+				continue
+			}
+			if states == nil {
+				states = make(map[string]int)
+			}
+			states[state]++
+		}
+		f.Close()
+	}
+	return states
+}
+
+// fdUsage 返回本进程打开的文件描述符数量及其软限制
+func fdUsage() *FDUsage {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return nil
+	}
+
+	usage := &FDUsage{
+		Open: uint64(len(entries)),
+	}
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err == nil {
+		usage.Limit = rlimit.Cur
+	}
+	return usage
+}
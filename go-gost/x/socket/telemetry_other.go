@@ -0,0 +1,13 @@
+//go:build !linux
+
+package socket
+
+// tcpStates 仅在 Linux 上统计
+func tcpStates() map[string]int {
+	return nil
+}
+
+// fdUsage 仅在 Linux 上统计
+func fdUsage() *FDUsage {
+	return nil
+}
@@ -0,0 +1,98 @@
+package socket
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-gost/x/config"
+	tls_util "github.com/go-gost/x/internal/util/tls"
+)
+
+// PanelTLSOptions 面板连接（WebSocket 与 HTTP 上报）的 TLS 选项
+type PanelTLSOptions struct {
+	// CAFile 自定义 CA 证书文件，为空时使用系统根证书
+	CAFile string
+	// CertFile/KeyFile 客户端证书，面板要求双向认证时使用
+	CertFile string
+	KeyFile  string
+	// ServerName 覆盖用于证书校验的主机名
+	ServerName string
+	// Pin 面板证书公钥（SPKI）的 SHA-256 指纹，hex 或 base64 编码，
+	// 多个指纹用逗号分隔以便轮换证书；设置后服务端证书必须匹配其中之一
+	Pin string
+	// Insecure 跳过证书链校验，只应与 Pin 配合用于自签证书
+	Insecure bool
+}
+
+// NewPanelTLSConfig 根据选项创建面板连接使用的 TLS 配置
+func NewPanelTLSConfig(opts PanelTLSOptions) (*tls.Config, error) {
+	pins, err := parsePins(opts.Pin)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Insecure && len(pins) == 0 {
+		return nil, errors.New("跳过证书校验时必须设置公钥指纹 pin")
+	}
+
+	cfg, err := tls_util.LoadClientConfig(&config.TLSConfig{
+		CAFile:     opts.CAFile,
+		CertFile:   opts.CertFile,
+		KeyFile:    opts.KeyFile,
+		ServerName: opts.ServerName,
+		Secure:     !opts.Insecure,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("加载面板 TLS 配置失败: %v", err)
+	}
+
+	if len(pins) > 0 {
+		verify := cfg.VerifyConnection
+		cfg.VerifyConnection = func(state tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(state); err != nil {
+					return err
+				}
+			}
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("面板未提供证书")
+			}
+			sum := sha256.Sum256(state.PeerCertificates[0].RawSubjectPublicKeyInfo)
+			for _, pin := range pins {
+				if subtle.ConstantTimeCompare(sum[:], pin) == 1 {
+					return nil
+				}
+			}
+			return fmt.Errorf("面板证书公钥指纹不匹配: %s", base64.StdEncoding.EncodeToString(sum[:]))
+		}
+	}
+
+	return cfg, nil
+}
+
+// parsePins 解析公钥指纹列表，支持 hex、base64 以及 "sha256/" 前缀写法
+func parsePins(s string) ([][]byte, error) {
+	var pins [][]byte
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "sha256/")
+		if v == "" {
+			continue
+		}
+
+		var pin []byte
+		if b, err := hex.DecodeString(strings.ReplaceAll(v, ":", "")); err == nil && len(b) == sha256.Size {
+			pin = b
+		} else if b, err := base64.StdEncoding.DecodeString(v); err == nil && len(b) == sha256.Size {
+			pin = b
+		} else {
+			return nil, fmt.Errorf("无效的公钥指纹: %s", v)
+		}
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
@@ -0,0 +1,217 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-gost/x/config"
+	xlogger "github.com/go-gost/x/logger"
+	"github.com/go-gost/x/registry"
+)
+
+// 事务操作类型
+const (
+	txnOpAdd    = "add"
+	txnOpUpdate = "update"
+	txnOpPut    = "put"
+	txnOpDelete = "delete"
+	txnOpPause  = "pause"
+	txnOpResume = "resume"
+)
+
+// txnKindService 服务，其余资源类型见 resourceKinds
+const txnKindService = "service"
+
+// transactionOp 事务中的一个操作
+type transactionOp struct {
+	// Op add/update/put/delete，服务另外支持 pause/resume
+	Op string `json:"op"`
+	// Kind service 或 resourceKinds 中的资源类型（chain、hop、limiter、bypass 等）
+	Kind string `json:"kind"`
+	// Name 资源名称，add/update/put 时可省略，取 Data 中的 name
+	Name string `json:"name,omitempty"`
+	// Data 资源配置，格式与 AddService 或 Apply 中的单个元素相同
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+type transactionRequest struct {
+	Operations []transactionOp `json:"operations"`
+}
+
+// TransactionResult 单个操作的执行结果
+type TransactionResult struct {
+	Index int    `json:"index"`
+	Op    string `json:"op"`
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// TransactionResponse 事务执行结果
+type TransactionResponse struct {
+	Committed bool                `json:"committed"`
+	Results   []TransactionResult `json:"results"`
+	Hash      string              `json:"hash,omitempty"`
+}
+
+func (w *WebSocketReporter) handleTransaction(data interface{}) (TransactionResponse, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return TransactionResponse{}, fmt.Errorf("序列化数据失败: %v", err)
+	}
+
+	// 预处理：将字符串格式的 duration 转换为纳秒数
+	processedData, err := w.preprocessDurationFields(jsonData)
+	if err != nil {
+		return TransactionResponse{}, fmt.Errorf("预处理duration字段失败: %v", err)
+	}
+
+	var req transactionRequest
+	if err := json.Unmarshal(processedData, &req); err != nil {
+		return TransactionResponse{}, fmt.Errorf("解析事务请求失败: %v", err)
+	}
+
+	return runTransaction(req)
+}
+
+// runTransaction 按顺序执行事务中的所有操作
+//
+// 操作依次作用于注册表和暂存配置，后面的操作可以引用前面刚创建的资源
+// （例如先建链和限速器，再建引用它们的服务）。全部成功后一次性提交配置并持久化；
+// 任一操作失败时撤销已执行的操作，节点、全局配置和 gost.json 都保持事务前的状态。
+func runTransaction(req transactionRequest) (TransactionResponse, error) {
+	if len(req.Operations) == 0 {
+		return TransactionResponse{}, errors.New("operations list cannot be empty")
+	}
+
+	var resp TransactionResponse
+	t := beginTxn()
+
+	for i, op := range req.Operations {
+		name, err := t.exec(op)
+		result := TransactionResult{Index: i, Op: op.Op, Kind: op.Kind, Name: name}
+		if err != nil {
+			result.Error = err.Error()
+			resp.Results = append(resp.Results, result)
+
+			if rerr := t.rollback(); rerr != nil {
+				return resp, fmt.Errorf("操作 %d 失败: %v，回滚未完全成功: %v", i, err, rerr)
+			}
+			return resp, fmt.Errorf("操作 %d 失败，事务已回滚: %v", i, err)
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	if err := t.commit(); err != nil {
+		return resp, fmt.Errorf("提交事务失败，已回滚: %v", err)
+	}
+
+	resp.Committed = true
+	resp.Hash, _ = StateHash()
+	xlogger.Printf("✅ 事务提交成功，共 %d 个操作\n", len(req.Operations))
+	return resp, nil
+}
+
+// exec 执行单个操作，返回操作的资源名称
+func (t *txn) exec(op transactionOp) (string, error) {
+	name := strings.TrimSpace(op.Name)
+	op.Kind = strings.ToLower(strings.TrimSpace(op.Kind))
+
+	switch op.Kind {
+	case txnKindService:
+		switch op.Op {
+		case txnOpDelete:
+			return name, t.deleteService(name)
+		case txnOpPause, txnOpResume:
+			return name, t.setPaused(name, op.Op == txnOpPause)
+		}
+		var sc config.ServiceConfig
+		if err := decodeOpData(op, &sc, &sc.Name, &name); err != nil {
+			return name, err
+		}
+		if err := checkExists(op.Op, name, t.service(name) != nil || registry.ServiceRegistry().IsRegistered(name)); err != nil {
+			return name, err
+		}
+		return name, t.putService(&sc)
+
+	default:
+		kind, ok := resourceKinds[op.Kind]
+		if !ok {
+			return name, fmt.Errorf("未知资源类型: %s", op.Kind)
+		}
+		if op.Op == txnOpDelete {
+			return name, kind.remove(t, name)
+		}
+		return kind.stage(t, op)
+	}
+}
+
+// checkExists 校验 add/update/put 的前置条件
+func checkExists(op, name string, exists bool) error {
+	switch op {
+	case txnOpAdd:
+		if exists {
+			return fmt.Errorf("%s 已存在", name)
+		}
+	case txnOpUpdate:
+		if !exists {
+			return fmt.Errorf("%s 不存在", name)
+		}
+	case txnOpPut:
+	default:
+		return fmt.Errorf("未知操作: %s", op)
+	}
+	return nil
+}
+
+// setPaused 在暂存配置中设置服务的暂停状态并重建服务
+func (t *txn) setPaused(name string, paused bool) error {
+	old := t.service(name)
+	if old == nil {
+		return fmt.Errorf("服务 %s 不存在", name)
+	}
+	if isPaused(old) == paused {
+		return nil
+	}
+
+	sc := *old
+	sc.Metadata = make(map[string]any, len(old.Metadata)+1)
+	for k, v := range old.Metadata {
+		sc.Metadata[k] = v
+	}
+	if paused {
+		sc.Metadata["paused"] = true
+	} else {
+		delete(sc.Metadata, "paused")
+		if len(sc.Metadata) == 0 {
+			sc.Metadata = nil
+		}
+	}
+	return t.putService(&sc)
+}
+
+// decodeOpData 解析操作携带的配置，并统一操作名称与配置中的名称
+func decodeOpData(op transactionOp, v any, cfgName *string, name *string) error {
+	if len(op.Data) == 0 {
+		return errors.New("缺少资源配置 data")
+	}
+	if err := json.Unmarshal(op.Data, v); err != nil {
+		return fmt.Errorf("解析资源配置失败: %v", err)
+	}
+
+	*cfgName = strings.TrimSpace(*cfgName)
+	switch {
+	case *name == "":
+		*name = *cfgName
+	case *cfgName == "":
+		*cfgName = *name
+	case *cfgName != *name:
+		return fmt.Errorf("操作名称 %s 与配置名称 %s 不一致", *name, *cfgName)
+	}
+	if *name == "" {
+		return errors.New("资源名称不能为空")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package socket
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/config"
+	xlogger "github.com/go-gost/x/logger"
+	"github.com/go-gost/x/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useStateDir runs the test in a temporary directory with an empty global config,
+// so committed transactions do not write gost.json into the package directory.
+func useStateDir(t *testing.T) {
+	if logger.Default() == nil {
+		logger.SetDefault(xlogger.NewLogger())
+	}
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+
+	prev := config.Global()
+	config.Set(&config.Config{})
+	t.Cleanup(func() {
+		config.Set(prev)
+		os.Chdir(wd)
+	})
+}
+
+func txnOp(op, kind, name string, data any) transactionOp {
+	o := transactionOp{Op: op, Kind: kind, Name: name}
+	if data != nil {
+		o.Data, _ = json.Marshal(data)
+	}
+	return o
+}
+
+func TestRunTransaction(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		ops        []transactionOp
+		committed  bool
+		hops       []string
+		bypasses   []string
+		registered map[string]bool
+	}{
+		{
+			desc: "resources of different kinds",
+			ops: []transactionOp{
+				txnOp(txnOpAdd, "hop", "", map[string]any{"name": "txn-hop"}),
+				txnOp(txnOpAdd, "Bypass", "", map[string]any{"name": "txn-bypass", "matchers": []string{"example.com"}}),
+				txnOp(txnOpAdd, "chain", "", map[string]any{"name": "txn-chain", "hops": []map[string]any{{"name": "txn-hop"}}}),
+			},
+			committed:  true,
+			hops:       []string{"txn-hop"},
+			bypasses:   []string{"txn-bypass"},
+			registered: map[string]bool{"hop": true, "bypass": true, "chain": true},
+		},
+		{
+			desc: "failed operation rolls back the earlier ones",
+			ops: []transactionOp{
+				txnOp(txnOpAdd, "hop", "", map[string]any{"name": "txn-hop"}),
+				txnOp(txnOpAdd, "bypass", "", map[string]any{"name": "txn-bypass"}),
+				txnOp(txnOpUpdate, "admission", "", map[string]any{"name": "txn-missing"}),
+			},
+			registered: map[string]bool{"hop": false, "bypass": false, "chain": false},
+		},
+		{
+			desc: "unknown kind",
+			ops: []transactionOp{
+				txnOp(txnOpAdd, "hop", "", map[string]any{"name": "txn-hop"}),
+				txnOp(txnOpAdd, "unknown", "", map[string]any{"name": "txn-unknown"}),
+			},
+			registered: map[string]bool{"hop": false},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			useStateDir(t)
+			t.Cleanup(func() {
+				registry.ChainRegistry().Unregister("txn-chain")
+				registry.HopRegistry().Unregister("txn-hop")
+				registry.BypassRegistry().Unregister("txn-bypass")
+			})
+
+			resp, err := runTransaction(transactionRequest{Operations: test.ops})
+			assert.Equal(t, test.committed, resp.Committed)
+			if test.committed {
+				require.NoError(t, err)
+				assert.NotEmpty(t, resp.Hash)
+			} else {
+				assert.Error(t, err)
+			}
+
+			cfg := config.Global()
+			assert.ElementsMatch(t, test.hops, names(cfg.Hops, func(c *config.HopConfig) string { return c.Name }))
+			assert.ElementsMatch(t, test.bypasses, names(cfg.Bypasses, func(c *config.BypassConfig) string { return c.Name }))
+
+			registered := map[string]bool{
+				"hop":    registry.HopRegistry().IsRegistered("txn-hop"),
+				"bypass": registry.BypassRegistry().IsRegistered("txn-bypass"),
+				"chain":  registry.ChainRegistry().IsRegistered("txn-chain"),
+			}
+			for kind, expect := range test.registered {
+				assert.Equal(t, expect, registered[kind], kind)
+			}
+		})
+	}
+}
+
+func TestRunTransactionDelete(t *testing.T) {
+	useStateDir(t)
+	t.Cleanup(func() { registry.HopRegistry().Unregister("txn-hop") })
+
+	_, err := runTransaction(transactionRequest{Operations: []transactionOp{
+		txnOp(txnOpAdd, "hop", "", map[string]any{"name": "txn-hop"}),
+	}})
+	require.NoError(t, err)
+	before := config.Global().Hops
+
+	// the deletion is undone when a later operation fails
+	_, err = runTransaction(transactionRequest{Operations: []transactionOp{
+		txnOp(txnOpDelete, "hop", "txn-hop", nil),
+		txnOp(txnOpDelete, "hop", "txn-missing", nil),
+	}})
+	assert.Error(t, err)
+	assert.True(t, registry.HopRegistry().IsRegistered("txn-hop"))
+	assert.Equal(t, before, config.Global().Hops)
+
+	resp, err := runTransaction(transactionRequest{Operations: []transactionOp{
+		txnOp(txnOpDelete, "hop", "txn-hop", nil),
+	}})
+	require.NoError(t, err)
+	assert.True(t, resp.Committed)
+	assert.False(t, registry.HopRegistry().IsRegistered("txn-hop"))
+	assert.Empty(t, config.Global().Hops)
+}
@@ -0,0 +1,266 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-gost/core/observer/stats"
+	"github.com/go-gost/x/config"
+	service_parser "github.com/go-gost/x/config/parsing/service"
+	xlogger "github.com/go-gost/x/logger"
+	"github.com/go-gost/x/registry"
+	xservice "github.com/go-gost/x/service"
+)
+
+// txnMutex 串行化所有多资源变更，保证暂存配置与全局配置之间没有其他写入
+var txnMutex sync.Mutex
+
+// txn 跨服务及 resourceKinds 中各类资源的事务
+//
+// 所有变更先作用于注册表和暂存的配置副本，每一步都记录撤销操作；
+// commit 时一次性替换全局配置中变更过的资源列表并写入 gost.json，
+// rollback 按相反顺序撤销已生效的变更，旧资源根据变更前的配置重新创建。
+type txn struct {
+	// cfg 暂存的配置副本，资源列表在变更时整体替换，不修改全局配置共享的切片
+	cfg *config.Config
+	// lists 变更过的资源列表，提交时从 src 复制到 dst
+	lists map[string]func(dst, src *config.Config)
+	undo  []func() error
+}
+
+// beginTxn 开始事务，调用方必须以 commit 或 rollback 结束
+func beginTxn() *txn {
+	txnMutex.Lock()
+
+	return &txn{
+		cfg:   config.Global(),
+		lists: make(map[string]func(dst, src *config.Config)),
+	}
+}
+
+// commit 将暂存的配置写入全局配置并持久化，
+// 持久化失败时恢复原配置并撤销所有变更
+func (t *txn) commit() error {
+	defer txnMutex.Unlock()
+
+	prev := config.Global()
+	t.apply(t.cfg)
+
+	if err := SaveState(); err != nil {
+		t.apply(prev)
+		t.undoAll()
+		return fmt.Errorf("持久化配置失败: %v", err)
+	}
+	return nil
+}
+
+// apply 将 src 中变更过的资源列表写入全局配置
+func (t *txn) apply(src *config.Config) {
+	config.OnUpdate(func(c *config.Config) error {
+		for _, set := range t.lists {
+			set(c, src)
+		}
+		return nil
+	})
+}
+
+// touch 记录资源列表已变更
+func (t *txn) touch(kind string, set func(dst, src *config.Config)) {
+	t.lists[kind] = set
+}
+
+// rollback 撤销所有已生效的变更，返回撤销过程中遇到的第一个错误
+func (t *txn) rollback() error {
+	defer txnMutex.Unlock()
+
+	return t.undoAll()
+}
+
+func (t *txn) undoAll() error {
+	var first error
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		if err := t.undo[i](); err != nil {
+			xlogger.Printf("⚠️ 事务回滚失败: %v\n", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	t.undo = nil
+	return first
+}
+
+func (t *txn) service(name string) *config.ServiceConfig {
+	for _, s := range t.cfg.Services {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (t *txn) setServices(services []*config.ServiceConfig) {
+	t.cfg.Services = services
+	t.touch(txnKindService, func(dst, src *config.Config) { dst.Services = src.Services })
+}
+
+// putService 创建或替换服务
+func (t *txn) putService(sc *config.ServiceConfig) error {
+	old := t.service(sc.Name)
+
+	if err := updateService(old, sc); err != nil {
+		return fmt.Errorf("服务 %s: %v", sc.Name, err)
+	}
+
+	t.setServices(replaceByName(t.cfg.Services, sc, func(s *config.ServiceConfig) string { return s.Name }))
+	t.undo = append(t.undo, func() error {
+		if old != nil {
+			return updateService(sc, old)
+		}
+		stopService(sc.Name, false)
+		return nil
+	})
+	return nil
+}
+
+// deleteService 删除服务
+func (t *txn) deleteService(name string) error {
+	old := t.service(name)
+	if old == nil && !registry.ServiceRegistry().IsRegistered(name) {
+		return fmt.Errorf("服务 %s 不存在", name)
+	}
+
+	stopService(name, true)
+	t.setServices(removeByName(t.cfg.Services, name, func(s *config.ServiceConfig) string { return s.Name }))
+	t.undo = append(t.undo, func() error {
+		if old != nil {
+			return startService(old)
+		}
+		return nil
+	})
+	return nil
+}
+
+// putChain 创建或替换链
+func (t *txn) putChain(cc *config.ChainConfig) error {
+	return chainResource.put(t, cc)
+}
+
+// deleteChain 删除链
+func (t *txn) deleteChain(name string) error {
+	return chainResource.remove(t, name)
+}
+
+// putLimiter 创建或替换限速器
+func (t *txn) putLimiter(lc *config.LimiterConfig) error {
+	return limiterResource.put(t, lc)
+}
+
+// deleteLimiter 删除限速器
+func (t *txn) deleteLimiter(name string) error {
+	return limiterResource.remove(t, name)
+}
+
+// updateService 将服务从 old 更新到 sc，old 为 nil 表示当前没有该服务的配置
+//
+// 地址、监听器等绑定在监听器上的配置不变时原地更新，端口不释放，已建立的连接不受影响；
+// 否则重建服务并沿用原统计对象，尚未上报的流量不会丢失。失败时服务保持原状。
+func updateService(old, sc *config.ServiceConfig) error {
+	svc := registry.ServiceRegistry().Get(sc.Name)
+
+	if svc != nil && old != nil && !isPaused(old) && !isPaused(sc) &&
+		!service_parser.NeedsRebind(old, sc) {
+		err := service_parser.ReloadService(svc, sc)
+		if err == nil {
+			xlogger.Printf("♻️ 服务 %s 已原地更新\n", sc.Name)
+			return nil
+		}
+		if !errors.Is(err, xservice.ErrReloadUnsupported) {
+			return err
+		}
+	}
+
+	var st stats.Stats
+	if svc != nil {
+		st = xservice.StatsOf(svc)
+	}
+
+	// 暂停时断开现有连接，普通更新保留进行中的连接
+	stopService(sc.Name, isPaused(sc))
+	if err := startService(sc, service_parser.StatsParseOption(st)); err != nil {
+		// 恢复旧服务，保证失败的这一步本身不留下变更
+		if old != nil {
+			startService(old, service_parser.StatsParseOption(st))
+		}
+		return err
+	}
+	return nil
+}
+
+// startService 解析、注册并启动服务，暂停状态的服务注册后立即关闭，不占用端口
+func startService(sc *config.ServiceConfig, opts ...service_parser.ParseOption) error {
+	svc, err := service_parser.ParseService(sc, opts...)
+	if err != nil {
+		return err
+	}
+	if err := registry.ServiceRegistry().Register(sc.Name, svc); err != nil {
+		svc.Close()
+		return err
+	}
+
+	if isPaused(sc) {
+		svc.Close()
+		return nil
+	}
+	go svc.Serve()
+	return nil
+}
+
+// stopService 关闭并注销服务，closeConns 为 true 时同时断开服务的所有连接
+func stopService(name string, closeConns bool) {
+	if svc := registry.ServiceRegistry().Get(name); svc != nil {
+		svc.Close()
+		registry.ServiceRegistry().Unregister(name)
+		if closeConns {
+			xservice.CloseConnections(svc)
+		}
+	}
+}
+
+// serviceConfigOf 返回全局配置中指定服务的配置
+func serviceConfigOf(name string) *config.ServiceConfig {
+	for _, s := range config.Global().Services {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func isPaused(sc *config.ServiceConfig) bool {
+	return sc.Metadata != nil && sc.Metadata["paused"] == true
+}
+
+// replaceByName 返回替换或追加 v 后的新列表，不修改 list
+func replaceByName[T any](list []T, v T, name func(T) string) []T {
+	out := append(list[:0:0], list...)
+	for i := range out {
+		if name(out[i]) == name(v) {
+			out[i] = v
+			return out
+		}
+	}
+	return append(out, v)
+}
+
+// removeByName 返回去掉名称为 n 的元素后的新列表，不修改 list
+func removeByName[T any](list []T, n string, name func(T) string) []T {
+	out := list[:0:0]
+	for _, v := range list {
+		if name(v) != n {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -4,16 +4,20 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/url"
 	"strings"
 	"sync" // 新增：用于管理连接状态的互斥锁
+	"sync/atomic"
 	"time"
 
 	"github.com/go-gost/x/config"
+	xhop "github.com/go-gost/x/hop"
 	"github.com/go-gost/x/internal/util/crypto"
+	xlogger "github.com/go-gost/x/logger"
 	"github.com/gorilla/websocket"
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/host"
@@ -28,6 +32,11 @@ type SystemInfo struct {
 	BytesTransmitted uint64  `json:"bytes_transmitted"` // 发送字节数
 	CPUUsage         float64 `json:"cpu_usage"`         // CPU使用率（百分比）
 	MemoryUsage      float64 `json:"memory_usage"`      // 内存使用率（百分比）
+
+	Full            bool                       `json:"full,omitempty"`             // telemetry 和 services 是否为完整快照
+	Telemetry       map[string]json.RawMessage `json:"telemetry,omitempty"`        // 发生变化的遥测字段，见 NodeTelemetry
+	Services        []ServiceStatus            `json:"services,omitempty"`         // 状态发生变化的服务
+	RemovedServices []string                   `json:"removed_services,omitempty"` // 已删除的服务
 }
 
 // NetworkStats 网络统计信息
@@ -51,6 +60,7 @@ type CommandMessage struct {
 	Type      string      `json:"type"`
 	Data      interface{} `json:"data"`
 	RequestId string      `json:"requestId,omitempty"`
+	Timeout   int64       `json:"timeout,omitempty"` // 命令期限(毫秒)，包含排队时间
 }
 
 // CommandResponse 命令响应结构体
@@ -91,25 +101,42 @@ type WebSocketReporter struct {
 	ctx            context.Context
 	cancel         context.CancelFunc
 	connected      bool
-	connecting     bool              // 新增：正在连接状态
-	connMutex      sync.Mutex        // 新增：连接状态锁
-	aesCrypto      *crypto.AESCrypto // 新增：AES加密器
+	connecting     bool                            // 新增：正在连接状态
+	connMutex      sync.Mutex                      // 新增：连接状态锁
+	keyring        *crypto.Keyring                 // 按用途派生的加密密钥，支持轮换
+	tlsConfig      *tls.Config                     // 面板使用 wss 时的 TLS 配置
+	replayGuard    *replayGuard                    // 加密帧防重放
+	pending        map[string]chan CommandResponse // 等待面板确认的上报消息
+	pendingMutex   sync.Mutex
+	reportPaused   atomic.Int64           // 面板未确认上报时暂停 WebSocket 上报的截止时间（Unix 秒）
+	dispatcher     *commandDispatcher     // 命令调度器
+	sessions       *sessionRecorder       // 会话记录上报
+	telemetry      *telemetryCollector    // 心跳遥测采集
+	serviceStats   *serviceStatsCollector // 心跳服务状态采集
+	logs           *logStreams            // 日志订阅
 }
 
 // NewWebSocketReporter 创建一个新的WebSocket报告器
-func NewWebSocketReporter(serverURL string, secret string) *WebSocketReporter {
+// tlsConfig 仅在 serverURL 为 wss:// 时使用，为 nil 时使用默认配置
+// version 为节点版本，随心跳遥测上报
+func NewWebSocketReporter(serverURL string, secret string, version string, tlsConfig *tls.Config) *WebSocketReporter {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// 创建 AES 加密器
-	aesCrypto, err := crypto.NewAESCrypto(secret)
+	// 创建密钥环
+	keyring, err := crypto.NewKeyring(secret, crypto.DefaultKeyringFile)
 	if err != nil {
-		fmt.Printf("❌ 创建 AES 加密器失败: %v\n", err)
-		aesCrypto = nil
+		xlogger.Printf("❌ 创建密钥环失败: %v\n", err)
+		keyring = nil
 	} else {
-		fmt.Printf("🔐 AES 加密器创建成功\n")
+		kid, _ := keyring.Current(crypto.PurposeCommand)
+		xlogger.Printf("🔐 密钥环创建成功，当前密钥ID: %s\n", kid)
+		if legacyFramesDisabled.Load() {
+			keyring.DisableLegacy()
+			xlogger.Printf("🔐 已关闭旧版加密格式，只接受认证帧\n")
+		}
 	}
 
-	return &WebSocketReporter{
+	w := &WebSocketReporter{
 		url:            serverURL,
 		reconnectTime:  5 * time.Second,  // 重连间隔
 		pingInterval:   2 * time.Second,  // 发送间隔改为2秒
@@ -118,12 +145,25 @@ func NewWebSocketReporter(serverURL string, secret string) *WebSocketReporter {
 		cancel:         cancel,
 		connected:      false,
 		connecting:     false,
-		aesCrypto:      aesCrypto,
-	}
+		keyring:        keyring,
+		tlsConfig:      tlsConfig,
+		replayGuard:    newReplayGuard(frameFreshnessWindow),
+		pending:        make(map[string]chan CommandResponse),
+	}
+	w.dispatcher = newCommandDispatcher(w)
+	w.sessions = newSessionRecorder(w)
+	w.telemetry = newTelemetryCollector(version)
+	w.serviceStats = newServiceStatsCollector()
+	w.logs = newLogStreams()
+	return w
 }
 
 // Start 启动WebSocket报告器
 func (w *WebSocketReporter) Start() {
+	w.dispatcher.start(w.ctx)
+	w.registerSessionRecorder()
+	xhop.OnHealthChange(w.reportHealth)
+	go w.sessions.run(w.ctx)
 	go w.run()
 }
 
@@ -150,7 +190,7 @@ func (w *WebSocketReporter) run() {
 
 			if needConnect {
 				if err := w.connect(); err != nil {
-					fmt.Printf("❌ WebSocket连接失败: %v，%v后重试\n", err, w.reconnectTime)
+					xlogger.Printf("❌ WebSocket连接失败: %v，%v后重试\n", err, w.reconnectTime)
 					select {
 					case <-time.After(w.reconnectTime):
 						continue
@@ -197,8 +237,12 @@ func (w *WebSocketReporter) connect() error {
 		return fmt.Errorf("解析URL失败: %v", err)
 	}
 
-	dialer := websocket.DefaultDialer
+	// 复制默认拨号器，避免修改全局配置
+	dialer := *websocket.DefaultDialer
 	dialer.HandshakeTimeout = 10 * time.Second
+	if u.Scheme == "wss" && w.tlsConfig != nil {
+		dialer.TLSClientConfig = w.tlsConfig.Clone()
+	}
 
 	conn, _, err := dialer.Dial(u.String(), nil)
 	if err != nil {
@@ -213,6 +257,7 @@ func (w *WebSocketReporter) connect() error {
 
 	w.conn = conn
 	w.connected = true
+	w.reportPaused.Store(0)
 
 	// 设置关闭处理器来检测连接状态
 	w.conn.SetCloseHandler(func(code int, text string) error {
@@ -222,7 +267,7 @@ func (w *WebSocketReporter) connect() error {
 		return nil
 	})
 
-	fmt.Printf("✅ WebSocket连接建立成功\n")
+	xlogger.Printf("✅ WebSocket连接建立成功\n")
 	return nil
 }
 
@@ -236,12 +281,20 @@ func (w *WebSocketReporter) handleConnection() {
 		}
 		w.connected = false
 		w.connMutex.Unlock()
-		fmt.Printf("🔌 WebSocket连接已关闭\n")
+		w.failPendingReports()
+		xlogger.Printf("🔌 WebSocket连接已关闭\n")
 	}()
 
 	// 启动消息接收goroutine
 	go w.receiveMessages()
 
+	// 上报配置校验和，便于面板在重连后对账
+	go w.reportConfigState()
+
+	// 新连接的首个心跳携带完整遥测和服务状态
+	w.telemetry.reset()
+	w.serviceStats.reset()
+
 	// 主发送循环
 	ticker := time.NewTicker(w.pingInterval)
 	defer ticker.Stop()
@@ -263,7 +316,7 @@ func (w *WebSocketReporter) handleConnection() {
 			// 获取系统信息并发送
 			sysInfo := w.collectSystemInfo()
 			if err := w.sendSystemInfo(sysInfo); err != nil {
-				fmt.Printf("❌ 发送系统信息失败: %v，准备重连\n", err)
+				xlogger.Printf("❌ 发送系统信息失败: %v，准备重连\n", err)
 				return
 			}
 		}
@@ -275,6 +328,8 @@ func (w *WebSocketReporter) collectSystemInfo() SystemInfo {
 	networkStats := getNetworkStats()
 	cpuInfo := getCPUInfo()
 	memoryInfo := getMemoryInfo()
+	telemetry, full := w.telemetry.next()
+	services, removed := w.serviceStats.next(full)
 
 	return SystemInfo{
 		Uptime:           getUptime(),
@@ -282,6 +337,10 @@ func (w *WebSocketReporter) collectSystemInfo() SystemInfo {
 		BytesTransmitted: networkStats.BytesTransmitted,
 		CPUUsage:         cpuInfo.Usage,
 		MemoryUsage:      memoryInfo.Usage,
+		Full:             full,
+		Telemetry:        telemetry,
+		Services:         services,
+		RemovedServices:  removed,
 	}
 }
 
@@ -300,30 +359,7 @@ func (w *WebSocketReporter) sendSystemInfo(sysInfo SystemInfo) error {
 		return fmt.Errorf("序列化系统信息失败: %v", err)
 	}
 
-	var messageData []byte
-
-	// 如果有加密器，则加密数据
-	if w.aesCrypto != nil {
-		encryptedData, err := w.aesCrypto.Encrypt(jsonData)
-		if err != nil {
-			fmt.Printf("⚠️ 加密失败，发送原始数据: %v\n", err)
-			messageData = jsonData
-		} else {
-			// 创建加密消息包装器
-			encryptedMessage := map[string]interface{}{
-				"encrypted": true,
-				"data":      encryptedData,
-				"timestamp": time.Now().Unix(),
-			}
-			messageData, err = json.Marshal(encryptedMessage)
-			if err != nil {
-				fmt.Printf("⚠️ 序列化加密消息失败，发送原始数据: %v\n", err)
-				messageData = jsonData
-			}
-		}
-	} else {
-		messageData = jsonData
-	}
+	messageData := w.encodeMessage(jsonData)
 
 	// 设置写入超时
 	w.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
@@ -336,6 +372,33 @@ func (w *WebSocketReporter) sendSystemInfo(sysInfo SystemInfo) error {
 	return nil
 }
 
+// encodeMessage 按当前加密设置包装待发送的消息，加密失败时发送原始数据
+func (w *WebSocketReporter) encodeMessage(jsonData []byte) []byte {
+	if w.keyring == nil {
+		return jsonData
+	}
+
+	messageData, err := w.sealFrame(jsonData)
+	if err != nil {
+		xlogger.Printf("⚠️ 加密失败，发送原始数据: %v\n", err)
+		return jsonData
+	}
+	return messageData
+}
+
+// gzipData 压缩上报数据
+func gzipData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // receiveMessages 接收服务端发送的消息
 func (w *WebSocketReporter) receiveMessages() {
 	for {
@@ -358,7 +421,7 @@ func (w *WebSocketReporter) receiveMessages() {
 			messageType, message, err := conn.ReadMessage()
 			if err != nil {
 				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					fmt.Printf("❌ WebSocket读取消息错误: %v\n", err)
+					xlogger.Printf("❌ WebSocket读取消息错误: %v\n", err)
 				}
 				w.connMutex.Lock()
 				w.connected = false
@@ -376,46 +439,44 @@ func (w *WebSocketReporter) receiveMessages() {
 func (w *WebSocketReporter) handleReceivedMessage(messageType int, message []byte) {
 	switch messageType {
 	case websocket.TextMessage:
-		// 先检查是否是加密消息
-		var encryptedWrapper struct {
-			Encrypted bool   `json:"encrypted"`
-			Data      string `json:"data"`
-			Timestamp int64  `json:"timestamp"`
+		// 校验并解密加密帧（时间窗口、重放、附加数据认证）
+		plain, err := w.openFrame(message)
+		if err != nil {
+			xlogger.Printf("❌ 拒绝消息: %v\n", err)
+			w.sendFrameRejection(err)
+			return
 		}
+		message = plain
 
-		// 尝试解析为加密消息格式
-		if err := json.Unmarshal(message, &encryptedWrapper); err == nil && encryptedWrapper.Encrypted {
-			if w.aesCrypto != nil {
-				// 解密数据
-				decryptedData, err := w.aesCrypto.Decrypt(encryptedWrapper.Data)
-				if err != nil {
-					fmt.Printf("❌ 解密失败: %v\n", err)
-					w.sendErrorResponse("DecryptError", fmt.Sprintf("解密失败: %v", err))
-					return
-				}
-				message = decryptedData
-			} else {
-				fmt.Printf("❌ 收到加密消息但没有加密器\n")
-				w.sendErrorResponse("NoDecryptor", "没有可用的解密器")
-				return
-			}
+		// 面板对本节点上报消息的确认
+		if w.deliverReportAck(message) {
+			return
 		}
+
 		// 先尝试解析是否是压缩消息
 		var compressedMsg struct {
 			Type       string          `json:"type"`
 			Compressed bool            `json:"compressed"`
 			Data       json.RawMessage `json:"data"`
 			RequestId  string          `json:"requestId,omitempty"`
+			Timeout    int64           `json:"timeout,omitempty"`
 		}
 
 		if err := json.Unmarshal(message, &compressedMsg); err == nil && compressedMsg.Compressed {
 			// 处理压缩消息
-			fmt.Printf("📥 收到压缩消息，正在解压...\n")
+			xlogger.Printf("📥 收到压缩消息，正在解压...\n")
+
+			// 压缩数据可能是原始字节，也可能是 base64 字符串（与上报消息格式一致）
+			compressedData := []byte(compressedMsg.Data)
+			var encodedData []byte
+			if err := json.Unmarshal(compressedMsg.Data, &encodedData); err == nil {
+				compressedData = encodedData
+			}
 
 			// 解压数据
-			gzipReader, err := gzip.NewReader(bytes.NewReader(compressedMsg.Data))
+			gzipReader, err := gzip.NewReader(bytes.NewReader(compressedData))
 			if err != nil {
-				fmt.Printf("❌ 创建解压读取器失败: %v\n", err)
+				xlogger.Printf("❌ 创建解压读取器失败: %v\n", err)
 				w.sendErrorResponse("DecompressError", fmt.Sprintf("解压失败: %v", err))
 				return
 			}
@@ -423,7 +484,7 @@ func (w *WebSocketReporter) handleReceivedMessage(messageType int, message []byt
 
 			var decompressedData bytes.Buffer
 			if _, err := decompressedData.ReadFrom(gzipReader); err != nil {
-				fmt.Printf("❌ 解压数据失败: %v\n", err)
+				xlogger.Printf("❌ 解压数据失败: %v\n", err)
 				w.sendErrorResponse("DecompressError", fmt.Sprintf("解压失败: %v", err))
 				return
 			}
@@ -435,8 +496,9 @@ func (w *WebSocketReporter) handleReceivedMessage(messageType int, message []byt
 			var cmdMsg CommandMessage
 			cmdMsg.Type = compressedMsg.Type
 			cmdMsg.RequestId = compressedMsg.RequestId
+			cmdMsg.Timeout = compressedMsg.Timeout
 			if err := json.Unmarshal(message, &cmdMsg.Data); err != nil {
-				fmt.Printf("❌ 解析解压后的命令数据失败: %v\n", err)
+				xlogger.Printf("❌ 解析解压后的命令数据失败: %v\n", err)
 				w.sendErrorResponse("ParseError", fmt.Sprintf("解析命令失败: %v", err))
 				return
 			}
@@ -448,7 +510,7 @@ func (w *WebSocketReporter) handleReceivedMessage(messageType int, message []byt
 			// 处理普通消息
 			var cmdMsg CommandMessage
 			if err := json.Unmarshal(message, &cmdMsg); err != nil {
-				fmt.Printf("❌ 解析命令消息失败: %v\n", err)
+				xlogger.Printf("❌ 解析命令消息失败: %v\n", err)
 				w.sendErrorResponse("ParseError", fmt.Sprintf("解析命令失败: %v", err))
 				return
 			}
@@ -458,19 +520,54 @@ func (w *WebSocketReporter) handleReceivedMessage(messageType int, message []byt
 		}
 
 	default:
-		fmt.Printf("📨 收到未知类型消息: %d\n", messageType)
+		xlogger.Printf("📨 收到未知类型消息: %d\n", messageType)
 	}
 }
 
-// routeCommand 路由命令到对应的处理函数
+// routeCommand 处理调度类命令，其余命令交给调度器异步执行
 func (w *WebSocketReporter) routeCommand(cmd CommandMessage) {
+	switch cmd.Type {
+	case "CancelCommand":
+		response := CommandResponse{Type: "CancelCommandResponse", RequestId: cmd.RequestId}
+		var req struct {
+			RequestId string `json:"requestId"`
+		}
+		jsonData, _ := json.Marshal(cmd.Data)
+		json.Unmarshal(jsonData, &req)
+		if err := w.dispatcher.cancel(req.RequestId); err != nil {
+			response.Message = err.Error()
+		} else {
+			response.Success = true
+			response.Message = "OK"
+		}
+		w.sendResponse(response)
+	case "CommandStatus":
+		w.sendResponse(CommandResponse{
+			Type:      "CommandStatusResponse",
+			Success:   true,
+			Message:   "OK",
+			Data:      w.dispatcher.status(),
+			RequestId: cmd.RequestId,
+		})
+	default:
+		w.dispatcher.submit(cmd)
+	}
+}
+
+// executeCommand 执行命令并返回响应
+func (w *WebSocketReporter) executeCommand(ctx context.Context, cmd CommandMessage) CommandResponse {
 	jsonBytes, errs := json.Marshal(cmd)
 	if errs != nil {
-		fmt.Println("Error marshaling JSON:", errs)
-		return
+		xlogger.Println("Error marshaling JSON:", errs)
+		return failedResponse(cmd, fmt.Sprintf("序列化命令失败: %v", errs))
 	}
 
-	fmt.Println("🔔 收到命令: ", string(jsonBytes))
+	if cmd.Type == "RotateKey" {
+		// 不记录新的密钥材料
+		xlogger.Println("🔔 收到命令: ", cmd.Type)
+	} else {
+		xlogger.Println("🔔 收到命令: ", string(jsonBytes))
+	}
 	var err error
 	var response CommandResponse
 
@@ -486,14 +583,27 @@ func (w *WebSocketReporter) routeCommand(cmd CommandMessage) {
 		err = w.handleUpdateService(cmd.Data)
 		response.Type = "UpdateServiceResponse"
 	case "DeleteService":
-		err = w.handleDeleteService(cmd.Data)
+		var drainResult *ServiceDrainResponse
+		drainResult, err = w.handleDeleteService(cmd.Data)
 		response.Type = "DeleteServiceResponse"
+		if drainResult != nil {
+			response.Data = drainResult
+		}
 	case "PauseService":
-		err = w.handlePauseService(cmd.Data)
+		var drainResult *ServiceDrainResponse
+		drainResult, err = w.handlePauseService(cmd.Data)
 		response.Type = "PauseServiceResponse"
+		if drainResult != nil {
+			response.Data = drainResult
+		}
 	case "ResumeService":
 		err = w.handleResumeService(cmd.Data)
 		response.Type = "ResumeServiceResponse"
+	case "KillConnections":
+		var killResult KillConnectionsResponse
+		killResult, err = w.handleKillConnections(cmd.Data)
+		response.Type = "KillConnectionsResponse"
+		response.Data = killResult
 
 	// Chain 相关命令
 	case "AddChains":
@@ -517,12 +627,99 @@ func (w *WebSocketReporter) routeCommand(cmd CommandMessage) {
 		err = w.handleDeleteLimiter(cmd.Data)
 		response.Type = "DeleteLimitersResponse"
 
-	// TCP Ping 诊断命令
+	// 网络诊断命令
 	case "TcpPing":
 		var tcpPingResult TcpPingResponse
-		tcpPingResult, err = w.handleTcpPing(cmd.Data)
+		tcpPingResult, err = w.handleTcpPing(ctx, cmd.Data)
 		response.Type = "TcpPingResponse"
 		response.Data = tcpPingResult
+	case "UdpProbe":
+		var udpProbeResult UdpProbeResponse
+		udpProbeResult, err = w.handleUdpProbe(ctx, cmd.Data)
+		response.Type = "UdpProbeResponse"
+		response.Data = udpProbeResult
+	case "TcpTraceroute":
+		var tracerouteResult TcpTracerouteResponse
+		tracerouteResult, err = w.handleTcpTraceroute(ctx, cmd.Data)
+		response.Type = "TcpTracerouteResponse"
+		response.Data = tracerouteResult
+	case "DnsResolve":
+		var dnsResult DnsResolveResponse
+		dnsResult, err = w.handleDnsResolve(ctx, cmd.Data)
+		response.Type = "DnsResolveResponse"
+		response.Data = dnsResult
+	case "TlsProbe":
+		var tlsProbeResult TlsProbeResponse
+		tlsProbeResult, err = w.handleTlsProbe(ctx, cmd.Data)
+		response.Type = "TlsProbeResponse"
+		response.Data = tlsProbeResult
+	case "HttpProbe":
+		var httpProbeResult HttpProbeResponse
+		httpProbeResult, err = w.handleHttpProbe(ctx, cmd.Data)
+		response.Type = "HttpProbeResponse"
+		response.Data = httpProbeResult
+	case "ChainProbe":
+		var chainProbeResult ChainProbeResponse
+		chainProbeResult, err = w.handleChainProbe(ctx, cmd.Data)
+		response.Type = "ChainProbeResponse"
+		response.Data = chainProbeResult
+	case "SpeedTest":
+		response.Data, err = w.handleSpeedTest(ctx, cmd.Data)
+		response.Type = "SpeedTestResponse"
+
+	// 日志命令
+	case "SubscribeLogs":
+		response.Data, err = w.handleSubscribeLogs(cmd.Data)
+		response.Type = "SubscribeLogsResponse"
+
+	case "UnsubscribeLogs":
+		err = w.handleUnsubscribeLogs(cmd.Data)
+		response.Type = "UnsubscribeLogsResponse"
+
+	case "SetLogLevel":
+		response.Data, err = w.handleSetLogLevel(cmd.Data)
+		response.Type = "SetLogLevelResponse"
+
+	// 节点健康状态
+	case "HealthStatus":
+		response.Data, err = w.handleHealthStatus(cmd.Data)
+		response.Type = "HealthStatusResponse"
+
+	// 全量状态对账
+	case "SyncState":
+		var syncResult SyncStateResponse
+		syncResult, err = w.handleSyncState(cmd.Data)
+		response.Type = "SyncStateResponse"
+		response.Data = syncResult
+
+	// 多资源事务
+	case "Transaction":
+		var txnResult TransactionResponse
+		txnResult, err = w.handleTransaction(cmd.Data)
+		response.Type = "TransactionResponse"
+		response.Data = txnResult
+
+	// 通用资源管理
+	case "Apply":
+		var applyResult ResourceResponse
+		applyResult, err = w.handleApply(cmd.Data)
+		response.Type = "ApplyResponse"
+		response.Data = applyResult
+	case "Delete":
+		var deleteResult ResourceResponse
+		deleteResult, err = w.handleDelete(cmd.Data)
+		response.Type = "DeleteResponse"
+		response.Data = deleteResult
+	case "Get":
+		response.Data, err = w.handleGet(cmd.Data)
+		response.Type = "GetResponse"
+
+	// 密钥轮换
+	case "RotateKey":
+		var rotateResult RotateKeyResponse
+		rotateResult, err = w.handleRotateKey(cmd.Data)
+		response.Type = "RotateKeyResponse"
+		response.Data = rotateResult
 
 	default:
 		err = fmt.Errorf("未知命令类型: %s", cmd.Type)
@@ -540,7 +737,7 @@ func (w *WebSocketReporter) routeCommand(cmd CommandMessage) {
 		response.Message = "OK"
 	}
 
-	w.sendResponse(response)
+	return response
 }
 
 // Service 命令处理函数
@@ -587,32 +784,32 @@ func (w *WebSocketReporter) handleUpdateService(data interface{}) error {
 	return updateServices(req)
 }
 
-func (w *WebSocketReporter) handleDeleteService(data interface{}) error {
+func (w *WebSocketReporter) handleDeleteService(data interface{}) (*ServiceDrainResponse, error) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		return fmt.Errorf("序列化数据失败: %v", err)
+		return nil, fmt.Errorf("序列化数据失败: %v", err)
 	}
 
 	var req deleteServicesRequest
 	if err := json.Unmarshal(jsonData, &req); err != nil {
-		return fmt.Errorf("解析删除请求失败: %v", err)
+		return nil, fmt.Errorf("解析删除请求失败: %v", err)
 	}
 
-	return deleteServices(req)
+	return deleteServices(req, w.reportDrain)
 }
 
-func (w *WebSocketReporter) handlePauseService(data interface{}) error {
+func (w *WebSocketReporter) handlePauseService(data interface{}) (*ServiceDrainResponse, error) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		return fmt.Errorf("序列化数据失败: %v", err)
+		return nil, fmt.Errorf("序列化数据失败: %v", err)
 	}
 
 	var req pauseServicesRequest
 	if err := json.Unmarshal(jsonData, &req); err != nil {
-		return fmt.Errorf("解析暂停请求失败: %v", err)
+		return nil, fmt.Errorf("解析暂停请求失败: %v", err)
 	}
 
-	return pauseServices(req)
+	return pauseServices(req, w.reportDrain)
 }
 
 func (w *WebSocketReporter) handleResumeService(data interface{}) error {
@@ -629,6 +826,20 @@ func (w *WebSocketReporter) handleResumeService(data interface{}) error {
 	return resumeServices(req)
 }
 
+func (w *WebSocketReporter) handleKillConnections(data interface{}) (KillConnectionsResponse, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return KillConnectionsResponse{}, fmt.Errorf("序列化数据失败: %v", err)
+	}
+
+	var req killConnectionsRequest
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return KillConnectionsResponse{}, fmt.Errorf("解析断开连接请求失败: %v", err)
+	}
+
+	return killConnections(req)
+}
+
 // Chain 命令处理函数
 func (w *WebSocketReporter) handleAddChain(data interface{}) error {
 	jsonData, err := json.Marshal(data)
@@ -779,22 +990,22 @@ func (w *WebSocketReporter) handleCall(data interface{}) error {
 		return fmt.Errorf("解析call数据失败: %v", err)
 	}
 
-	fmt.Printf("🔔 收到服务端call回调: %v\n", callData)
+	xlogger.Printf("🔔 收到服务端call回调: %v\n", callData)
 
 	// 根据call的类型执行不同的操作
 	if callType, exists := callData["type"]; exists {
 		switch callType {
 		case "ping":
-			fmt.Printf("📡 收到ping，发送pong回应\n")
+			xlogger.Printf("📡 收到ping，发送pong回应\n")
 			// 可以在这里发送pong响应
 		case "info_request":
-			fmt.Printf("📊 服务端请求额外信息\n")
+			xlogger.Printf("📊 服务端请求额外信息\n")
 			// 可以在这里发送额外的系统信息
 		case "command":
-			fmt.Printf("⚡ 服务端发送执行命令\n")
+			xlogger.Printf("⚡ 服务端发送执行命令\n")
 			// 可以在这里执行特定命令
 		default:
-			fmt.Printf("❓ 未知的call类型: %v\n", callType)
+			xlogger.Printf("❓ 未知的call类型: %v\n", callType)
 		}
 	}
 
@@ -808,44 +1019,21 @@ func (w *WebSocketReporter) sendResponse(response CommandResponse) {
 	defer w.connMutex.Unlock()
 
 	if w.conn == nil || !w.connected {
-		fmt.Printf("❌ 无法发送响应：连接未建立\n")
+		xlogger.Printf("❌ 无法发送响应：连接未建立\n")
 		return
 	}
 
 	jsonData, err := json.Marshal(response)
 	if err != nil {
-		fmt.Printf("❌ 序列化响应失败: %v\n", err)
+		xlogger.Printf("❌ 序列化响应失败: %v\n", err)
 		return
 	}
 
-	var messageData []byte
-
-	// 如果有加密器，则加密数据
-	if w.aesCrypto != nil {
-		encryptedData, err := w.aesCrypto.Encrypt(jsonData)
-		if err != nil {
-			fmt.Printf("⚠️ 加密响应失败，发送原始数据: %v\n", err)
-			messageData = jsonData
-		} else {
-			// 创建加密消息包装器
-			encryptedMessage := map[string]interface{}{
-				"encrypted": true,
-				"data":      encryptedData,
-				"timestamp": time.Now().Unix(),
-			}
-			messageData, err = json.Marshal(encryptedMessage)
-			if err != nil {
-				fmt.Printf("⚠️ 序列化加密响应失败，发送原始数据: %v\n", err)
-				messageData = jsonData
-			}
-		}
-	} else {
-		messageData = jsonData
-	}
+	messageData := w.encodeMessage(jsonData)
 
 	// 检查消息大小，如果超过10MB则记录警告
 	if len(messageData) > 10*1024*1024 {
-		fmt.Printf("⚠️ 响应消息过大 (%.2f MB)，可能会被拒绝\n", float64(len(messageData))/(1024*1024))
+		xlogger.Printf("⚠️ 响应消息过大 (%.2f MB)，可能会被拒绝\n", float64(len(messageData))/(1024*1024))
 	}
 
 	// 设置较长的写入超时，以应对大消息
@@ -856,7 +1044,7 @@ func (w *WebSocketReporter) sendResponse(response CommandResponse) {
 
 	w.conn.SetWriteDeadline(time.Now().Add(timeout))
 	if err := w.conn.WriteMessage(websocket.TextMessage, messageData); err != nil {
-		fmt.Printf("❌ 发送响应失败: %v\n", err)
+		xlogger.Printf("❌ 发送响应失败: %v\n", err)
 		w.connected = false
 	}
 }
@@ -886,7 +1074,7 @@ func getNetworkStats() NetworkStats {
 
 	ioCounters, err := psnet.IOCounters(true)
 	if err != nil {
-		fmt.Printf("获取网络统计失败: %v\n", err)
+		xlogger.Printf("获取网络统计失败: %v\n", err)
 		return stats
 	}
 
@@ -932,20 +1120,26 @@ func getMemoryInfo() MemoryInfo {
 }
 
 // StartWebSocketReporterWithConfig 使用配置启动WebSocket报告器
-func StartWebSocketReporterWithConfig(Addr string, Secret string, Version string) *WebSocketReporter {
+// tlsConfig 不为 nil 时使用 wss 连接面板
+func StartWebSocketReporterWithConfig(Addr string, Secret string, Version string, tlsConfig *tls.Config) *WebSocketReporter {
+
+	scheme := "ws"
+	if tlsConfig != nil {
+		scheme = "wss"
+	}
 
 	// 构建包含本机IP的WebSocket URL
-	var fullURL = "ws://" + Addr + "/system-info?type=1&secret=" + Secret + "&version=" + Version
+	var fullURL = scheme + "://" + Addr + "/system-info?type=1&secret=" + Secret + "&version=" + Version
 
-	fmt.Printf("🔗 WebSocket连接URL: %s\n", fullURL)
+	xlogger.Printf("🔗 WebSocket连接地址: %s://%s/system-info\n", scheme, Addr)
 
-	reporter := NewWebSocketReporter(fullURL, Secret) // Pass Secret here
+	reporter := NewWebSocketReporter(fullURL, Secret, Version, tlsConfig) // Pass Secret here
 	reporter.Start()
 	return reporter
 }
 
 // handleTcpPing 处理TCP ping诊断命令
-func (w *WebSocketReporter) handleTcpPing(data interface{}) (TcpPingResponse, error) {
+func (w *WebSocketReporter) handleTcpPing(ctx context.Context, data interface{}) (TcpPingResponse, error) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return TcpPingResponse{}, fmt.Errorf("序列化TCP ping数据失败: %v", err)
@@ -987,7 +1181,7 @@ func (w *WebSocketReporter) handleTcpPing(data interface{}) (TcpPingResponse, er
 	}
 
 	// 执行TCP ping操作
-	avgTime, packetLoss, err := tcpPingHost(req.IP, req.Port, req.Count, req.Timeout)
+	avgTime, packetLoss, err := tcpPingHost(ctx, req.IP, req.Port, req.Count, req.Timeout)
 
 	response := TcpPingResponse{
 		IP:        req.IP,
@@ -1008,7 +1202,7 @@ func (w *WebSocketReporter) handleTcpPing(data interface{}) (TcpPingResponse, er
 }
 
 // tcpPingHost 执行TCP连接测试，返回平均连接时间和失败率
-func tcpPingHost(ip string, port int, count int, timeoutMs int) (float64, float64, error) {
+func tcpPingHost(ctx context.Context, ip string, port int, count int, timeoutMs int) (float64, float64, error) {
 	var totalTime float64
 	var successCount int
 
@@ -1018,20 +1212,24 @@ func tcpPingHost(ip string, port int, count int, timeoutMs int) (float64, float6
 	// 它会自动为IPv6地址添加方括号
 	target := net.JoinHostPort(ip, fmt.Sprintf("%d", port))
 
-	fmt.Printf("🔍 开始TCP ping测试: %s，次数: %d，超时: %dms\n", target, count, timeoutMs)
+	xlogger.Printf("🔍 开始TCP ping测试: %s，次数: %d，超时: %dms\n", target, count, timeoutMs)
 
+	dialer := net.Dialer{Timeout: timeout}
 	for i := 0; i < count; i++ {
+		if err := ctx.Err(); err != nil {
+			return 0, 0, err
+		}
 		start := time.Now()
 
 		// 创建带超时的TCP连接
-		conn, err := net.DialTimeout("tcp", target, timeout)
+		conn, err := dialer.DialContext(ctx, "tcp", target)
 
 		elapsed := time.Since(start)
 
 		if err != nil {
-			fmt.Printf("  第%d次连接失败: %v (%.2fms)\n", i+1, err, elapsed.Seconds()*1000)
+			xlogger.Printf("  第%d次连接失败: %v (%.2fms)\n", i+1, err, elapsed.Seconds()*1000)
 		} else {
-			fmt.Printf("  第%d次连接成功: %.2fms\n", i+1, elapsed.Seconds()*1000)
+			xlogger.Printf("  第%d次连接成功: %.2fms\n", i+1, elapsed.Seconds()*1000)
 			conn.Close()
 			totalTime += elapsed.Seconds() * 1000 // 转换为毫秒
 			successCount++
@@ -1039,7 +1237,10 @@ func tcpPingHost(ip string, port int, count int, timeoutMs int) (float64, float6
 
 		// 如果不是最后一次，等待一下再进行下次测试
 		if i < count-1 {
-			time.Sleep(100 * time.Millisecond)
+			select {
+			case <-time.After(100 * time.Millisecond):
+			case <-ctx.Done():
+			}
 		}
 	}
 
@@ -1050,7 +1251,7 @@ func tcpPingHost(ip string, port int, count int, timeoutMs int) (float64, float6
 	avgTime := totalTime / float64(successCount)
 	packetLoss := float64(count-successCount) / float64(count) * 100
 
-	fmt.Printf("✅ TCP ping完成: 平均连接时间 %.2fms，失败率 %.1f%%\n", avgTime, packetLoss)
+	xlogger.Printf("✅ TCP ping完成: 平均连接时间 %.2fms，失败率 %.1f%%\n", avgTime, packetLoss)
 
 	return avgTime, packetLoss, nil
 }
@@ -1110,6 +1311,18 @@ func (w *WebSocketReporter) processDurationInData(data interface{}) interface{}
 					}
 				}
 			}
+			if key == "healthCheck" {
+				// 处理 healthCheck 对象中的 interval 和 timeout
+				if healthCheckObj, ok := value.(map[string]interface{}); ok {
+					for _, field := range []string{"interval", "timeout"} {
+						if durationStr, ok := healthCheckObj[field].(string); ok {
+							if duration, err := time.ParseDuration(durationStr); err == nil {
+								healthCheckObj[field] = int64(duration)
+							}
+						}
+					}
+				}
+			}
 			v[key] = w.processDurationInData(value)
 		}
 		return v
@@ -68,6 +68,51 @@ CREATE TABLE `node` (
 
 -- --------------------------------------------------------
 
+--
+-- 表的结构 `node_health_event`
+--
+
+CREATE TABLE `node_health_event` (
+  `id` int(10) NOT NULL,
+  `node_id` int(10) NOT NULL,
+  `hop` varchar(100) NOT NULL,
+  `node` varchar(100) NOT NULL,
+  `addr` varchar(255) NOT NULL,
+  `healthy` int(10) NOT NULL,
+  `last_error` text,
+  `latency` bigint(20) NOT NULL DEFAULT '0',
+  `since` bigint(20) NOT NULL,
+  `created_time` bigint(20) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- --------------------------------------------------------
+
+--
+-- 表的结构 `session_record`
+--
+
+CREATE TABLE `session_record` (
+  `id` bigint(20) NOT NULL,
+  `node_id` int(10) NOT NULL,
+  `service` varchar(100) NOT NULL,
+  `network` varchar(20) NOT NULL,
+  `remote_addr` varchar(255) NOT NULL,
+  `local_addr` varchar(255) NOT NULL,
+  `host` varchar(255) NOT NULL,
+  `dst` varchar(255) NOT NULL,
+  `client_ip` varchar(100) NOT NULL,
+  `route` varchar(255) DEFAULT NULL,
+  `input_bytes` bigint(20) NOT NULL DEFAULT '0',
+  `output_bytes` bigint(20) NOT NULL DEFAULT '0',
+  `duration` bigint(20) NOT NULL DEFAULT '0',
+  `err` text,
+  `sid` varchar(100) NOT NULL,
+  `time` bigint(20) NOT NULL,
+  `created_time` bigint(20) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- --------------------------------------------------------
+
 --
 -- 表的结构 `speed_limit`
 --
@@ -208,6 +253,21 @@ ALTER TABLE `forward`
 ALTER TABLE `node`
   ADD PRIMARY KEY (`id`);
 
+--
+-- 表的索引 `node_health_event`
+--
+ALTER TABLE `node_health_event`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `node_id` (`node_id`,`created_time`);
+
+--
+-- 表的索引 `session_record`
+--
+ALTER TABLE `session_record`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `node_service` (`node_id`,`service`,`time`),
+  ADD KEY `created_time` (`created_time`);
+
 --
 -- 表的索引 `speed_limit`
 --
@@ -261,6 +321,18 @@ ALTER TABLE `forward`
 ALTER TABLE `node`
   MODIFY `id` int(10) NOT NULL AUTO_INCREMENT, AUTO_INCREMENT=1;
 
+--
+-- 使用表AUTO_INCREMENT `node_health_event`
+--
+ALTER TABLE `node_health_event`
+  MODIFY `id` int(10) NOT NULL AUTO_INCREMENT, AUTO_INCREMENT=1;
+
+--
+-- 使用表AUTO_INCREMENT `session_record`
+--
+ALTER TABLE `session_record`
+  MODIFY `id` bigint(20) NOT NULL AUTO_INCREMENT, AUTO_INCREMENT=1;
+
 --
 -- 使用表AUTO_INCREMENT `speed_limit`
 --
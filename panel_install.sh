@@ -853,6 +853,46 @@ UPDATE \`statistics_flow\`
 SET \`created_time\` = UNIX_TIMESTAMP() * 1000
 WHERE \`created_time\` = 0 OR \`created_time\` IS NULL;
 
+-- 创建 session_record 表（如果不存在）
+CREATE TABLE IF NOT EXISTS \`session_record\` (
+  \`id\` bigint(20) NOT NULL AUTO_INCREMENT,
+  \`node_id\` int(10) NOT NULL,
+  \`service\` varchar(100) NOT NULL,
+  \`network\` varchar(20) NOT NULL,
+  \`remote_addr\` varchar(255) NOT NULL,
+  \`local_addr\` varchar(255) NOT NULL,
+  \`host\` varchar(255) NOT NULL,
+  \`dst\` varchar(255) NOT NULL,
+  \`client_ip\` varchar(100) NOT NULL,
+  \`route\` varchar(255) DEFAULT NULL,
+  \`input_bytes\` bigint(20) NOT NULL DEFAULT '0',
+  \`output_bytes\` bigint(20) NOT NULL DEFAULT '0',
+  \`duration\` bigint(20) NOT NULL DEFAULT '0',
+  \`err\` text,
+  \`sid\` varchar(100) NOT NULL,
+  \`time\` bigint(20) NOT NULL,
+  \`created_time\` bigint(20) NOT NULL,
+  PRIMARY KEY (\`id\`),
+  KEY \`node_service\` (\`node_id\`,\`service\`,\`time\`),
+  KEY \`created_time\` (\`created_time\`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 创建 node_health_event 表（如果不存在）
+CREATE TABLE IF NOT EXISTS \`node_health_event\` (
+  \`id\` int(10) NOT NULL AUTO_INCREMENT,
+  \`node_id\` int(10) NOT NULL,
+  \`hop\` varchar(100) NOT NULL,
+  \`node\` varchar(100) NOT NULL,
+  \`addr\` varchar(255) NOT NULL,
+  \`healthy\` int(10) NOT NULL,
+  \`last_error\` text,
+  \`latency\` bigint(20) NOT NULL DEFAULT '0',
+  \`since\` bigint(20) NOT NULL,
+  \`created_time\` bigint(20) NOT NULL,
+  PRIMARY KEY (\`id\`),
+  KEY \`node_id\` (\`node_id\`,\`created_time\`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 EOF
 
   # 检查数据库容器
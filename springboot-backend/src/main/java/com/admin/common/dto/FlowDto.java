@@ -13,4 +13,7 @@ public class FlowDto {
 
     // 下载流量
     private Long d;
+
+    // 持久化队列序号，批量上报时用于去重
+    private Long s;
 }
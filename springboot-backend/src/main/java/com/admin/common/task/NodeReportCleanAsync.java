@@ -0,0 +1,46 @@
+package com.admin.common.task;
+
+import com.admin.entity.NodeHealthEvent;
+import com.admin.entity.SessionRecord;
+import com.admin.service.NodeHealthEventService;
+import com.admin.service.SessionRecordService;
+import com.baomidou.mybatisplus.core.conditions.query.LambdaQueryWrapper;
+import lombok.extern.slf4j.Slf4j;
+import org.springframework.context.annotation.Configuration;
+import org.springframework.scheduling.annotation.EnableScheduling;
+import org.springframework.scheduling.annotation.Scheduled;
+
+import javax.annotation.Resource;
+
+/**
+ * 清理过期的节点上报记录（会话记录、节点健康状态变化）
+ */
+@Slf4j
+@Configuration
+@EnableScheduling
+public class NodeReportCleanAsync {
+
+    // 上报记录保留天数
+    private static final long RETENTION_DAYS = 30;
+
+    @Resource
+    SessionRecordService sessionRecordService;
+
+    @Resource
+    NodeHealthEventService nodeHealthEventService;
+
+    @Scheduled(cron = "0 30 3 * * ?")
+    public void clean_node_reports() {
+        long cutoffMs = System.currentTimeMillis() - RETENTION_DAYS * 24 * 60 * 60 * 1000;
+
+        sessionRecordService.remove(
+                new LambdaQueryWrapper<SessionRecord>()
+                        .lt(SessionRecord::getCreatedTime, cutoffMs)
+        );
+        nodeHealthEventService.remove(
+                new LambdaQueryWrapper<NodeHealthEvent>()
+                        .lt(NodeHealthEvent::getCreatedTime, cutoffMs)
+        );
+        log.info("已清理 {} 天前的会话记录和节点健康状态", RETENTION_DAYS);
+    }
+}
@@ -0,0 +1,219 @@
+package com.admin.common.task;
+
+import com.admin.common.dto.FlowDto;
+import com.admin.common.dto.GostConfigDto;
+import com.admin.common.utils.GzipUtil;
+import com.admin.controller.FlowController;
+import com.admin.entity.NodeHealthEvent;
+import com.admin.entity.SessionRecord;
+import com.admin.service.NodeHealthEventService;
+import com.admin.service.SessionRecordService;
+import com.alibaba.fastjson.JSON;
+import com.alibaba.fastjson.JSONArray;
+import com.alibaba.fastjson.JSONObject;
+import lombok.extern.slf4j.Slf4j;
+import org.springframework.context.annotation.Lazy;
+import org.springframework.stereotype.Service;
+
+import javax.annotation.Resource;
+import java.nio.charset.StandardCharsets;
+import java.time.OffsetDateTime;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.Base64;
+import java.util.HashSet;
+import java.util.List;
+import java.util.Set;
+
+/**
+ * 节点上报消息处理
+ * 节点通过 WebSocket 发送 {"type","compressed","data","requestId"} 形式的上报，
+ * 面板处理后以相同 requestId 的响应确认；节点在确认超时后改用 HTTP 上报。
+ * 节点收到成功确认后即丢弃本地缓冲，需要留存的上报必须写入数据库后才能确认
+ */
+@Slf4j
+@Service
+public class NodeReportHandler {
+
+    // 节点主动上报的消息类型
+    private static final Set<String> REPORT_TYPES = new HashSet<>(Arrays.asList(
+            "TrafficReport", "ConfigReport", "ConfigState", "ServiceDrain",
+            "SessionRecords", "NodeHealth", "LogRecords"));
+
+    @Resource
+    @Lazy
+    private FlowController flowController;
+
+    @Resource
+    private CheckGostConfigAsync checkGostConfigAsync;
+
+    @Resource
+    private SessionRecordService sessionRecordService;
+
+    @Resource
+    private NodeHealthEventService nodeHealthEventService;
+
+    /**
+     * 判断消息是否为节点上报，命令响应带有 success 字段
+     */
+    public boolean isReport(JSONObject message) {
+        return message != null
+                && REPORT_TYPES.contains(message.getString("type"))
+                && message.getString("requestId") != null
+                && !message.containsKey("success");
+    }
+
+    /**
+     * 解出上报数据，压缩的数据为 base64 编码的 gzip 内容
+     */
+    public String reportData(JSONObject message) throws Exception {
+        if (message.getBooleanValue("compressed")) {
+            byte[] compressed = Base64.getDecoder().decode(message.getString("data"));
+            return new String(GzipUtil.gunzip(compressed), StandardCharsets.UTF_8);
+        }
+        return message.getString("data");
+    }
+
+    /**
+     * 处理上报并返回确认消息，处理失败时确认中 success 为 false
+     */
+    public JSONObject handle(Long nodeId, JSONObject message) {
+        String type = message.getString("type");
+
+        JSONObject ack = new JSONObject();
+        ack.put("type", type + "Ack");
+        ack.put("requestId", message.getString("requestId"));
+
+        try {
+            Object data = process(nodeId, type, reportData(message));
+            ack.put("success", true);
+            ack.put("message", "ok");
+            if (data != null) {
+                ack.put("data", data);
+            }
+        } catch (Exception e) {
+            log.error("处理节点 {} 上报 {} 失败: {}", nodeId, type, e.getMessage());
+            ack.put("success", false);
+            ack.put("message", e.getMessage());
+        }
+        return ack;
+    }
+
+    private Object process(Long nodeId, String type, String data) {
+        switch (type) {
+            case "TrafficReport":
+                // 与 HTTP 批量上报相同，按序号去重并返回逐条确认
+                List<FlowDto> items = JSON.parseArray(data, FlowDto.class);
+                return JSON.parseObject(flowController.processFlowBatch(nodeId, items));
+            case "ConfigReport":
+                GostConfigDto gostConfigDto = JSON.parseObject(data, GostConfigDto.class);
+                checkGostConfigAsync.cleanNodeConfigs(nodeId.toString(), gostConfigDto);
+                return null;
+            case "ConfigState":
+                // 面板不保存节点配置摘要，不返回 match，节点只记录确认
+                JSONObject state = JSON.parseObject(data);
+                log.info("节点 {} 配置摘要: hash={}, 服务 {} 个", nodeId, state.getString("hash"), state.getIntValue("services"));
+                return null;
+            case "SessionRecords":
+                saveSessionRecords(nodeId, JSON.parseObject(data));
+                return null;
+            case "NodeHealth":
+                saveHealthEvent(nodeId, JSON.parseObject(data));
+                return null;
+            default:
+                // 排空进度和日志订阅只转发给管理员页面：排空进度可以随时向节点查询，
+                // 日志只在管理员订阅期间上报，这里只确认
+                return null;
+        }
+    }
+
+    /**
+     * 保存会话记录，批量写入在同一事务中，失败时整批不确认，由节点重试
+     */
+    private void saveSessionRecords(Long nodeId, JSONObject batch) {
+        long dropped = batch.getLongValue("dropped");
+        if (dropped > 0) {
+            log.warn("节点 {} 会话记录缓冲已满，丢弃 {} 条记录", nodeId, dropped);
+        }
+
+        JSONArray records = batch.getJSONArray("records");
+        if (records == null || records.isEmpty()) {
+            return;
+        }
+
+        long now = System.currentTimeMillis();
+        List<SessionRecord> list = new ArrayList<>(records.size());
+        for (int i = 0; i < records.size(); i++) {
+            JSONObject r = records.getJSONObject(i);
+            SessionRecord record = new SessionRecord();
+            record.setNodeId(nodeId);
+            record.setService(clip(r.getString("service"), 100));
+            record.setNetwork(clip(r.getString("network"), 20));
+            record.setRemoteAddr(clip(r.getString("remote"), 255));
+            record.setLocalAddr(clip(r.getString("local"), 255));
+            record.setHost(clip(r.getString("host"), 255));
+            record.setDst(clip(r.getString("dst"), 255));
+            record.setClientIp(clip(r.getString("clientIP"), 100));
+            record.setRoute(clip(r.getString("route"), 255));
+            record.setInputBytes(r.getLongValue("inputBytes"));
+            record.setOutputBytes(r.getLongValue("outputBytes"));
+            // 节点上报的耗时单位为纳秒
+            record.setDuration(r.getLongValue("duration") / 1_000_000);
+            record.setErr(r.getString("err"));
+            record.setSid(clip(r.getString("sid"), 100));
+            record.setTime(parseTime(r.getString("time"), now));
+            record.setCreatedTime(now);
+            list.add(record);
+        }
+
+        if (!sessionRecordService.saveBatch(list)) {
+            throw new IllegalStateException("保存会话记录失败");
+        }
+    }
+
+    /**
+     * 保存节点健康状态变化
+     */
+    private void saveHealthEvent(Long nodeId, JSONObject report) {
+        long now = System.currentTimeMillis();
+
+        NodeHealthEvent event = new NodeHealthEvent();
+        event.setNodeId(nodeId);
+        event.setHop(clip(report.getString("hop"), 100));
+        event.setNode(clip(report.getString("node"), 100));
+        event.setAddr(clip(report.getString("addr"), 255));
+        event.setHealthy(report.getBooleanValue("healthy") ? 1 : 0);
+        event.setLastError(report.getString("lastError"));
+        event.setLatency(report.getLongValue("latency"));
+        event.setSince(parseTime(report.getString("since"), now));
+        event.setCreatedTime(now);
+
+        if (!nodeHealthEventService.save(event)) {
+            throw new IllegalStateException("保存节点健康状态失败");
+        }
+    }
+
+    /**
+     * 截断超出字段长度的字符串，避免单条异常记录导致整批写入失败
+     */
+    private static String clip(String value, int max) {
+        if (value == null) {
+            return "";
+        }
+        return value.length() > max ? value.substring(0, max) : value;
+    }
+
+    /**
+     * 解析节点上报的 RFC 3339 时间，解析失败时使用 fallback
+     */
+    private static long parseTime(String value, long fallback) {
+        if (value == null || value.isEmpty()) {
+            return fallback;
+        }
+        try {
+            return OffsetDateTime.parse(value).toInstant().toEpochMilli();
+        } catch (Exception e) {
+            return fallback;
+        }
+    }
+}
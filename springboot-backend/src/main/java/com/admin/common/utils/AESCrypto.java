@@ -3,6 +3,7 @@ package com.admin.common.utils;
 import lombok.extern.slf4j.Slf4j;
 
 import javax.crypto.Cipher;
+import javax.crypto.Mac;
 import javax.crypto.spec.GCMParameterSpec;
 import javax.crypto.spec.SecretKeySpec;
 import java.nio.ByteBuffer;
@@ -10,6 +11,7 @@ import java.nio.charset.StandardCharsets;
 import java.security.MessageDigest;
 import java.security.SecureRandom;
 import java.util.Base64;
+import java.util.concurrent.ConcurrentHashMap;
 
 /**
  * AES加密工具类
@@ -22,7 +24,22 @@ public class AESCrypto {
     private static final String TRANSFORMATION = "AES/GCM/NoPadding";
     private static final int GCM_IV_LENGTH = 12; // GCM推荐的IV长度
     private static final int GCM_TAG_LENGTH = 16; // GCM认证标签长度
+
+    /**
+     * 密钥用途，与Go端 crypto.Purpose* 一致
+     */
+    public static final String PURPOSE_COMMAND = "command";
+    public static final String PURPOSE_TRAFFIC = "traffic";
+    public static final String PURPOSE_CONFIG = "config";
+
+    /**
+     * 初始密钥ID，由节点密钥派生
+     */
+    public static final String DEFAULT_KEY_ID = "0";
     
+    // 缓存派生的加密器，key为 secret + 密钥ID + 用途
+    private static final ConcurrentHashMap<String, AESCrypto> DERIVED_CACHE = new ConcurrentHashMap<>();
+
     private final SecretKeySpec secretKey;
     private final SecureRandom secureRandom;
     
@@ -49,12 +66,74 @@ public class AESCrypto {
         }
     }
     
+    /**
+     * 使用已派生的32字节密钥构造
+     */
+    private AESCrypto(byte[] keyBytes) {
+        this.secretKey = new SecretKeySpec(keyBytes, ALGORITHM);
+        this.secureRandom = new SecureRandom();
+    }
+
+    /**
+     * 使用 HKDF-SHA256 派生指定用途的加密器，与Go端 crypto.DeriveKey 一致
+     * 密钥ID作为盐，"flux-agent/" + 用途作为 info
+     * @param secret 节点密钥
+     * @param keyId 密钥ID
+     * @param purpose 密钥用途
+     * @return 派生密钥的加密器
+     */
+    public static AESCrypto derive(String secret, String keyId, String purpose) {
+        if (secret == null || secret.isEmpty() || keyId == null || keyId.isEmpty()) {
+            throw new IllegalArgumentException("密钥和密钥ID不能为空");
+        }
+
+        try {
+            Mac mac = Mac.getInstance("HmacSHA256");
+            mac.init(new SecretKeySpec(keyId.getBytes(StandardCharsets.UTF_8), "HmacSHA256"));
+            byte[] prk = mac.doFinal(secret.getBytes(StandardCharsets.UTF_8));
+
+            // 32字节只需要一轮 expand
+            mac.init(new SecretKeySpec(prk, "HmacSHA256"));
+            mac.update(("flux-agent/" + purpose).getBytes(StandardCharsets.UTF_8));
+            mac.update((byte) 1);
+            return new AESCrypto(mac.doFinal());
+        } catch (Exception e) {
+            throw new RuntimeException("派生密钥失败: " + e.getMessage(), e);
+        }
+    }
+
+    /**
+     * 获取派生的加密器，相同参数复用同一实例
+     */
+    public static AESCrypto derived(String secret, String keyId, String purpose) {
+        return DERIVED_CACHE.computeIfAbsent(secret + "\n" + keyId + "\n" + purpose, k -> derive(secret, keyId, purpose));
+    }
+
+    /**
+     * 构造绑定到密文的附加认证数据，与Go端 crypto.AdditionalData 一致
+     */
+    public static byte[] additionalData(String keyId, String type, String requestId, long timestamp) {
+        String ad = (keyId == null ? "" : keyId) + "\n" + (type == null ? "" : type) + "\n"
+                + (requestId == null ? "" : requestId) + "\n" + timestamp;
+        return ad.getBytes(StandardCharsets.UTF_8);
+    }
+
     /**
      * 加密数据
      * @param data 要加密的原始数据
      * @return Base64编码的加密数据，格式为: nonce + ciphertext
      */
     public String encrypt(byte[] data) {
+        return encrypt(data, null);
+    }
+
+    /**
+     * 加密数据并认证附加数据
+     * @param data 要加密的原始数据
+     * @param aad 附加认证数据，为 null 时不使用
+     * @return Base64编码的加密数据，格式为: nonce + ciphertext
+     */
+    public String encrypt(byte[] data, byte[] aad) {
         if (data == null || data.length == 0) {
             throw new IllegalArgumentException("待加密数据不能为空");
         }
@@ -70,6 +149,9 @@ public class AESCrypto {
             // 初始化Cipher
             Cipher cipher = Cipher.getInstance(TRANSFORMATION);
             cipher.init(Cipher.ENCRYPT_MODE, secretKey, gcmSpec);
+            if (aad != null) {
+                cipher.updateAAD(aad);
+            }
             
             // 加密数据
             byte[] ciphertext = cipher.doFinal(data);
@@ -106,6 +188,16 @@ public class AESCrypto {
      * @return 解密后的原始数据
      */
     public byte[] decrypt(String encryptedData) {
+        return decrypt(encryptedData, null);
+    }
+
+    /**
+     * 解密数据并校验附加认证数据
+     * @param encryptedData Base64编码的加密数据
+     * @param aad 附加认证数据，为 null 时不使用
+     * @return 解密后的原始数据
+     */
+    public byte[] decrypt(String encryptedData, byte[] aad) {
         if (encryptedData == null || encryptedData.isEmpty()) {
             throw new IllegalArgumentException("加密数据不能为空");
         }
@@ -132,6 +224,9 @@ public class AESCrypto {
             // 初始化Cipher
             Cipher cipher = Cipher.getInstance(TRANSFORMATION);
             cipher.init(Cipher.DECRYPT_MODE, secretKey, gcmSpec);
+            if (aad != null) {
+                cipher.updateAAD(aad);
+            }
             
             // 解密数据
             return cipher.doFinal(ciphertext);
@@ -4,6 +4,7 @@ package com.admin.common.utils;
 import com.admin.common.dto.GostConfigDto;
 import com.admin.common.dto.GostDto;
 import com.admin.common.task.CheckGostConfigAsync;
+import com.admin.common.task.NodeReportHandler;
 import com.admin.entity.Node;
 import com.admin.service.NodeService;
 import com.alibaba.fastjson.JSON;
@@ -17,6 +18,7 @@ import org.springframework.web.socket.WebSocketSession;
 import org.springframework.web.socket.handler.TextWebSocketHandler;
 
 import javax.annotation.Resource;
+import java.nio.charset.StandardCharsets;
 import java.util.Objects;
 import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.CopyOnWriteArraySet;
@@ -31,6 +33,9 @@ public class WebSocketServer extends TextWebSocketHandler {
     @Resource
     NodeService nodeService;
 
+    @Resource
+    NodeReportHandler nodeReportHandler;
+
     // 存储所有活跃的 WebSocket 连接（
     private static final CopyOnWriteArraySet<WebSocketSession> activeSessions = new CopyOnWriteArraySet<>();
     
@@ -46,13 +51,22 @@ public class WebSocketServer extends TextWebSocketHandler {
     // 缓存加密器实例，避免重复创建
     private static final ConcurrentHashMap<String, AESCrypto> cryptoCache = new ConcurrentHashMap<>();
 
+    // 加密帧时间戳允许的最大偏差，与节点端一致
+    private static final long FRAME_FRESHNESS_MS = 5 * 60 * 1000L;
+
     /**
      * 加密消息包装器
+     * kid、type、requestId 非空时为认证帧：密钥按 kid 派生，帧头作为附加认证数据；
+     * 旧版节点发送的帧只有 encrypted、data、timestamp，使用 SHA-256(secret) 密钥
      */
     public static class EncryptedMessage {
         private boolean encrypted;
         private String data;
         private Long timestamp;
+        private String kid;
+        private String type;
+        private String requestId;
+        private boolean compressed;
 
         // getters and setters
         public boolean isEncrypted() { return encrypted; }
@@ -61,6 +75,14 @@ public class WebSocketServer extends TextWebSocketHandler {
         public void setData(String data) { this.data = data; }
         public Long getTimestamp() { return timestamp; }
         public void setTimestamp(Long timestamp) { this.timestamp = timestamp; }
+        public String getKid() { return kid; }
+        public void setKid(String kid) { this.kid = kid; }
+        public String getType() { return type; }
+        public void setType(String type) { this.type = type; }
+        public String getRequestId() { return requestId; }
+        public void setRequestId(String requestId) { this.requestId = requestId; }
+        public boolean isCompressed() { return compressed; }
+        public void setCompressed(boolean compressed) { this.compressed = compressed; }
     }
 
     //接受客户端消息
@@ -74,11 +96,22 @@ public class WebSocketServer extends TextWebSocketHandler {
                 String nodeSecret = (String) session.getAttributes().get("nodeSecret");
 
                 // 尝试解密消息
-                String decryptedPayload = decryptMessageIfNeeded(message.getPayload(), nodeSecret);
-
-                if (decryptedPayload.contains("memory_usage")){
-                    // 先发送确认消息
-                    sendToUser(session, "{\"type\":\"call\"}", nodeSecret);
+                String decryptedPayload = decryptMessageIfNeeded(session, message.getPayload(), nodeSecret);
+                JSONObject report = Objects.equals(type, "1") ? parseReport(decryptedPayload) : null;
+
+                if (report != null) {
+                    // 节点上报，处理后以相同 requestId 确认，压缩的数据解压后再转发给管理员
+                    JSONObject ack = nodeReportHandler.handle(Long.valueOf(id), report);
+                    sendToUser(session, ack.toJSONString(), nodeSecret);
+                    report.put("data", JSON.parse(nodeReportHandler.reportData(report)));
+                    report.remove("compressed");
+                    decryptedPayload = report.toJSONString();
+                } else if (decryptedPayload.contains("memory_usage")){
+                    // 先发送确认消息，认证帧要求携带 requestId
+                    JSONObject call = new JSONObject();
+                    call.put("type", "call");
+                    call.put("requestId", UUID.randomUUID().toString());
+                    sendToUser(session, call.toJSONString(), nodeSecret);
                 }else if (decryptedPayload.contains("requestId")) {
                     log.info("收到消息: {}", decryptedPayload);
                     // 处理命令响应消息
@@ -139,10 +172,25 @@ public class WebSocketServer extends TextWebSocketHandler {
         }
     }
 
+    /**
+     * 解析节点上报消息，不是上报时返回 null
+     */
+    private JSONObject parseReport(String payload) {
+        if (!payload.contains("requestId")) {
+            return null;
+        }
+        try {
+            JSONObject json = JSONObject.parseObject(payload);
+            return nodeReportHandler.isReport(json) ? json : null;
+        } catch (Exception e) {
+            return null;
+        }
+    }
+
     /**
      * 尝试解密消息（如果需要）
      */
-    private String decryptMessageIfNeeded(String payload, String nodeSecret) {
+    private String decryptMessageIfNeeded(WebSocketSession session, String payload, String nodeSecret) {
         if (payload == null || payload.trim().isEmpty()) {
             return payload;
         }
@@ -150,7 +198,14 @@ public class WebSocketServer extends TextWebSocketHandler {
         try {
             // 尝试解析为加密消息格式
             EncryptedMessage encryptedMessage = JSON.parseObject(payload, EncryptedMessage.class);
-            
+
+            if (encryptedMessage.isEncrypted() && StringUtils.isNotBlank(encryptedMessage.getKid())) {
+                String decryptedData = new String(openFrame(encryptedMessage, nodeSecret, AESCrypto.PURPOSE_COMMAND), StandardCharsets.UTF_8);
+                // 记录节点当前使用的密钥ID，之后发送的帧使用相同的密钥
+                session.getAttributes().put("keyId", encryptedMessage.getKid());
+                return decryptedData;
+            }
+
             if (encryptedMessage.isEncrypted() && encryptedMessage.getData() != null) {
                 // 获取或创建加密器
                 AESCrypto crypto = getOrCreateCrypto(nodeSecret);
@@ -171,27 +226,51 @@ public class WebSocketServer extends TextWebSocketHandler {
         return payload;
     }
 
+    /**
+     * 校验并解密认证帧：时间戳在窗口内，密钥按 kid 和用途派生，帧头作为附加认证数据
+     */
+    public static byte[] openFrame(EncryptedMessage frame, String nodeSecret, String purpose) {
+        long timestamp = frame.getTimestamp() == null ? 0 : frame.getTimestamp();
+        long timestampMs = timestamp > 1_000_000_000_000L ? timestamp : timestamp * 1000;
+        if (Math.abs(System.currentTimeMillis() - timestampMs) > FRAME_FRESHNESS_MS) {
+            throw new IllegalArgumentException("加密帧时间戳超出允许范围");
+        }
+
+        AESCrypto crypto = AESCrypto.derived(nodeSecret, frame.getKid(), purpose);
+        byte[] aad = AESCrypto.additionalData(frame.getKid(), frame.getType(), frame.getRequestId(), timestamp);
+        return crypto.decrypt(frame.getData(), aad);
+    }
+
     /**
      * 加密消息（如果可能）
+     * 使用认证帧发送，节点收到第一个认证帧后也改用认证帧上报
      */
-    private static String encryptMessageIfPossible(String message, String nodeSecret) {
+    private static String encryptMessageIfPossible(String message, String nodeSecret, String keyId) {
         if (message == null || nodeSecret == null) {
             return message;
         }
 
         try {
-            AESCrypto crypto = getOrCreateCrypto(nodeSecret);
-            if (crypto != null) {
-                String encryptedData = crypto.encrypt(message);
-                
-                // 创建加密消息包装器
-                JSONObject encryptedMessage = new JSONObject();
-                encryptedMessage.put("encrypted", true);
-                encryptedMessage.put("data", encryptedData);
-                encryptedMessage.put("timestamp", System.currentTimeMillis());
-                
-                return encryptedMessage.toJSONString();
-            }
+            JSONObject inner = JSON.parseObject(message);
+            String kid = keyId != null ? keyId : AESCrypto.DEFAULT_KEY_ID;
+            String type = inner.getString("type");
+            String requestId = inner.getString("requestId");
+            long timestamp = System.currentTimeMillis();
+
+            AESCrypto crypto = AESCrypto.derived(nodeSecret, kid, AESCrypto.PURPOSE_COMMAND);
+            String encryptedData = crypto.encrypt(message.getBytes(StandardCharsets.UTF_8),
+                    AESCrypto.additionalData(kid, type, requestId, timestamp));
+
+            // 创建加密消息包装器，帧头明文携带 kid、type、requestId
+            JSONObject encryptedMessage = new JSONObject();
+            encryptedMessage.put("encrypted", true);
+            encryptedMessage.put("data", encryptedData);
+            encryptedMessage.put("timestamp", timestamp);
+            encryptedMessage.put("kid", kid);
+            encryptedMessage.put("type", type);
+            encryptedMessage.put("requestId", requestId);
+
+            return encryptedMessage.toJSONString();
         } catch (Exception e) {
             log.info("⚠️ WebSocket消息加密失败，发送原始数据: {}", e.getMessage());
         }
@@ -375,7 +454,8 @@ public class WebSocketServer extends TextWebSocketHandler {
                         if (nodeSecret != null && !nodeSecret.isEmpty()) {
                             String type = (String) socketSession.getAttributes().get("type");
                             if ("1".equals(type)) { // 节点连接
-                                finalMessage = encryptMessageIfPossible(message, nodeSecret);
+                                String keyId = (String) socketSession.getAttributes().get("keyId");
+                                finalMessage = encryptMessageIfPossible(message, nodeSecret, keyId);
                             }
                         }
                         socketSession.sendMessage(new TextMessage(finalMessage));
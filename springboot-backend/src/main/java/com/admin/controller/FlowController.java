@@ -7,16 +7,22 @@ import com.admin.common.lang.R;
 import com.admin.common.task.CheckGostConfigAsync;
 import com.admin.common.utils.AESCrypto;
 import com.admin.common.utils.GostUtil;
+import com.admin.common.utils.GzipUtil;
+import com.admin.common.utils.WebSocketServer;
 import com.admin.entity.*;
 import com.alibaba.fastjson.JSON;
+import com.alibaba.fastjson.JSONArray;
 import com.alibaba.fastjson.JSONObject;
 import com.baomidou.mybatisplus.core.conditions.query.QueryWrapper;
 import com.baomidou.mybatisplus.core.conditions.update.UpdateWrapper;
 import org.springframework.web.bind.annotation.*;
 import lombok.extern.slf4j.Slf4j;
+import org.apache.commons.lang3.StringUtils;
 
 import javax.annotation.Resource;
+import java.io.IOException;
 import java.math.BigDecimal;
+import java.nio.charset.StandardCharsets;
 import java.util.Date;
 import java.util.List;
 import java.util.Objects;
@@ -62,52 +68,28 @@ public class FlowController extends BaseController {
     // 缓存加密器实例，避免重复创建
     private static final ConcurrentHashMap<String, AESCrypto> CRYPTO_CACHE = new ConcurrentHashMap<>();
 
-    @Resource
-    CheckGostConfigAsync checkGostConfigAsync;
-
-    /**
-     * 加密消息包装器
-     */
-    public static class EncryptedMessage {
-        private boolean encrypted;
-        private String data;
-        private Long timestamp;
-
-        // getters and setters
-        public boolean isEncrypted() {
-            return encrypted;
-        }
-
-        public void setEncrypted(boolean encrypted) {
-            this.encrypted = encrypted;
-        }
-
-        public String getData() {
-            return data;
-        }
+    // 批量上报中每个节点服务已处理的最大序号，用于丢弃重发的记录
+    private static final ConcurrentHashMap<String, Long> LAST_SEQ = new ConcurrentHashMap<>();
+    private static final ConcurrentHashMap<String, Object> SEQ_LOCKS = new ConcurrentHashMap<>();
 
-        public void setData(String data) {
-            this.data = data;
-        }
+    // 节点通过该请求头携带密钥，旧版节点仍使用 secret 查询参数
+    private static final String NODE_SECRET_HEADER = "X-Node-Secret";
 
-        public Long getTimestamp() {
-            return timestamp;
-        }
-
-        public void setTimestamp(Long timestamp) {
-            this.timestamp = timestamp;
-        }
-    }
+    @Resource
+    CheckGostConfigAsync checkGostConfigAsync;
 
     @PostMapping("/config")
     @LogAnnotation
-    public String config(@RequestBody String rawData, String secret) {
+    public String config(@RequestBody String rawData,
+                         @RequestHeader(value = NODE_SECRET_HEADER, required = false) String headerSecret,
+                         @RequestParam(value = "secret", required = false) String querySecret) {
+        String secret = resolveSecret(headerSecret, querySecret);
         Node node = nodeService.getOne(new QueryWrapper<Node>().eq("secret", secret));
         if (node == null) return SUCCESS_RESPONSE;
 
         try {
             // 尝试解密数据
-            String decryptedData = decryptIfNeeded(rawData, secret);
+            String decryptedData = decryptIfNeeded(rawData, secret, AESCrypto.PURPOSE_CONFIG);
 
             // 解析为GostConfigDto
             GostConfigDto gostConfigDto = JSON.parseObject(decryptedData, GostConfigDto.class);
@@ -130,23 +112,45 @@ public class FlowController extends BaseController {
 
     /**
      * 处理流量数据上报
+     * 兼容旧版节点的单条上报，以及新版节点带序号的批量上报
      *
-     * @param rawData 原始数据（可能是加密的）
-     * @param secret  节点密钥
-     * @return 处理结果
+     * @param body            原始数据（可能是加密或 gzip 压缩的）
+     * @param contentEncoding 未加密的批量报告压缩后为 gzip
+     * @param headerSecret    请求头中的节点密钥
+     * @param querySecret     查询参数中的节点密钥（旧版节点）
+     * @return 单条上报返回 ok，批量上报返回逐条确认
      */
     @RequestMapping("/upload")
     @LogAnnotation
-    public String uploadFlowData(@RequestBody String rawData, String secret) {
+    public String uploadFlowData(@RequestBody byte[] body,
+                                 @RequestHeader(value = "Content-Encoding", required = false) String contentEncoding,
+                                 @RequestHeader(value = NODE_SECRET_HEADER, required = false) String headerSecret,
+                                 @RequestParam(value = "secret", required = false) String querySecret) {
+        String secret = resolveSecret(headerSecret, querySecret);
+
         // 1. 验证节点权限
-        if (!isValidNode(secret)) {
+        Node node = nodeService.getOne(new QueryWrapper<Node>().eq("secret", secret));
+        if (node == null) {
             return SUCCESS_RESPONSE;
         }
 
-        // 2. 尝试解密数据
-        String decryptedData = decryptIfNeeded(rawData, secret);
+        // 2. 解压并尝试解密数据
+        String rawData;
+        try {
+            rawData = new String("gzip".equalsIgnoreCase(contentEncoding) ? GzipUtil.gunzip(body) : body, StandardCharsets.UTF_8);
+        } catch (IOException e) {
+            log.error("解压节点 {} 流量数据失败: {}", node.getId(), e.getMessage());
+            return SUCCESS_RESPONSE;
+        }
+        String decryptedData = decryptIfNeeded(rawData, secret, AESCrypto.PURPOSE_TRAFFIC);
+
+        // 3. 批量上报为数组，每条带持久化队列序号
+        if (decryptedData.trim().startsWith("[")) {
+            List<FlowDto> items = JSON.parseArray(decryptedData, FlowDto.class);
+            log.info("节点 {} 批量上报流量数据 {} 条", node.getId(), items.size());
+            return processFlowBatch(node.getId(), items);
+        }
 
-        // 3. 解析为FlowDto列表
         FlowDto flowDataList = JSONObject.parseObject(decryptedData, FlowDto.class);
         if (Objects.equals(flowDataList.getN(), "web_api")) {
             return SUCCESS_RESPONSE;
@@ -158,6 +162,57 @@ public class FlowController extends BaseController {
         return processFlowData(flowDataList);
     }
 
+    /**
+     * 处理批量流量数据，返回 {"acks":[{"n":..,"s":..}]}
+     * 节点未收到确认时会重发同一序号的记录，已处理过的序号只确认不重复计费；
+     * 处理失败的记录不确认，由节点下个周期重发
+     */
+    public String processFlowBatch(Long nodeId, List<FlowDto> items) {
+        JSONArray acks = new JSONArray();
+        for (FlowDto item : items) {
+            if (item == null || item.getN() == null) {
+                continue;
+            }
+            if (!Objects.equals(item.getN(), "web_api")) {
+                try {
+                    processSequencedFlowData(nodeId, item);
+                } catch (Exception e) {
+                    log.error("处理节点 {} 服务 {} 流量数据失败: {}", nodeId, item.getN(), e.getMessage());
+                    continue;
+                }
+            }
+            JSONObject ack = new JSONObject();
+            ack.put("n", item.getN());
+            ack.put("s", item.getS());
+            acks.add(ack);
+        }
+
+        JSONObject response = new JSONObject();
+        response.put("acks", acks);
+        return response.toJSONString();
+    }
+
+    /**
+     * 按节点和服务记录已处理的最大序号，序号不大于该值的记录视为重发
+     */
+    private void processSequencedFlowData(Long nodeId, FlowDto item) {
+        if (item.getS() == null || item.getS() <= 0) {
+            processFlowData(item);
+            return;
+        }
+
+        String key = nodeId + "_" + item.getN();
+        synchronized (SEQ_LOCKS.computeIfAbsent(key, k -> new Object())) {
+            Long last = LAST_SEQ.get(key);
+            if (last != null && item.getS() <= last) {
+                log.info("节点 {} 服务 {} 序号 {} 已处理，跳过", nodeId, item.getN(), item.getS());
+                return;
+            }
+            processFlowData(item);
+            LAST_SEQ.put(key, item.getS());
+        }
+    }
+
     /**
      * 检测消息是否为加密格式
      */
@@ -170,17 +225,30 @@ public class FlowController extends BaseController {
         }
     }
 
+    /**
+     * 优先使用请求头中的节点密钥
+     */
+    private String resolveSecret(String headerSecret, String querySecret) {
+        return StringUtils.isNotBlank(headerSecret) ? headerSecret : querySecret;
+    }
+
     /**
      * 根据需要解密数据
+     * 带 kid 的报告使用按用途派生的密钥，帧头作为附加认证数据；否则按旧版格式解密
      */
-    private String decryptIfNeeded(String rawData, String secret) {
+    private String decryptIfNeeded(String rawData, String secret, String purpose) {
         if (rawData == null || rawData.trim().isEmpty()) {
             throw new IllegalArgumentException("数据不能为空");
         }
 
         try {
             // 尝试解析为加密消息格式
-            EncryptedMessage encryptedMessage = JSON.parseObject(rawData, EncryptedMessage.class);
+            WebSocketServer.EncryptedMessage encryptedMessage = JSON.parseObject(rawData, WebSocketServer.EncryptedMessage.class);
+
+            if (encryptedMessage.isEncrypted() && StringUtils.isNotBlank(encryptedMessage.getKid())) {
+                byte[] plain = WebSocketServer.openFrame(encryptedMessage, secret, purpose);
+                return decompressIfNeeded(plain, encryptedMessage.isCompressed());
+            }
 
             if (encryptedMessage.isEncrypted() && encryptedMessage.getData() != null) {
                 // 获取或创建加密器
@@ -191,8 +259,8 @@ public class FlowController extends BaseController {
                 }
 
                 // 解密数据
-                String decryptedData = crypto.decryptString(encryptedMessage.getData());
-                return decryptedData;
+                byte[] plain = crypto.decrypt(encryptedMessage.getData());
+                return decompressIfNeeded(plain, encryptedMessage.isCompressed());
             }
         } catch (Exception e) {
             // 解析失败，可能是非加密格式，直接返回原始数据
@@ -202,6 +270,13 @@ public class FlowController extends BaseController {
         return rawData;
     }
 
+    /**
+     * 节点先压缩再加密，解密后按 compressed 标记解压
+     */
+    private String decompressIfNeeded(byte[] data, boolean compressed) throws IOException {
+        return new String(compressed ? GzipUtil.gunzip(data) : data, StandardCharsets.UTF_8);
+    }
+
     /**
      * 获取或创建加密器实例
      */
@@ -392,11 +467,6 @@ public class FlowController extends BaseController {
         return FORWARD_LOCKS.computeIfAbsent(forwardId, k -> new Object());
     }
 
-    private boolean isValidNode(String secret) {
-        int nodeCount = nodeService.count(new QueryWrapper<Node>().eq("secret", secret));
-        return nodeCount > 0;
-    }
-
     private String[] parseServiceName(String serviceName) {
         return serviceName.split("_");
     }
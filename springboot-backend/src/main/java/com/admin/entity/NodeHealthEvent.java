@@ -0,0 +1,54 @@
+package com.admin.entity;
+
+import com.baomidou.mybatisplus.annotation.IdType;
+import com.baomidou.mybatisplus.annotation.TableId;
+import lombok.Data;
+
+import java.io.Serializable;
+
+/**
+ * <p>
+ * 节点上报的转发链节点健康状态变化
+ * </p>
+ */
+@Data
+public class NodeHealthEvent implements Serializable {
+
+    private static final long serialVersionUID = 1L;
+
+    @TableId(value = "id", type = IdType.AUTO)
+    private Long id;
+
+    private Long nodeId;
+
+    /**
+     * 跳跃点（hop）名称
+     */
+    private String hop;
+
+    /**
+     * 转发链中的节点名
+     */
+    private String node;
+
+    private String addr;
+
+    /**
+     * 是否可用（0：不可用，1：可用）
+     */
+    private Integer healthy;
+
+    private String lastError;
+
+    /**
+     * 最后一次成功探测的耗时（毫秒）
+     */
+    private Long latency;
+
+    /**
+     * 状态变化时间（时间戳）
+     */
+    private Long since;
+
+    private Long createdTime;
+}
@@ -0,0 +1,71 @@
+package com.admin.entity;
+
+import com.baomidou.mybatisplus.annotation.IdType;
+import com.baomidou.mybatisplus.annotation.TableId;
+import lombok.Data;
+
+import java.io.Serializable;
+
+/**
+ * <p>
+ * 节点上报的转发会话记录，用于追溯经由某个转发的连接
+ * </p>
+ */
+@Data
+public class SessionRecord implements Serializable {
+
+    private static final long serialVersionUID = 1L;
+
+    @TableId(value = "id", type = IdType.AUTO)
+    private Long id;
+
+    private Long nodeId;
+
+    /**
+     * 服务名，与转发的服务名一致
+     */
+    private String service;
+
+    private String network;
+
+    /**
+     * 客户端地址
+     */
+    private String remoteAddr;
+
+    /**
+     * 服务监听地址
+     */
+    private String localAddr;
+
+    private String host;
+
+    /**
+     * 目标地址
+     */
+    private String dst;
+
+    private String clientIp;
+
+    private String route;
+
+    private Long inputBytes;
+
+    private Long outputBytes;
+
+    /**
+     * 会话持续时间（毫秒）
+     */
+    private Long duration;
+
+    private String err;
+
+    private String sid;
+
+    /**
+     * 会话开始时间（时间戳）
+     */
+    private Long time;
+
+    private Long createdTime;
+}
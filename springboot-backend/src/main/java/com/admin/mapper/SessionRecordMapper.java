@@ -0,0 +1,13 @@
+package com.admin.mapper;
+
+import com.admin.entity.SessionRecord;
+import com.baomidou.mybatisplus.core.mapper.BaseMapper;
+
+/**
+ * <p>
+ *  Mapper 接口
+ * </p>
+ */
+public interface SessionRecordMapper extends BaseMapper<SessionRecord> {
+
+}
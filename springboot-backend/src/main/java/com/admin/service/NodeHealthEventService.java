@@ -0,0 +1,13 @@
+package com.admin.service;
+
+import com.admin.entity.NodeHealthEvent;
+import com.baomidou.mybatisplus.extension.service.IService;
+
+/**
+ * <p>
+ *  服务类
+ * </p>
+ */
+public interface NodeHealthEventService extends IService<NodeHealthEvent> {
+
+}
@@ -0,0 +1,17 @@
+package com.admin.service.impl;
+
+import com.admin.entity.NodeHealthEvent;
+import com.admin.mapper.NodeHealthEventMapper;
+import com.admin.service.NodeHealthEventService;
+import com.baomidou.mybatisplus.extension.service.impl.ServiceImpl;
+import org.springframework.stereotype.Service;
+
+/**
+ * <p>
+ *  服务实现类
+ * </p>
+ */
+@Service
+public class NodeHealthEventServiceImpl extends ServiceImpl<NodeHealthEventMapper, NodeHealthEvent> implements NodeHealthEventService {
+
+}
@@ -0,0 +1,17 @@
+package com.admin.service.impl;
+
+import com.admin.entity.SessionRecord;
+import com.admin.mapper.SessionRecordMapper;
+import com.admin.service.SessionRecordService;
+import com.baomidou.mybatisplus.extension.service.impl.ServiceImpl;
+import org.springframework.stereotype.Service;
+
+/**
+ * <p>
+ *  服务实现类
+ * </p>
+ */
+@Service
+public class SessionRecordServiceImpl extends ServiceImpl<SessionRecordMapper, SessionRecord> implements SessionRecordService {
+
+}